@@ -1,10 +1,17 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
+	"slices"
 	"strconv"
+	"time"
 )
 
 type config struct {
@@ -12,18 +19,52 @@ type config struct {
 	Service service  `json:"service"`
 	Routes  []routes `json:"routes"`
 	Redis   redis    `json:"redis"`
+	Storage storage  `json:"storage"`
+	Uploads uploads  `json:"uploads"`
+	// The supported media types, replacing the built in ones when given
+	Media []media.MediaType `json:"media_types"`
+	// The named resize options that files can be processed with e.g. thumbnail, card, hero
+	Presets map[string]preset `json:"presets"`
+	// The named renditions that videos can be transcoded to e.g. 720p, 480p-webm
+	Profiles map[string]transcodeProfile `json:"transcode_profiles"`
+	// The processors run on every upload, keyed by media category or mime type e.g. image, video/mp4
+	Pipelines map[string][]pipelineStep `json:"pipelines"`
+	Webhooks  webhooks                  `json:"webhooks"`
+	// The options that tasks are enqueued with, keyed by task type e.g. image:resize
+	Tasks  map[string]taskOptions `json:"tasks"`
+	Worker worker                 `json:"worker"`
 }
 
 type service struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Port    int    `json:"port"`
+	Name              string `json:"name"`
+	Version           string `json:"version"`
+	Port              int    `json:"port"`
+	ReadHeaderTimeout int    `json:"read_header_timeout"` // The seconds a client has to send the headers of a request
+	ReadTimeout       int    `json:"read_timeout"`        // The seconds a client has to send a whole request
+	WriteTimeout      int    `json:"write_timeout"`       // The seconds a response has to be written in
+	IdleTimeout       int    `json:"idle_timeout"`        // The seconds an idle keep-alive connection is kept open
+	ShutdownTimeout   int    `json:"shutdown_timeout"`    // The seconds requests in flight are given to finish on shutdown
+	AdminToken        string `json:"admin_token"`         // The bearer token of the admin routes, which are not served without it
 }
 
+// Defaults of the timeouts of the server
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 60 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
 type routes struct {
-	Path    string `json:"path"`
-	Handler string `json:"handler"`
-	Method  string `json:"method"`
+	Path        string `json:"path"`
+	Handler     string `json:"handler"`
+	Method      string `json:"method"`
+	MaxBodySize int64  `json:"max_body_size"` // The maximum request body size in bytes, unlimited when zero
+	Admin       bool   `json:"admin"`         // The route is only served to requests carrying the admin token
+	// The seconds the route has to read the request and write the response in, replacing the
+	// read and write timeouts of the server for long requests such as uploads
+	Timeout int `json:"timeout"`
 }
 
 type database struct {
@@ -42,8 +83,182 @@ type redis struct {
 	Database int    `json:"database"`
 }
 
+type storage struct {
+	Driver string       `json:"driver"`
+	Local  localStorage `json:"local"`
+	S3     s3Storage    `json:"s3"`
+}
+
+type localStorage struct {
+	Root string `json:"root"`
+}
+
+type s3Storage struct {
+	Endpoint  string `json:"endpoint"`
+	Bucket    string `json:"bucket"`
+	Region    string `json:"region"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	UseSSL    bool   `json:"use_ssl"`
+}
+
+type uploads struct {
+	MaxSize       int64            `json:"max_size"`         // The maximum size of an uploaded file in bytes
+	MaxSizeByType map[string]int64 `json:"max_size_by_type"` // Overrides the maximum size per file type e.g. image, video
+	// What to do when the content of a file does not match its extension e.g. record, reject
+	ExtensionMismatch string `json:"extension_mismatch"`
+}
+
+type webhooks struct {
+	Secret    string            `json:"secret"`    // The key that deliveries are signed with using HMAC-SHA256
+	Timeout   int               `json:"timeout"`   // The seconds a webhook has to answer a delivery
+	Endpoints []events.Endpoint `json:"endpoints"` // The webhooks that receive the events of every file
+}
+
+// How long a webhook has to answer a delivery when no timeout is configured
+const DefaultWebhookTimeout = 10 * time.Second
+
+type worker struct {
+	Concurrency int `json:"concurrency"` // The number of tasks processed at once
+	// The weights of the queues that tasks are taken from e.g. critical: 6, default: 3, low: 1
+	Queues map[string]int `json:"queues"`
+	// Whether a queue is emptied before any task is taken from a queue of lower weight
+	StrictPriority bool `json:"strict_priority"`
+}
+
+// How many tasks the worker processes at once when no concurrency is configured
+const DefaultWorkerConcurrency = 10
+
+type taskOptions struct {
+	Queue     string `json:"queue"`     // The queue the tasks are enqueued to
+	Priority  int    `json:"priority"`  // The weight of the queue, which is the highest priority of its task types
+	MaxRetry  *int   `json:"max_retry"` // The number of times a failed task is retried, zero to never retry
+	Timeout   int    `json:"timeout"`   // The seconds a single attempt may run for
+	Deadline  int    `json:"deadline"`  // The seconds after being enqueued by which the task must be done
+	Retention int    `json:"retention"` // The seconds a completed task is kept in the queue
+	Unique    int    `json:"unique"`    // The seconds during which identical tasks of a file collapse into one
+}
+
+// UnmarshalJSON rejects unknown fields so that a misspelt option fails
+// when the configuration is loaded instead of being silently ignored
+func (o *taskOptions) UnmarshalJSON(b []byte) error {
+	type plain taskOptions
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode((*plain)(o))
+}
+
+// TaskOptions are the options that tasks of a type are enqueued with
+type TaskOptions struct {
+	Queue     string        // e.g. default, critical
+	Priority  int           // e.g. 6, the weight of the queue
+	MaxRetry  int           // e.g. 3
+	Timeout   time.Duration // e.g. 60s, how long a single attempt may run for
+	Deadline  time.Duration // e.g. 1h after being enqueued, no deadline when zero
+	Retention time.Duration // e.g. 24h, completed tasks are deleted straight away when zero
+	Unique    time.Duration // e.g. 5m, identical tasks are not deduplicated when zero
+}
+
+// Defaults of the options of tasks
+const (
+	DefaultTaskQueue    = "default"
+	DefaultTaskPriority = 1
+	DefaultTaskMaxRetry = 3
+	DefaultTaskTimeout  = 60 * time.Second
+)
+
+type preset struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Mode       string `json:"mode"`
+	Gravity    string `json:"gravity"`
+	Background string `json:"background"`
+	Filter     string `json:"filter"`
+	Format     string `json:"format"`
+	Quality    int    `json:"quality"`
+}
+
+// UnmarshalJSON rejects unknown fields so that a misspelt option fails
+// when the configuration is loaded instead of being silently ignored
+func (p *preset) UnmarshalJSON(b []byte) error {
+	type plain preset
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode((*plain)(p))
+}
+
+// options returns the resize options of the preset
+func (p preset) options() lib.ResizeOptions {
+	return lib.ResizeOptions{
+		Width:      p.Width,
+		Height:     p.Height,
+		Mode:       p.Mode,
+		Gravity:    p.Gravity,
+		Background: p.Background,
+		Filter:     p.Filter,
+		Format:     p.Format,
+		Quality:    p.Quality,
+	}
+}
+
+type transcodeProfile struct {
+	Format       string `json:"format"`        // e.g. mp4, webm
+	Width        int    `json:"width"`         // e.g. 1280, zero to scale by the height
+	Height       int    `json:"height"`        // e.g. 720, zero to scale by the width
+	VideoBitrate int    `json:"video_bitrate"` // e.g. 2500, in kbit/s
+	AudioBitrate int    `json:"audio_bitrate"` // e.g. 128, in kbit/s
+}
+
+// UnmarshalJSON rejects unknown fields so that a misspelt option fails
+// when the configuration is loaded instead of being silently ignored
+func (p *transcodeProfile) UnmarshalJSON(b []byte) error {
+	type plain transcodeProfile
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode((*plain)(p))
+}
+
+// profile returns the transcode profile with the given name
+func (p transcodeProfile) profile(name string) lib.TranscodeProfile {
+	return lib.TranscodeProfile{
+		Name:         name,
+		Format:       p.Format,
+		Width:        p.Width,
+		Height:       p.Height,
+		VideoBitrate: p.VideoBitrate,
+		AudioBitrate: p.AudioBitrate,
+	}
+}
+
+type pipelineStep struct {
+	Processor string  `json:"processor"` // e.g. resize, video_metadata, transcode, poster
+	Preset    string  `json:"preset"`    // The preset a resize is done with, or the profile a video is transcoded to
+	Options   *preset `json:"options"`   // The options a resize is done with when it has no preset
+}
+
+// PipelineStep is a processor that an uploaded file is run through
+type PipelineStep struct {
+	Processor string            // e.g. resize, video_metadata, transcode, poster
+	Preset    string            // The name of the preset the options were taken from, or of the transcode profile, if any
+	Options   lib.ResizeOptions // The options of a resize
+}
+
+// The pipelines run when none are configured, which extract the metadata of videos
+var defaultPipelines = map[string][]PipelineStep{
+	media.CategoryVideo: {{Processor: media.ProcessorVideoMetadata}},
+}
+
+// The maximum size of an uploaded file when none is configured
+const DefaultMaxUploadSize int64 = 10 << 20 // 10 MB
+
 type Config interface {
 	Port() int
+	ReadHeaderTimeout() time.Duration
+	ReadTimeout() time.Duration
+	WriteTimeout() time.Duration
+	IdleTimeout() time.Duration
+	ShutdownTimeout() time.Duration
+	AdminToken() string
 	GetRoutes() []routes
 	GetDB() database
 	DatabaseUsername() string
@@ -56,12 +271,33 @@ type Config interface {
 	RedisAddress() string
 	RedisDB() int
 	RedisURL() string
+	StorageDriver() string
+	LocalStorageRoot() string
+	S3Endpoint() string
+	S3Bucket() string
+	S3Region() string
+	S3AccessKey() string
+	S3SecretKey() string
+	S3UseSSL() bool
+	MaxUploadSize(fileType string) int64
+	UploadSizeCeiling() int64
+	RejectExtensionMismatch() bool
+	MediaTypes() []media.MediaType
+	Preset(name string) (lib.ResizeOptions, bool)
+	TranscodeProfile(name string) (lib.TranscodeProfile, bool)
+	TranscodeProfiles() []lib.TranscodeProfile
+	Pipeline(mimeType string, category string) []PipelineStep
+	WebhookSecret() string
+	WebhookTimeout() time.Duration
+	WebhookEndpoints() []events.Endpoint
+	TaskOptions(taskType string) TaskOptions
+	Queues() map[string]int
+	WorkerConcurrency() int
+	StrictPriority() bool
 }
 
 // NewConfig creates a new Config instance with default values
 func NewConfig() Config {
-	c := &config{}
-
 	// load the configuratiion file using runtime
 	conf, err := os.ReadFile("config/configuration.json")
 	if err != nil {
@@ -69,9 +305,7 @@ func NewConfig() Config {
 		panic(err)
 	}
 
-	// unmarshal the configuration JSON into a Config struct
-	// and change the configuration string to a byte array before unmarshalling
-	err = json.Unmarshal([]byte(conf), &c)
+	c, err := FromJSON(conf)
 	if err != nil {
 		fmt.Printf("Error unmarshalling configuration: %v\n", err)
 		panic(err)
@@ -81,6 +315,79 @@ func NewConfig() Config {
 	return c
 }
 
+// FromJSON creates a new Config instance from the given configuration JSON
+// Settings missing from the JSON fall back to their defaults
+func FromJSON(conf []byte) (Config, error) {
+	c := &config{}
+
+	// unmarshal the configuration JSON into a Config struct
+	if err := json.Unmarshal(conf, &c); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateService(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validatePresets(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateProfiles(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validatePipelines(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range c.Webhooks.Endpoints {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.validateTasks(); err != nil {
+		return nil, err
+	}
+
+	if err := c.validateWorker(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// validatePresets verifies that every preset describes a resize that can be done
+func (c *config) validatePresets() error {
+	for name, p := range c.Presets {
+		if name == "" {
+			return fmt.Errorf("preset names must not be empty")
+		}
+
+		if err := p.options().Validate(); err != nil {
+			return fmt.Errorf("preset %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateProfiles verifies that every transcode profile describes a rendition that can be transcoded
+func (c *config) validateProfiles() error {
+	for name, p := range c.Profiles {
+		if name == "" {
+			return fmt.Errorf("transcode profile names must not be empty")
+		}
+
+		if err := p.profile(name).Validate(); err != nil {
+			return fmt.Errorf("transcode profile %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
 // returns the port from the configuration
 func (c *config) Port() int {
 	p := EnvOrDefault("APP_PORT", strconv.Itoa(c.Service.Port))
@@ -88,6 +395,61 @@ func (c *config) Port() int {
 	return port
 }
 
+// validateService verifies that no timeout of the server or of a route is negative
+func (c *config) validateService() error {
+	sv := c.Service
+	if sv.ReadHeaderTimeout < 0 || sv.ReadTimeout < 0 || sv.WriteTimeout < 0 || sv.IdleTimeout < 0 || sv.ShutdownTimeout < 0 {
+		return fmt.Errorf("service: timeouts must not be negative")
+	}
+
+	for _, rt := range c.Routes {
+		if rt.Timeout < 0 {
+			return fmt.Errorf("route %s %s: timeout must not be negative", rt.Method, rt.Path)
+		}
+	}
+
+	return nil
+}
+
+// seconds returns the duration of the given seconds, or the default when they are not configured
+func seconds(s int, d time.Duration) time.Duration {
+	if s > 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	return d
+}
+
+// returns how long a client has to send the headers of a request
+func (c *config) ReadHeaderTimeout() time.Duration {
+	return seconds(c.Service.ReadHeaderTimeout, DefaultReadHeaderTimeout)
+}
+
+// returns how long a client has to send a whole request, unless its route sets a timeout of its own
+func (c *config) ReadTimeout() time.Duration {
+	return seconds(c.Service.ReadTimeout, DefaultReadTimeout)
+}
+
+// returns how long a response has to be written in, unless its route sets a timeout of its own
+func (c *config) WriteTimeout() time.Duration {
+	return seconds(c.Service.WriteTimeout, DefaultWriteTimeout)
+}
+
+// returns how long an idle keep-alive connection is kept open
+func (c *config) IdleTimeout() time.Duration {
+	return seconds(c.Service.IdleTimeout, DefaultIdleTimeout)
+}
+
+// returns how long requests in flight are given to finish when the server shuts down
+func (c *config) ShutdownTimeout() time.Duration {
+	return seconds(c.Service.ShutdownTimeout, DefaultShutdownTimeout)
+}
+
+// returns the bearer token that the admin routes are served with, empty when they are not served
+func (c *config) AdminToken() string {
+	return EnvOrDefault("ADMIN_TOKEN", c.Service.AdminToken)
+}
+
 // returns the routes from the configuration
 func (c *config) GetRoutes() []routes {
 	return c.Routes
@@ -150,6 +512,332 @@ func (c *config) RedisDB() int {
 	return c.Redis.Database
 }
 
+// returns the storage driver used to store uploads and processed outputs eg. local, s3
+func (c *config) StorageDriver() string {
+	return EnvOrDefault("STORAGE_DRIVER", c.Storage.Driver)
+}
+
+// returns the directory that the local storage driver keeps objects in
+func (c *config) LocalStorageRoot() string {
+	return EnvOrDefault("LOCAL_STORAGE_ROOT", c.Storage.Local.Root)
+}
+
+// returns the host and port of the S3 compatible object store
+func (c *config) S3Endpoint() string {
+	return EnvOrDefault("S3_ENDPOINT", c.Storage.S3.Endpoint)
+}
+
+func (c *config) S3Bucket() string {
+	return EnvOrDefault("S3_BUCKET", c.Storage.S3.Bucket)
+}
+
+func (c *config) S3Region() string {
+	return EnvOrDefault("S3_REGION", c.Storage.S3.Region)
+}
+
+func (c *config) S3AccessKey() string {
+	return EnvOrDefault("S3_ACCESS_KEY", c.Storage.S3.AccessKey)
+}
+
+func (c *config) S3SecretKey() string {
+	return EnvOrDefault("S3_SECRET_KEY", c.Storage.S3.SecretKey)
+}
+
+// returns whether the object store is reached over TLS
+func (c *config) S3UseSSL() bool {
+	ssl, err := strconv.ParseBool(EnvOrDefault("S3_USE_SSL", strconv.FormatBool(c.Storage.S3.UseSSL)))
+	if err != nil {
+		return c.Storage.S3.UseSSL
+	}
+
+	return ssl
+}
+
+// returns the maximum size in bytes of an uploaded file of the given type eg. image, video
+// falling back to the default maximum size when the type has no override
+func (c *config) MaxUploadSize(fileType string) int64 {
+	if s, ok := c.Uploads.MaxSizeByType[fileType]; ok && s > 0 {
+		return s
+	}
+
+	if c.Uploads.MaxSize > 0 {
+		return c.Uploads.MaxSize
+	}
+
+	return DefaultMaxUploadSize
+}
+
+// returns the largest size in bytes that an upload of any type may have
+// requests that are larger can be rejected before reading the body
+func (c *config) UploadSizeCeiling() int64 {
+	ceil := c.MaxUploadSize("")
+	for t := range c.Uploads.MaxSizeByType {
+		ceil = max(ceil, c.MaxUploadSize(t))
+	}
+
+	return ceil
+}
+
+// returns whether uploads whose content does not match their extension are rejected
+// by default the mismatch is only recorded on the file
+func (c *config) RejectExtensionMismatch() bool {
+	return c.Uploads.ExtensionMismatch == "reject"
+}
+
+// returns the supported media types, falling back to the built in ones when none are configured
+func (c *config) MediaTypes() []media.MediaType {
+	if len(c.Media) > 0 {
+		return c.Media
+	}
+
+	return media.DefaultTypes()
+}
+
+// validatePipelines verifies that every step of a pipeline names a known processor,
+// that resizes name a configured preset or carry valid options,
+// and that transcodes have configured profiles to transcode to
+func (c *config) validatePipelines() error {
+	for key, steps := range c.Pipelines {
+		for i, st := range steps {
+			if !media.IsProcessor(st.Processor) {
+				return fmt.Errorf("pipeline %q step %d has unknown processor %q", key, i, st.Processor)
+			}
+
+			if st.Processor == media.ProcessorTranscode {
+				if err := c.validateTranscodeStep(st); err != nil {
+					return fmt.Errorf("pipeline %q step %d: %v", key, i, err)
+				}
+				continue
+			}
+
+			if st.Processor != media.ProcessorResize {
+				if st.Preset != "" || st.Options != nil {
+					return fmt.Errorf("pipeline %q step %d: the %s processor takes no preset or options", key, i, st.Processor)
+				}
+				continue
+			}
+
+			if (st.Preset == "") == (st.Options == nil) {
+				return fmt.Errorf("pipeline %q step %d: a resize takes either a preset or options", key, i)
+			}
+
+			if _, ok := c.Presets[st.Preset]; st.Preset != "" && !ok {
+				return fmt.Errorf("pipeline %q step %d has unknown preset %q", key, i, st.Preset)
+			}
+
+			if st.Options != nil {
+				if err := st.Options.options().Validate(); err != nil {
+					return fmt.Errorf("pipeline %q step %d: %v", key, i, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateTranscodeStep verifies that a transcode names a configured profile,
+// or that there are profiles to transcode to when it transcodes to every profile
+func (c *config) validateTranscodeStep(st pipelineStep) error {
+	if st.Options != nil {
+		return fmt.Errorf("a transcode takes no options")
+	}
+
+	if _, ok := c.Profiles[st.Preset]; st.Preset != "" && !ok {
+		return fmt.Errorf("unknown transcode profile %q", st.Preset)
+	}
+
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("no transcode profiles are configured")
+	}
+
+	return nil
+}
+
+// returns the resize options of the named preset and whether the preset is configured
+func (c *config) Preset(name string) (lib.ResizeOptions, bool) {
+	p, ok := c.Presets[name]
+	if !ok {
+		return lib.ResizeOptions{}, false
+	}
+
+	return p.options(), true
+}
+
+// returns the named transcode profile and whether the profile is configured
+func (c *config) TranscodeProfile(name string) (lib.TranscodeProfile, bool) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return lib.TranscodeProfile{}, false
+	}
+
+	return p.profile(name), true
+}
+
+// returns every transcode profile ordered by name
+func (c *config) TranscodeProfiles() []lib.TranscodeProfile {
+	profiles := make([]lib.TranscodeProfile, 0, len(c.Profiles))
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		profiles = append(profiles, c.Profiles[name].profile(name))
+	}
+
+	return profiles
+}
+
+// returns the steps that an upload of the given mime type and category is run through
+// the pipeline of the mime type takes precedence over the pipeline of its category
+func (c *config) Pipeline(mimeType string, category string) []PipelineStep {
+	if c.Pipelines == nil {
+		return defaultPipelines[category]
+	}
+
+	steps, ok := c.Pipelines[mimeType]
+	if !ok {
+		steps = c.Pipelines[category]
+	}
+
+	pipeline := make([]PipelineStep, 0, len(steps))
+	for _, st := range steps {
+		ps := PipelineStep{Processor: st.Processor, Preset: st.Preset}
+		if st.Options != nil {
+			ps.Options = st.Options.options()
+		} else if st.Preset != "" && st.Processor == media.ProcessorResize {
+			ps.Options = c.Presets[st.Preset].options()
+		}
+
+		pipeline = append(pipeline, ps)
+	}
+
+	return pipeline
+}
+
+// returns the key that webhook deliveries are signed with
+func (c *config) WebhookSecret() string {
+	return EnvOrDefault("WEBHOOK_SECRET", c.Webhooks.Secret)
+}
+
+// returns how long a webhook has to answer a delivery
+func (c *config) WebhookTimeout() time.Duration {
+	if c.Webhooks.Timeout > 0 {
+		return time.Duration(c.Webhooks.Timeout) * time.Second
+	}
+
+	return DefaultWebhookTimeout
+}
+
+// returns the webhooks that receive the events of every file
+func (c *config) WebhookEndpoints() []events.Endpoint {
+	return c.Webhooks.Endpoints
+}
+
+// validateTasks verifies that the options of every task type can be enqueued with
+func (c *config) validateTasks() error {
+	for t, o := range c.Tasks {
+		if o.MaxRetry != nil && *o.MaxRetry < 0 {
+			return fmt.Errorf("task %q: max_retry must not be negative", t)
+		}
+
+		if o.Priority < 0 || o.Timeout < 0 || o.Deadline < 0 || o.Retention < 0 || o.Unique < 0 {
+			return fmt.Errorf("task %q: priority, timeout, deadline, retention and unique must not be negative", t)
+		}
+	}
+
+	return nil
+}
+
+// returns the options that tasks of the given type are enqueued with
+// options that are not configured fall back to their defaults
+func (c *config) TaskOptions(taskType string) TaskOptions {
+	o := c.Tasks[taskType]
+	to := TaskOptions{
+		Queue:     o.Queue,
+		Priority:  o.Priority,
+		MaxRetry:  DefaultTaskMaxRetry,
+		Timeout:   time.Duration(o.Timeout) * time.Second,
+		Deadline:  time.Duration(o.Deadline) * time.Second,
+		Retention: time.Duration(o.Retention) * time.Second,
+		Unique:    time.Duration(o.Unique) * time.Second,
+	}
+
+	if to.Queue == "" {
+		to.Queue = DefaultTaskQueue
+	}
+
+	if to.Priority == 0 {
+		to.Priority = DefaultTaskPriority
+	}
+
+	if o.MaxRetry != nil {
+		to.MaxRetry = *o.MaxRetry
+	}
+
+	if to.Timeout == 0 {
+		to.Timeout = DefaultTaskTimeout
+	}
+
+	return to
+}
+
+// validateWorker verifies that the worker takes tasks from the queue of every task type
+// Task types that are not configured are enqueued to the default queue, so it must be listed too
+func (c *config) validateWorker() error {
+	if c.Worker.Concurrency < 0 {
+		return fmt.Errorf("worker: concurrency must not be negative")
+	}
+
+	if len(c.Worker.Queues) == 0 {
+		return nil
+	}
+
+	for q, w := range c.Worker.Queues {
+		if q == "" || w <= 0 {
+			return fmt.Errorf("worker: queue %q must be named and have a weight greater than zero", q)
+		}
+	}
+
+	if _, ok := c.Worker.Queues[DefaultTaskQueue]; !ok {
+		return fmt.Errorf("worker: the %q queue must be listed", DefaultTaskQueue)
+	}
+
+	for t := range c.Tasks {
+		if q := c.TaskOptions(t).Queue; c.Worker.Queues[q] == 0 {
+			return fmt.Errorf("task %q: queue %q is not listed in the queues of the worker", t, q)
+		}
+	}
+
+	return nil
+}
+
+// returns the queues that tasks are taken from along with their weights
+// without configured queues, the weight of a queue is the highest priority of the task types routed to it
+func (c *config) Queues() map[string]int {
+	if len(c.Worker.Queues) > 0 {
+		return maps.Clone(c.Worker.Queues)
+	}
+
+	qs := map[string]int{DefaultTaskQueue: DefaultTaskPriority}
+	for t := range c.Tasks {
+		o := c.TaskOptions(t)
+		qs[o.Queue] = max(qs[o.Queue], o.Priority)
+	}
+
+	return qs
+}
+
+// returns how many tasks the worker processes at once
+func (c *config) WorkerConcurrency() int {
+	if c.Worker.Concurrency > 0 {
+		return c.Worker.Concurrency
+	}
+
+	return DefaultWorkerConcurrency
+}
+
+// returns whether queues of higher weight are emptied before queues of lower weight are taken from
+func (c *config) StrictPriority() bool {
+	return c.Worker.StrictPriority
+}
+
 func EnvOrDefault(key string, defaultValue string) string {
 	value, exists := os.LookupEnv(key)
 	if !exists {
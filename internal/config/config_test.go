@@ -2,7 +2,11 @@ package config
 
 import (
 	"os"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -136,4 +140,268 @@ func TestConfig(t *testing.T) {
 			assert.Equal(t, c.RedisDB(), 0)
 		})
 	})
+
+	t.Run("StorageDriver", func(t *testing.T) {
+		t.Run("Default Driver", func(t *testing.T) {
+			assert.Equal(t, c.StorageDriver(), "local")
+		})
+
+		t.Run("Set Driver", func(t *testing.T) {
+			os.Setenv("STORAGE_DRIVER", "s3")
+			assert.Equal(t, c.StorageDriver(), "s3")
+			os.Unsetenv("STORAGE_DRIVER")
+		})
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		t.Run("Default Settings", func(t *testing.T) {
+			assert.Equal(t, c.S3Endpoint(), "localhost:9000")
+			assert.Equal(t, c.S3Bucket(), "simple-file-processor")
+			assert.Equal(t, c.S3UseSSL(), false)
+		})
+
+		t.Run("Set UseSSL", func(t *testing.T) {
+			os.Setenv("S3_USE_SSL", "true")
+			assert.Equal(t, c.S3UseSSL(), true)
+			os.Unsetenv("S3_USE_SSL")
+		})
+	})
+
+	t.Run("MaxUploadSize", func(t *testing.T) {
+		t.Run("Type Override", func(t *testing.T) {
+			assert.Equal(t, c.MaxUploadSize("video"), int64(4294967296))
+		})
+
+		t.Run("Default Size", func(t *testing.T) {
+			assert.Equal(t, c.MaxUploadSize("other"), int64(10485760))
+		})
+
+		t.Run("Ceiling", func(t *testing.T) {
+			assert.Equal(t, c.UploadSizeCeiling(), int64(4294967296))
+		})
+
+		t.Run("Not Configured", func(t *testing.T) {
+			empty, err := FromJSON([]byte(`{}`))
+			assert.NoError(t, err)
+			assert.Equal(t, empty.MaxUploadSize("image"), DefaultMaxUploadSize)
+			assert.Equal(t, empty.UploadSizeCeiling(), DefaultMaxUploadSize)
+		})
+	})
+
+	t.Run("MediaTypes", func(t *testing.T) {
+		t.Run("Configured", func(t *testing.T) {
+			_, err := media.NewRegistry(c.MediaTypes())
+			assert.NoError(t, err)
+			assert.Len(t, c.MediaTypes(), len(media.DefaultTypes()))
+		})
+
+		t.Run("Not Configured", func(t *testing.T) {
+			empty, err := FromJSON([]byte(`{}`))
+			assert.NoError(t, err)
+			assert.Equal(t, media.DefaultTypes(), empty.MediaTypes())
+		})
+	})
+
+	t.Run("RejectExtensionMismatch", func(t *testing.T) {
+		t.Run("Record", func(t *testing.T) {
+			assert.False(t, c.RejectExtensionMismatch())
+		})
+
+		t.Run("Reject", func(t *testing.T) {
+			reject, err := FromJSON([]byte(`{"uploads": {"extension_mismatch": "reject"}}`))
+			assert.NoError(t, err)
+			assert.True(t, reject.RejectExtensionMismatch())
+		})
+	})
+
+	t.Run("Presets", func(t *testing.T) {
+		t.Run("Configured", func(t *testing.T) {
+			o, ok := c.Preset("thumbnail")
+			assert.True(t, ok)
+			assert.Equal(t, lib.ResizeOptions{Width: 150, Height: 150, Mode: "fill", Format: "webp"}, o)
+		})
+
+		t.Run("Unknown", func(t *testing.T) {
+			_, ok := c.Preset("unknown")
+			assert.False(t, ok)
+		})
+
+		t.Run("Invalid Options", func(t *testing.T) {
+			_, err := FromJSON([]byte(`{"presets": {"thumbnail": {"width": 150, "height": 150, "mode": "fil"}}}`))
+			assert.ErrorContains(t, err, `preset "thumbnail"`)
+		})
+
+		t.Run("Misspelt Option", func(t *testing.T) {
+			_, err := FromJSON([]byte(`{"presets": {"thumbnail": {"width": 150, "heigth": 150}}}`))
+			assert.ErrorContains(t, err, "heigth")
+		})
+	})
+
+	t.Run("Transcode Profiles", func(t *testing.T) {
+		t.Run("Configured", func(t *testing.T) {
+			p, ok := c.TranscodeProfile("720p")
+			assert.True(t, ok)
+			assert.Equal(t, lib.TranscodeProfile{Name: "720p", Format: "mp4", Width: 1280, Height: 720, VideoBitrate: 2500, AudioBitrate: 128}, p)
+
+			names := []string{}
+			for _, p := range c.TranscodeProfiles() {
+				names = append(names, p.Name)
+			}
+			assert.Equal(t, []string{"480p", "480p-webm", "720p"}, names)
+		})
+
+		t.Run("Unknown", func(t *testing.T) {
+			_, ok := c.TranscodeProfile("4k")
+			assert.False(t, ok)
+		})
+
+		t.Run("Invalid Profile", func(t *testing.T) {
+			_, err := FromJSON([]byte(`{"transcode_profiles": {"720p": {"format": "mkv", "height": 720, "video_bitrate": 2500}}}`))
+			assert.ErrorContains(t, err, `transcode profile "720p"`)
+		})
+
+		t.Run("Misspelt Option", func(t *testing.T) {
+			_, err := FromJSON([]byte(`{"transcode_profiles": {"720p": {"format": "mp4", "height": 720, "bitrate": 2500}}}`))
+			assert.ErrorContains(t, err, "bitrate")
+		})
+	})
+
+	t.Run("Pipeline", func(t *testing.T) {
+		t.Run("Configured", func(t *testing.T) {
+			steps := c.Pipeline("image/jpeg", media.CategoryImage)
+			assert.Len(t, steps, 2)
+			assert.Equal(t, PipelineStep{Processor: media.ProcessorResize, Preset: "thumbnail", Options: lib.ResizeOptions{Width: 150, Height: 150, Mode: "fill", Format: "webp"}}, steps[0])
+			assert.Equal(t, lib.ResizeOptions{Width: 1024, Mode: "scale", Format: "webp"}, steps[1].Options)
+			assert.Empty(t, c.Pipeline("application/pdf", media.CategoryDocument))
+		})
+
+		t.Run("Mime Type Override", func(t *testing.T) {
+			mt, err := FromJSON([]byte(`{"pipelines": {"video": [{"processor": "video_metadata"}], "video/webm": []}}`))
+			assert.NoError(t, err)
+			assert.Len(t, mt.Pipeline("video/mp4", media.CategoryVideo), 1)
+			assert.Empty(t, mt.Pipeline("video/webm", media.CategoryVideo))
+		})
+
+		t.Run("Not Configured", func(t *testing.T) {
+			empty, err := FromJSON([]byte(`{}`))
+			assert.NoError(t, err)
+			assert.Equal(t, []PipelineStep{{Processor: media.ProcessorVideoMetadata}}, empty.Pipeline("video/mp4", media.CategoryVideo))
+			assert.Empty(t, empty.Pipeline("image/png", media.CategoryImage))
+		})
+
+		t.Run("Transcode", func(t *testing.T) {
+			tc, err := FromJSON([]byte(`{"transcode_profiles": {"720p": {"format": "mp4", "height": 720, "video_bitrate": 2500}}, "presets": {"720p": {"width": 1280, "height": 720}},
+				"pipelines": {"video": [{"processor": "transcode", "preset": "720p"}, {"processor": "transcode"}]}}`))
+			assert.NoError(t, err)
+			assert.Equal(t, []PipelineStep{{Processor: media.ProcessorTranscode, Preset: "720p"}, {Processor: media.ProcessorTranscode}}, tc.Pipeline("video/mp4", media.CategoryVideo))
+		})
+
+		t.Run("Invalid", func(t *testing.T) {
+			for _, conf := range []string{
+				`{"pipelines": {"video": [{"processor": "transcode"}]}}`,
+				`{"transcode_profiles": {"720p": {"format": "mp4", "height": 720, "video_bitrate": 2500}}, "pipelines": {"video": [{"processor": "transcode", "preset": "1080p"}]}}`,
+				`{"transcode_profiles": {"720p": {"format": "mp4", "height": 720, "video_bitrate": 2500}}, "pipelines": {"video": [{"processor": "transcode", "options": {"width": 100}}]}}`,
+				`{"pipelines": {"image": [{"processor": "exif"}]}}`,
+				`{"pipelines": {"image": [{"processor": "resize"}]}}`,
+				`{"pipelines": {"image": [{"processor": "resize", "preset": "banner"}]}}`,
+				`{"pipelines": {"image": [{"processor": "resize", "options": {"width": 0}}]}}`,
+				`{"pipelines": {"video": [{"processor": "video_metadata", "preset": "thumbnail"}]}}`,
+			} {
+				_, err := FromJSON([]byte(conf))
+				assert.ErrorContains(t, err, "pipeline", conf)
+			}
+		})
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		t.Run("Configured", func(t *testing.T) {
+			os.Unsetenv("WEBHOOK_SECRET")
+			assert.Equal(t, "change-me", c.WebhookSecret())
+			assert.Equal(t, 10*time.Second, c.WebhookTimeout())
+		})
+
+		t.Run("Set Secret", func(t *testing.T) {
+			os.Setenv("WEBHOOK_SECRET", "test")
+			assert.Equal(t, "test", c.WebhookSecret())
+			os.Unsetenv("WEBHOOK_SECRET")
+		})
+
+		t.Run("Endpoints", func(t *testing.T) {
+			wh, err := FromJSON([]byte(`{"webhooks": {"endpoints": [{"url": "https://example.com/hooks", "events": ["job.failed"]}]}}`))
+			assert.NoError(t, err)
+			assert.Equal(t, []events.Endpoint{{URL: "https://example.com/hooks", Events: []string{"job.failed"}}}, wh.WebhookEndpoints())
+			assert.Equal(t, DefaultWebhookTimeout, wh.WebhookTimeout())
+		})
+
+		t.Run("Invalid Endpoint", func(t *testing.T) {
+			_, err := FromJSON([]byte(`{"webhooks": {"endpoints": [{"url": "https://example.com/hooks", "events": ["file.deleted"]}]}}`))
+			assert.Error(t, err)
+		})
+	})
+
+	t.Run("Task Options", func(t *testing.T) {
+		tc, err := FromJSON([]byte(`{"tasks": {
+			"image:resize": {"queue": "critical", "priority": 6, "timeout": 30, "retention": 3600, "unique": 300},
+			"video:extract-metadata": {"queue": "low", "max_retry": 0, "timeout": 900, "deadline": 7200},
+			"webhook:deliver": {"max_retry": 5}
+		}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, TaskOptions{Queue: "critical", Priority: 6, MaxRetry: DefaultTaskMaxRetry, Timeout: 30 * time.Second, Retention: time.Hour, Unique: 5 * time.Minute}, tc.TaskOptions("image:resize"))
+		assert.Equal(t, TaskOptions{Queue: "low", Priority: DefaultTaskPriority, MaxRetry: 0, Timeout: 15 * time.Minute, Deadline: 2 * time.Hour}, tc.TaskOptions("video:extract-metadata"))
+		assert.Equal(t, TaskOptions{Queue: DefaultTaskQueue, Priority: DefaultTaskPriority, MaxRetry: 5, Timeout: DefaultTaskTimeout}, tc.TaskOptions("webhook:deliver"))
+		assert.Equal(t, TaskOptions{Queue: DefaultTaskQueue, Priority: DefaultTaskPriority, MaxRetry: DefaultTaskMaxRetry, Timeout: DefaultTaskTimeout}, tc.TaskOptions("unknown"))
+		assert.Equal(t, map[string]int{"critical": 6, "low": 1, "default": 1}, tc.Queues())
+
+		_, err = FromJSON([]byte(`{"tasks": {"image:resize": {"max_retries": 5}}}`))
+		assert.Error(t, err)
+
+		_, err = FromJSON([]byte(`{"tasks": {"image:resize": {"timeout": -1}}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Timeouts", func(t *testing.T) {
+		assert.Equal(t, 10*time.Second, c.ReadHeaderTimeout())
+		assert.Equal(t, time.Minute, c.ReadTimeout())
+		assert.Equal(t, time.Minute, c.WriteTimeout())
+		assert.Equal(t, 2*time.Minute, c.IdleTimeout())
+		assert.Equal(t, 30*time.Second, c.ShutdownTimeout())
+
+		tc, err := FromJSON([]byte(`{"service": {"read_timeout": 5, "shutdown_timeout": 90}, "routes": [{"path": "/file/upload", "timeout": 3600}]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Second, tc.ReadTimeout())
+		assert.Equal(t, 90*time.Second, tc.ShutdownTimeout())
+		assert.Equal(t, DefaultWriteTimeout, tc.WriteTimeout())
+		assert.Equal(t, 3600, tc.GetRoutes()[0].Timeout)
+
+		_, err = FromJSON([]byte(`{"service": {"write_timeout": -1}}`))
+		assert.Error(t, err)
+		_, err = FromJSON([]byte(`{"routes": [{"path": "/file/upload", "timeout": -1}]}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Worker", func(t *testing.T) {
+		assert.Equal(t, DefaultWorkerConcurrency, c.WorkerConcurrency())
+		assert.False(t, c.StrictPriority())
+		assert.Equal(t, "critical", c.TaskOptions("image:resize").Queue, "interactive resizes do not wait behind videos")
+		assert.Equal(t, map[string]int{"critical": 6, "default": 3, "low": 1}, c.Queues())
+
+		wc, err := FromJSON([]byte(`{
+			"tasks": {"image:resize": {"queue": "critical", "priority": 2}, "video:extract-metadata": {"queue": "low"}},
+			"worker": {"concurrency": 4, "queues": {"critical": 6, "default": 3, "low": 1}, "strict_priority": true}
+		}`))
+		assert.NoError(t, err)
+		assert.Equal(t, 4, wc.WorkerConcurrency())
+		assert.True(t, wc.StrictPriority())
+		assert.Equal(t, map[string]int{"critical": 6, "default": 3, "low": 1}, wc.Queues(), "configured weights take precedence over priorities")
+
+		for name, conf := range map[string]string{
+			"negative concurrency": `{"worker": {"concurrency": -1}}`,
+			"zero weight":          `{"worker": {"queues": {"default": 0}}}`,
+			"no default queue":     `{"worker": {"queues": {"critical": 6}}}`,
+			"unlisted task queue":  `{"tasks": {"image:resize": {"queue": "critical"}}, "worker": {"queues": {"default": 1}}}`,
+		} {
+			_, err := FromJSON([]byte(conf))
+			assert.Error(t, err, name)
+		}
+	})
 }
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks the position of the last record returned in a page
+// Records are ordered by creation time and then by ID so that the
+// position is stable even when several files share a timestamp
+type cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// encodeCursor encodes the cursor as an opaque url safe string
+func encodeCursor(c cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor decodes a cursor that was previously produced by encodeCursor
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+
+	return cursor{CreatedAt: t, ID: parts[1]}, nil
+}
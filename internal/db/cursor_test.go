@@ -0,0 +1,25 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func Test_Cursor_WhenEncoded_DecodesToSamePosition(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := cursor{CreatedAt: time.Date(2025, 2, 17, 19, 40, 6, 612592000, time.UTC), ID: "a0de50ee-d9f6-4fc3-8b26-16242724f0e9"}
+	got, err := decodeCursor(encodeCursor(c))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.ID).To(gomega.Equal(c.ID))
+	g.Expect(got.CreatedAt.Equal(c.CreatedAt)).To(gomega.BeTrue())
+}
+
+func Test_DecodeCursor_WhenMalformed_ReturnsErrInvalidCursor(t *testing.T) {
+	g := gomega.NewWithT(t)
+	for _, s := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXxpZA"} {
+		_, err := decodeCursor(s)
+		g.Expect(err).To(gomega.Equal(ErrInvalidCursor))
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"simple-file-processor/internal/models"
 	"time"
@@ -8,6 +10,8 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DB struct {
@@ -19,9 +23,32 @@ type Database interface {
 	Migrate() error
 	InsertFileMetadata(*models.File) error
 	AddProcessedOutput(string, models.ProcessedOutput) error
+	AddProcessedOutputs(string, []models.ProcessedOutput) error
 	FileByID(string) (*models.File, error)
+	Files(models.FileFilter) (*models.FilePage, error)
+	UpdateFile(string, map[string]interface{}) error
+	AdvanceUploadOffset(id string, from int64, to int64) error
+	DeleteFile(string) error
+	InsertJob(*models.Job) error
+	UpdateJob(string, map[string]interface{}) error
+	JobByID(string) (*models.Job, error)
+	JobsByFileID(string) ([]models.Job, error)
+	ActiveJob(fid string, taskType string, payload []byte) (*models.Job, error)
+	DeleteJob(string) error
+	TransitionFile(string, string, models.StatusTransition) error
+	StatusTransitions(string) ([]models.StatusTransition, error)
+	InsertWebhookDelivery(*models.WebhookDelivery) error
+	WebhookDeliveries(string) ([]models.WebhookDelivery, error)
 }
 
+// ErrOffsetConflict is returned when the upload offset was moved by another request
+var ErrOffsetConflict = errors.New("upload offset conflict")
+
+const (
+	DefaultPageSize = 20  // The number of files returned when no limit is given
+	MaxPageSize     = 100 // The maximum number of files returned in a single page
+)
+
 // NewDB creates a new database instance with the given configuration and gorm instance
 // the gorm instance is passed as an interface to allow for mocking in tests
 func NewDB(gdb GormDB, l *zerolog.Logger) Database {
@@ -31,7 +58,7 @@ func NewDB(gdb GormDB, l *zerolog.Logger) Database {
 func (db DB) Migrate() error {
 	// Perform database migrations here
 	db.Log.Info().Msg("Migrating database")
-	err := db.Gdb.AutoMigrate(&models.File{})
+	err := db.Gdb.AutoMigrate(&models.File{}, &models.Job{}, &models.StatusTransition{}, &models.WebhookDelivery{})
 
 	if err != nil {
 		db.Log.Error().Err(err).Msg("Failed to migrate database")
@@ -58,31 +85,47 @@ func (db DB) InsertFileMetadata(f *models.File) error {
 
 // Adds a processed output to the file
 func (db DB) AddProcessedOutput(fid string, po models.ProcessedOutput) error {
+	return db.AddProcessedOutputs(fid, []models.ProcessedOutput{po})
+}
+
+// Adds the processed outputs to the file in a single update
+// so that the outputs of a batch appear together
+// The outputs are appended to the stored ones by the database, since outputs
+// read and written back by concurrent tasks would overwrite each other
+func (db DB) AddProcessedOutputs(fid string, pos []models.ProcessedOutput) error {
 	// Adding a processed output to a file does not create a new record in the database
 	// Instead, it updates the existing file record with the new processed output
 	// Set the ID and timestamps for the processed output
-	if po.ID == uuid.Nil {
-		db.Log.Info().Msg("Setting ID for processed output")
-		po.ID = uuid.New()
-	}
+	for i := range pos {
+		if pos[i].ID == uuid.Nil {
+			db.Log.Info().Msg("Setting ID for processed output")
+			pos[i].ID = uuid.New()
+		}
 
-	po.CreatedAt = time.Now()
-	po.UpdatedAt = time.Now()
+		pos[i].CreatedAt = time.Now()
+		pos[i].UpdatedAt = time.Now()
+	}
 
-	// Add the processed output to the file
-	db.Log.Info().Msg(fmt.Sprintf("Adding processed output to file: %s", fid))
-	f, err := db.FileByID(fid)
+	b, err := json.Marshal(pos)
 	if err != nil {
 		return err
 	}
 
-	f.ProcessedOutputs = append(f.ProcessedOutputs, po)
-	if err := db.Gdb.Model(f).Update("processed_outputs", f.ProcessedOutputs).Error; err != nil {
-		db.Log.Error().Err(err).Msg("Failed to add processed output to file")
-		return err
+	// Add the processed output to the file
+	db.Log.Info().Msg(fmt.Sprintf("Adding %d processed outputs to file: %s", len(pos), fid))
+	res := db.Gdb.Model(&models.File{}).Where("id = ?", fid).
+		Update("processed_outputs", gorm.Expr("COALESCE(processed_outputs, '[]'::jsonb) || ?::jsonb", string(b)))
+	if res.Error != nil {
+		db.Log.Error().Err(res.Error).Msg("Failed to add processed output to file")
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		db.Log.Error().Msg("Failed to add processed output to missing file: " + fid)
+		return gorm.ErrRecordNotFound
 	}
 
-	db.Log.Info().Msg(fmt.Sprintf("Processed output added to file: %s", f.OriginalName))
+	db.Log.Info().Msg(fmt.Sprintf("Processed output added to file: %s", fid))
 	return nil
 }
 
@@ -98,3 +141,258 @@ func (db DB) FileByID(id string) (*models.File, error) {
 	db.Log.Info().Msg(fmt.Sprintf("File with ID: %s found", id))
 	return f, nil
 }
+
+// Files returns a page of files matching the given filter
+// Pagination is cursor based, ordered by creation time and ID from newest to oldest
+func (db DB) Files(ff models.FileFilter) (*models.FilePage, error) {
+	limit := ff.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	q := db.Gdb.Model(&models.File{})
+	if ff.Type != "" {
+		q = q.Where("type = ?", ff.Type)
+	}
+
+	if ff.Status != "" {
+		q = q.Where("status = ?", ff.Status)
+	}
+
+	if ff.MimeType != "" {
+		q = q.Where("mime_type = ?", ff.MimeType)
+	}
+
+	if ff.CreatedAfter != nil {
+		q = q.Where("created_at >= ?", *ff.CreatedAfter)
+	}
+
+	if ff.CreatedBefore != nil {
+		q = q.Where("created_at < ?", *ff.CreatedBefore)
+	}
+
+	// Files that never changed status have been in it since they were created
+	if ff.StatusChangedBefore != nil {
+		q = q.Where("COALESCE(status_changed_at, created_at) < ?", *ff.StatusChangedBefore)
+	}
+
+	// Continue after the last file of the previous page
+	if ff.Cursor != "" {
+		c, err := decodeCursor(ff.Cursor)
+		if err != nil {
+			db.Log.Error().Err(err).Msg("Failed to decode file cursor")
+			return nil, err
+		}
+
+		q = q.Where("(created_at, id) < (?, ?)", c.CreatedAt, c.ID)
+	}
+
+	// Fetch one extra record to find out whether there is a next page
+	files := []models.File{}
+	if err := q.Order("created_at DESC").Order("id DESC").Limit(limit + 1).Find(&files).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to list files")
+		return nil, err
+	}
+
+	page := &models.FilePage{Files: files}
+	if len(files) > limit {
+		page.Files = files[:limit]
+		last := page.Files[limit-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	db.Log.Info().Msg(fmt.Sprintf("Listed %d files", len(page.Files)))
+	return page, nil
+}
+
+// UpdateFile updates the given columns of the file with the given ID
+func (db DB) UpdateFile(id string, fields map[string]interface{}) error {
+	db.Log.Info().Msg(fmt.Sprintf("Updating file with ID: %s", id))
+	res := db.Gdb.Model(&models.File{}).Where("id = ?", id).Updates(fields)
+	if res.Error != nil {
+		db.Log.Error().Err(res.Error).Msg("Failed to update file")
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// AdvanceUploadOffset moves the upload offset of a file forward
+// The offset is only moved when it still matches the expected offset,
+// so that concurrent requests for the same upload cannot both succeed
+func (db DB) AdvanceUploadOffset(id string, from int64, to int64) error {
+	res := db.Gdb.Model(&models.File{}).Where("id = ? AND upload_offset = ?", id, from).Update("upload_offset", to)
+	if res.Error != nil {
+		db.Log.Error().Err(res.Error).Msg("Failed to advance upload offset")
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrOffsetConflict
+	}
+
+	db.Log.Info().Msg(fmt.Sprintf("Advanced upload offset of file %s to %d", id, to))
+	return nil
+}
+
+// DeleteFile deletes the file record with the given ID
+func (db DB) DeleteFile(id string) error {
+	db.Log.Info().Msg(fmt.Sprintf("Deleting file with ID: %s", id))
+	if err := db.Gdb.Model(&models.File{}).Delete(&models.File{}, "id = ?", id).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to delete file")
+		return err
+	}
+
+	return nil
+}
+
+// InsertJob records a newly enqueued job
+func (db DB) InsertJob(j *models.Job) error {
+	db.Log.Info().Msg(fmt.Sprintf("Inserting job %s of type %s for file: %s", j.ID, j.TaskType, j.FileID))
+	if err := db.Gdb.Create(j).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to insert job into the database")
+		return err
+	}
+
+	return nil
+}
+
+// UpdateJob updates the given columns of the job with the given ID
+func (db DB) UpdateJob(id string, fields map[string]interface{}) error {
+	db.Log.Info().Msg(fmt.Sprintf("Updating job with ID: %s", id))
+	res := db.Gdb.Model(&models.Job{}).Where("id = ?", id).Updates(fields)
+	if res.Error != nil {
+		db.Log.Error().Err(res.Error).Msg("Failed to update job")
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// JobByID returns the job with the given ID
+func (db DB) JobByID(id string) (*models.Job, error) {
+	j := &models.Job{}
+	if err := db.Gdb.Model(j).First(j, "id = ?", id).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to get job by ID")
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// JobsByFileID returns the jobs of the file with the given ID from newest to oldest
+func (db DB) JobsByFileID(fid string) ([]models.Job, error) {
+	jobs := []models.Job{}
+	if err := db.Gdb.Model(&models.Job{}).Where("file_id = ?", fid).Order("created_at DESC").Find(&jobs).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to list jobs of file")
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ActiveJob returns the newest job of the file with the given task type and payload that is yet to complete or fail
+func (db DB) ActiveJob(fid string, taskType string, payload []byte) (*models.Job, error) {
+	j := &models.Job{}
+	err := db.Gdb.Model(j).
+		Where("file_id = ? AND task_type = ? AND payload = ?::jsonb", fid, taskType, string(payload)).
+		Where("state IN ?", []string{models.JobQueued, models.JobRunning, models.JobRetrying}).
+		Order("created_at DESC").
+		First(j).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// DeleteJob deletes the job with the given ID
+func (db DB) DeleteJob(id string) error {
+	db.Log.Info().Msg(fmt.Sprintf("Deleting job with ID: %s", id))
+	if err := db.Gdb.Model(&models.Job{}).Delete(&models.Job{}, "id = ?", id).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to delete job")
+		return err
+	}
+
+	return nil
+}
+
+// TransitionFile moves the file with the given ID to the given status and records the transition
+// The file is locked while its status is read, so that concurrent transitions are validated in turn
+// Staying in the same status is not recorded
+func (db DB) TransitionFile(id string, to string, st models.StatusTransition) error {
+	return db.Gdb.Transaction(func(tx *gorm.DB) error {
+		f := &models.File{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(f, "id = ?", id).Error; err != nil {
+			db.Log.Error().Err(err).Msg("Failed to get file to transition")
+			return err
+		}
+
+		if err := models.ValidateTransition(f.Status, to); err != nil {
+			return err
+		}
+
+		if f.Status == to {
+			return nil
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.File{}).Where("id = ?", id).Updates(map[string]interface{}{"status": to, "status_changed_at": now}).Error; err != nil {
+			db.Log.Error().Err(err).Msg("Failed to update file status")
+			return err
+		}
+
+		st.FileID, st.From, st.To, st.CreatedAt = id, f.Status, to, now
+		if err := tx.Create(&st).Error; err != nil {
+			db.Log.Error().Err(err).Msg("Failed to record status transition")
+			return err
+		}
+
+		db.Log.Info().Msg(fmt.Sprintf("File %s moved from %s to %s", id, st.From, to))
+		return nil
+	})
+}
+
+// StatusTransitions returns the status transitions of the file with the given ID from oldest to newest
+func (db DB) StatusTransitions(fid string) ([]models.StatusTransition, error) {
+	sts := []models.StatusTransition{}
+	if err := db.Gdb.Model(&models.StatusTransition{}).Where("file_id = ?", fid).Order("created_at ASC").Find(&sts).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to list status transitions of file")
+		return nil, err
+	}
+
+	return sts, nil
+}
+
+// InsertWebhookDelivery records an attempt to deliver an event to a webhook
+func (db DB) InsertWebhookDelivery(d *models.WebhookDelivery) error {
+	if err := db.Gdb.Create(d).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to insert webhook delivery into the database")
+		return err
+	}
+
+	return nil
+}
+
+// WebhookDeliveries returns the webhook deliveries of the file with the given ID from newest to oldest
+func (db DB) WebhookDeliveries(fid string) ([]models.WebhookDelivery, error) {
+	ds := []models.WebhookDelivery{}
+	if err := db.Gdb.Model(&models.WebhookDelivery{}).Where("file_id = ?", fid).Order("created_at DESC").Find(&ds).Error; err != nil {
+		db.Log.Error().Err(err).Msg("Failed to list webhook deliveries of file")
+		return nil, err
+	}
+
+	return ds, nil
+}
@@ -26,7 +26,7 @@ func Test_Migrate_WhenCalled_ReturnsNil(t *testing.T) {
 	db := new(mockdb.GormDB)
 	g := gomega.NewWithT(t)
 	gdb := NewDB(db, &l)
-	db.On("AutoMigrate", &models.File{}).Return(nil)
+	db.On("AutoMigrate", &models.File{}, &models.Job{}, &models.StatusTransition{}, &models.WebhookDelivery{}).Return(nil)
 	err := gdb.Migrate()
 	g.Expect(err).To(gomega.BeNil())
 }
@@ -35,7 +35,7 @@ func Test_Migrate_WhenErrorAutoMigrate_ReturnsError(t *testing.T) {
 	db := new(mockdb.GormDB)
 	g := gomega.NewWithT(t)
 	gdb := NewDB(db, &l)
-	db.On("AutoMigrate", &models.File{}).Return(errors.New("error"))
+	db.On("AutoMigrate", &models.File{}, &models.Job{}, &models.StatusTransition{}, &models.WebhookDelivery{}).Return(errors.New("error"))
 	err := gdb.Migrate()
 	g.Expect(err).NotTo(gomega.BeNil())
 }
@@ -65,3 +65,36 @@ func Test_InsertFileMetadata_WhenError_ReturnsError(t *testing.T) {
 	err := gdb.InsertFileMetadata(file)
 	g.Expect(err).NotTo(gomega.BeNil())
 }
+
+func Test_InsertJob_WhenNoError_ReturnsNil(t *testing.T) {
+	db := new(mockdb.GormDB)
+	g := gomega.NewWithT(t)
+	gdb := NewDB(db, &l)
+	job := &models.Job{ID: "job-id", FileID: "file-id", TaskType: "image:resize"}
+
+	db.On("Create", job).Return(&gorm.DB{Error: nil})
+	err := gdb.InsertJob(job)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func Test_InsertWebhookDelivery_WhenError_ReturnsError(t *testing.T) {
+	db := new(mockdb.GormDB)
+	g := gomega.NewWithT(t)
+	gdb := NewDB(db, &l)
+	d := &models.WebhookDelivery{EventID: "event-id", FileID: "file-id", URL: "https://example.com/hooks", Attempt: 1}
+
+	db.On("Create", d).Return(&gorm.DB{Error: errors.New("error")})
+	err := gdb.InsertWebhookDelivery(d)
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func Test_InsertJob_WhenError_ReturnsError(t *testing.T) {
+	db := new(mockdb.GormDB)
+	g := gomega.NewWithT(t)
+	gdb := NewDB(db, &l)
+	job := &models.Job{ID: "job-id", FileID: "file-id", TaskType: "image:resize"}
+
+	db.On("Create", job).Return(&gorm.DB{Error: errors.New("error")})
+	err := gdb.InsertJob(job)
+	g.Expect(err).NotTo(gomega.BeNil())
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"database/sql"
+
 	"gorm.io/gorm"
 )
 
@@ -10,6 +12,7 @@ type GormDB interface {
 	Create(interface{}) *gorm.DB
 	AutoMigrate(...interface{}) error
 	Model(value interface{}) *gorm.DB
+	Transaction(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error
 }
 
 type gormDB struct {
@@ -33,3 +36,7 @@ func (gdb gormDB) AutoMigrate(value ...interface{}) error {
 func (gdb gormDB) Model(value interface{}) *gorm.DB {
 	return gdb.db.Model(value)
 }
+
+func (gdb gormDB) Transaction(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	return gdb.db.Transaction(fc, opts...)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-file-processor/internal/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// outputsConnector is an in memory database holding the processed outputs of files
+// Each statement is applied atomically, as Postgres applies an update to a single row
+type outputsConnector struct {
+	mu      sync.Mutex
+	outputs map[string][]json.RawMessage
+}
+
+type outputsConn struct {
+	c *outputsConnector
+}
+
+func (c *outputsConnector) Connect(context.Context) (driver.Conn, error) {
+	return &outputsConn{c: c}, nil
+}
+
+func (c *outputsConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *outputsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *outputsConn) Close() error              { return nil }
+func (c *outputsConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *outputsConn) Commit() error             { return nil }
+func (c *outputsConn) Rollback() error           { return nil }
+
+// ExecContext appends the outputs of the update to the outputs of its file
+// Anything but an append fails, so that outputs read and written back are caught
+func (c *outputsConn) ExecContext(_ context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.HasPrefix(q, `UPDATE "files" SET "processed_outputs"=COALESCE(processed_outputs, '[]'::jsonb) || $1::jsonb`) {
+		return nil, fmt.Errorf("unexpected statement: %s", q)
+	}
+
+	var pos []json.RawMessage
+	if err := json.Unmarshal([]byte(args[0].Value.(string)), &pos); err != nil {
+		return nil, err
+	}
+
+	c.c.mu.Lock()
+	defer c.c.mu.Unlock()
+	id := args[len(args)-1].Value.(string)
+	existing, ok := c.c.outputs[id]
+	if !ok {
+		return driver.RowsAffected(0), nil
+	}
+
+	c.c.outputs[id] = append(existing, pos...)
+	return driver.RowsAffected(1), nil
+}
+
+func newOutputsDB(t *testing.T, c *outputsConnector) Database {
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(c)}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewDB(NewGormDB(gdb), &l)
+}
+
+func Test_AddProcessedOutputs_WhenCalledConcurrently_KeepsEveryOutput(t *testing.T) {
+	g := gomega.NewWithT(t)
+	c := &outputsConnector{outputs: map[string][]json.RawMessage{"file-id": {}}}
+	gdb := newOutputsDB(t, c)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- gdb.AddProcessedOutputs("file-id", []models.ProcessedOutput{
+				{Name: fmt.Sprintf("small_%d.webp", i)},
+				{Name: fmt.Sprintf("large_%d.webp", i)},
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		g.Expect(err).To(gomega.BeNil())
+	}
+
+	g.Expect(c.outputs["file-id"]).To(gomega.HaveLen(20))
+}
+
+func Test_AddProcessedOutput_WhenFileMissing_ReturnsNotFound(t *testing.T) {
+	g := gomega.NewWithT(t)
+	gdb := newOutputsDB(t, &outputsConnector{outputs: map[string][]json.RawMessage{}})
+
+	err := gdb.AddProcessedOutput("missing-file-id", models.ProcessedOutput{Name: "resized.jpg"})
+	g.Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(gomega.BeTrue())
+}
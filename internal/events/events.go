@@ -0,0 +1,61 @@
+package events
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the events sent to webhooks
+const (
+	FileUploaded  = "file.uploaded"  // A file was uploaded and stored
+	OutputCreated = "output.created" // A processed output was added to a file
+	JobFailed     = "job.failed"     // A task of a file failed and will not be retried
+)
+
+// Types of the events only sent to the subscribers of the stream of a file
+const (
+	StatusChanged = "status.changed" // The status of a file changed
+)
+
+var types = []string{FileUploaded, OutputCreated, JobFailed}
+
+// Event describes something that happened to a file
+type Event struct {
+	ID        string    `json:"id"`         // e.g. a unique id that receivers can deduplicate deliveries with
+	Type      string    `json:"type"`       // e.g. output.created
+	FileID    string    `json:"file_id"`    // e.g. the file the event happened to
+	CreatedAt time.Time `json:"created_at"` // e.g. when the event happened
+	Data      any       `json:"data"`       // e.g. the file, the processed output or the failed job
+}
+
+// New creates an event of the given type for the file
+func New(eventType string, fid string, data any) Event {
+	return Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		FileID:    fid,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// NewStatusChanged creates the event announcing that the file moved to the given status
+func NewStatusChanged(fid string, status string, jobID string, reason string) Event {
+	return New(StatusChanged, fid, map[string]string{
+		"status": status,
+		"job_id": jobID,
+		"reason": reason,
+	})
+}
+
+// Types returns every type of event that may be sent to webhooks
+func Types() []string {
+	return append([]string(nil), types...)
+}
+
+// IsWebhookEvent reports whether events of the given type are sent to webhooks
+func IsWebhookEvent(eventType string) bool {
+	return slices.Contains(types, eventType)
+}
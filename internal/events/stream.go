@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// The prefix of the redis channel that the events of a file are published to
+const channelPrefix = "file-events:"
+
+// Stream carries the events of files from the process that produces them,
+// e.g. the worker, to the processes that subscribed to the file, e.g. the API
+type Stream interface {
+	// Publish sends the event to every subscriber of its file
+	Publish(ctx context.Context, e Event) error
+	// Subscribe returns the events of the file published from now on
+	// The channel is closed once the context is done
+	Subscribe(ctx context.Context, fid string) (<-chan Event, error)
+}
+
+type redisStream struct {
+	rdb redis.UniversalClient
+	log *zerolog.Logger
+}
+
+// NewRedisStream creates a stream that fans events out through redis pub/sub
+func NewRedisStream(rdb redis.UniversalClient, l *zerolog.Logger) Stream {
+	return &redisStream{rdb: rdb, log: l}
+}
+
+func (s *redisStream) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Publish(ctx, channelPrefix+e.FileID, body).Err()
+}
+
+func (s *redisStream) Subscribe(ctx context.Context, fid string) (<-chan Event, error) {
+	ps := s.rdb.Subscribe(ctx, channelPrefix+fid)
+
+	// Wait for the subscription to be confirmed so that no event published after this returns is missed
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	evs := make(chan Event)
+	go func() {
+		defer close(evs)
+		defer ps.Close()
+
+		msgs := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				var e Event
+				if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+					s.log.Error().Err(err).Str("file_id", fid).Msg("Failed to decode event of file")
+					continue
+				}
+
+				select {
+				case evs <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return evs, nil
+}
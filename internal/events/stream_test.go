@@ -0,0 +1,47 @@
+package events_test
+
+import (
+	"context"
+	"simple-file-processor/internal/events"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisStream(t *testing.T) {
+	log := zerolog.Nop()
+	mr := miniredis.RunT(t)
+	s := events.NewRedisStream(redis.NewClient(&redis.Options{Addr: mr.Addr()}), &log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	evs, err := s.Subscribe(ctx, "file-id")
+	assert.NoError(t, err)
+
+	// Only the events of the subscribed file are received
+	assert.NoError(t, s.Publish(context.Background(), events.New(events.OutputCreated, "other-file-id", nil)))
+	published := events.NewStatusChanged("file-id", "processing", "job-id", "job started")
+	assert.NoError(t, s.Publish(context.Background(), published))
+
+	select {
+	case e := <-evs:
+		assert.Equal(t, published.ID, e.ID)
+		assert.Equal(t, events.StatusChanged, e.Type)
+		assert.Equal(t, "file-id", e.FileID)
+		assert.Equal(t, map[string]interface{}{"status": "processing", "job_id": "job-id", "reason": "job started"}, e.Data)
+	case <-time.After(time.Second):
+		t.Fatal("event was not received")
+	}
+
+	// The channel is closed once the subscriber is done
+	cancel()
+	select {
+	case _, ok := <-evs:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed")
+	}
+}
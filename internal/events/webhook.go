@@ -0,0 +1,201 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every webhook delivery
+const (
+	SignatureHeader = "X-Webhook-Signature" // e.g. sha256=<hex encoded HMAC-SHA256 of the body>
+	EventHeader     = "X-Webhook-Event"     // e.g. output.created
+	IDHeader        = "X-Webhook-ID"        // e.g. the id of the event
+	signaturePrefix = "sha256="
+)
+
+// Endpoint is a webhook that receives events
+type Endpoint struct {
+	URL    string   `json:"url"`    // e.g. https://example.com/hooks/files
+	Events []string `json:"events"` // e.g. output.created, every type of event when empty
+}
+
+// Accepts reports whether the endpoint receives events of the given type
+func (e Endpoint) Accepts(eventType string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, eventType)
+}
+
+// Validate verifies that the endpoint has an http url and only known types of events
+func (e Endpoint) Validate() error {
+	if err := ValidateURL(e.URL); err != nil {
+		return err
+	}
+
+	for _, t := range e.Events {
+		if !slices.Contains(types, t) {
+			return fmt.Errorf("unknown event %q, must be one of %v", t, types)
+		}
+	}
+
+	return nil
+}
+
+// ValidateURL verifies that a webhook url is an absolute http or https url
+func ValidateURL(u string) error {
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil || req.URL.Host == "" || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+		return fmt.Errorf("webhook url %q must be an absolute http or https url", u)
+	}
+
+	return nil
+}
+
+// ErrForbiddenAddress is returned for a webhook of a client that resolves to an address of a private network
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// ValidatePublicURL verifies that a webhook url registered by a client is an absolute http or https url
+// whose host only resolves to public addresses, so that clients cannot make the worker reach internal services
+func ValidatePublicURL(ctx context.Context, u string) error {
+	if err := ValidateURL(u); err != nil {
+		return err
+	}
+
+	pu, _ := url.Parse(u)
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", pu.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook url %q cannot be resolved: %w", u, err)
+	}
+
+	for _, a := range addrs {
+		if !public(a) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, pu.Hostname(), a)
+		}
+	}
+
+	return nil
+}
+
+// public reports whether the address is neither loopback, private, link-local, multicast nor unspecified
+// Link-local addresses include the metadata endpoints of cloud providers e.g. 169.254.169.254
+func public(a netip.Addr) bool {
+	a = a.Unmap()
+	return a.IsValid() && !a.IsLoopback() && !a.IsPrivate() && !a.IsLinkLocalUnicast() &&
+		!a.IsLinkLocalMulticast() && !a.IsInterfaceLocalMulticast() && !a.IsMulticast() && !a.IsUnspecified()
+}
+
+// dialPublic refuses connections to addresses that are not public
+// It runs once the host is resolved, so a host that resolves to another address after it was validated is refused too
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !public(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ap.Addr())
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the body with the given secret
+func Sign(secret []byte, body []byte) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write(body)
+	return signaturePrefix + hex.EncodeToString(m.Sum(nil))
+}
+
+// Verify reports whether the signature is the signature of the body with the given secret
+func Verify(secret []byte, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Sender posts events to webhooks
+type Sender interface {
+	// Send posts the event to the url, returning the status code of the response
+	// An error is returned when the webhook cannot be reached or does not answer with a 2xx status
+	Send(ctx context.Context, url string, e Event) (int, error)
+}
+
+type sender struct {
+	secret  []byte
+	trusted []string     // The hosts of the configured endpoints
+	client  *http.Client // Posts to the configured endpoints
+	public  *http.Client // Posts to the webhooks of clients, only reaching public addresses
+}
+
+// NewSender creates a sender that signs the events it posts with the given secret
+// Events are sent unsigned when the secret is empty
+// Webhooks on the hosts of the given endpoints may be on any network, while other webhooks were
+// registered by clients and are only posted to when their host resolves to a public address
+func NewSender(secret string, timeout time.Duration, endpoints []Endpoint) Sender {
+	trusted := []string{}
+	for _, e := range endpoints {
+		if u, err := url.Parse(e.URL); err == nil {
+			trusted = append(trusted, u.Host)
+		}
+	}
+
+	// Proxies are not used for the webhooks of clients, as the address of the proxy is all the dialer would see
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = (&net.Dialer{Timeout: timeout, Control: dialPublic}).DialContext
+
+	return &sender{
+		secret:  []byte(secret),
+		trusted: trusted,
+		client:  &http.Client{Timeout: timeout},
+		public:  &http.Client{Timeout: timeout, Transport: tr},
+	}
+}
+
+func (s *sender) Send(ctx context.Context, url string, e Event) (int, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, e.Type)
+	req.Header.Set(IDHeader, e.ID)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	client := s.public
+	if slices.Contains(s.trusted, req.URL.Host) {
+		client = s.client
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16)) // drain the body so the connection is reused
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook answered with %d %s", res.StatusCode, strings.ToLower(http.StatusText(res.StatusCode)))
+	}
+
+	return res.StatusCode, nil
+}
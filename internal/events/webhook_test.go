@@ -0,0 +1,122 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/events"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	secret := "s3cret"
+	tests := []struct {
+		name       string
+		secret     string
+		status     int
+		expectErr  bool
+		expectSign bool
+	}{
+		{name: "signed delivery", secret: secret, status: http.StatusOK, expectSign: true},
+		{name: "unsigned delivery", status: http.StatusNoContent},
+		{name: "webhook error", secret: secret, status: http.StatusInternalServerError, expectErr: true, expectSign: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received events.Event
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, events.OutputCreated, r.Header.Get(events.EventHeader))
+				if tt.expectSign {
+					assert.True(t, events.Verify([]byte(tt.secret), body, r.Header.Get(events.SignatureHeader)))
+				} else {
+					assert.Empty(t, r.Header.Get(events.SignatureHeader))
+				}
+
+				assert.NoError(t, json.Unmarshal(body, &received))
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			e := events.New(events.OutputCreated, "file-id", map[string]string{"name": "resized.jpg"})
+			status, err := events.NewSender(tt.secret, time.Second, []events.Endpoint{{URL: srv.URL}}).Send(context.Background(), srv.URL, e)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.expectErr, err != nil)
+			assert.Equal(t, e.ID, received.ID)
+			assert.Equal(t, "file-id", received.FileID)
+		})
+	}
+
+	t.Run("unreachable webhook", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		_, err := events.NewSender(secret, time.Second, []events.Endpoint{{URL: srv.URL}}).Send(context.Background(), srv.URL, events.New(events.JobFailed, "file-id", nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("webhook of a client on a loopback address", func(t *testing.T) {
+		called := false
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+		defer srv.Close()
+
+		_, err := events.NewSender(secret, time.Second, nil).Send(context.Background(), srv.URL, events.New(events.JobFailed, "file-id", nil))
+		assert.ErrorIs(t, err, events.ErrForbiddenAddress)
+		assert.False(t, called)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"file.uploaded"}`)
+	sig := events.Sign([]byte("s3cret"), body)
+	assert.True(t, events.Verify([]byte("s3cret"), body, sig))
+	assert.False(t, events.Verify([]byte("other"), body, sig))
+	assert.False(t, events.Verify([]byte("s3cret"), []byte(`{"type":"job.failed"}`), sig))
+}
+
+func TestValidatePublicURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		forbidden bool
+	}{
+		{url: "https://203.0.113.7/hooks"},
+		{url: "http://[2001:db8::1]:8080/hooks"},
+		{url: "http://localhost/hooks", forbidden: true},
+		{url: "http://127.0.0.1:6379", forbidden: true},
+		{url: "http://[::1]/hooks", forbidden: true},
+		{url: "http://10.0.0.5/hooks", forbidden: true},
+		{url: "http://192.168.1.1/hooks", forbidden: true},
+		{url: "http://169.254.169.254/latest/meta-data", forbidden: true},
+		{url: "http://[::ffff:127.0.0.1]/hooks", forbidden: true},
+		{url: "http://0.0.0.0/hooks", forbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := events.ValidatePublicURL(context.Background(), tt.url)
+			if tt.forbidden {
+				assert.ErrorIs(t, err, events.ErrForbiddenAddress)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Error(t, events.ValidatePublicURL(context.Background(), "ftp://203.0.113.7/hooks"))
+}
+
+func TestEndpoint(t *testing.T) {
+	assert.True(t, events.Endpoint{URL: "https://example.com"}.Accepts(events.JobFailed))
+	assert.False(t, events.Endpoint{URL: "https://example.com", Events: []string{events.OutputCreated}}.Accepts(events.JobFailed))
+
+	assert.NoError(t, events.Endpoint{URL: "https://example.com/hooks", Events: []string{events.FileUploaded}}.Validate())
+	assert.Error(t, events.Endpoint{URL: "ftp://example.com"}.Validate())
+	assert.Error(t, events.Endpoint{URL: "/hooks"}.Validate())
+	assert.Error(t, events.Endpoint{URL: "https://example.com", Events: []string{"file.deleted"}}.Validate())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+)
+
+// drain tracks the requests that outlive the start of a shutdown
+// Event streams never end on their own, so they are closed,
+// while uploads are waited for and what they leave behind is removed
+type drain struct {
+	streams chan struct{} // Closed once the server shuts down
+	once    sync.Once
+	mu      sync.Mutex
+	uploads map[string]struct{} // The storage keys of the uploads in flight
+	wg      sync.WaitGroup
+}
+
+func newDrain() *drain {
+	return &drain{
+		streams: make(chan struct{}),
+		uploads: map[string]struct{}{},
+	}
+}
+
+// upload tracks an upload to the given key until the returned func is called
+func (d *drain) upload(key string) func() {
+	d.mu.Lock()
+	d.uploads[key] = struct{}{}
+	d.mu.Unlock()
+	d.wg.Add(1)
+
+	return func() {
+		d.mu.Lock()
+		delete(d.uploads, key)
+		d.mu.Unlock()
+		d.wg.Done()
+	}
+}
+
+// CloseStreams ends the event streams so that the server does not wait for them to shut down
+func (h handler) CloseStreams() {
+	h.drain.once.Do(func() {
+		close(h.drain.streams)
+	})
+}
+
+// AbandonUploads waits for the uploads in flight until the context is done
+// Uploads whose connection was closed remove what they wrote as they fail, and the
+// objects of the uploads that are still in flight afterwards are removed here
+func (h handler) AbandonUploads(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		h.drain.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	h.drain.mu.Lock()
+	keys := make([]string, 0, len(h.drain.uploads))
+	for k := range h.drain.uploads {
+		keys = append(keys, k)
+	}
+	h.drain.mu.Unlock()
+
+	for _, k := range keys {
+		h.log.Warn().Str("key", k).Msg("Removing upload abandoned on shutdown")
+		if err := h.st.Delete(context.Background(), k); err != nil {
+			h.log.Error().Err(err).Str("key", k).Msg("Failed to remove abandoned upload")
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Verifies that the uploads still in flight once the wait is over are removed from the storage
+func TestAbandonUploads(t *testing.T) {
+	st := storage.NewLocal(t.TempDir(), &log)
+	h := NewHandlers(conf, &log, new(mockdb.Database), new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).(*handler)
+	_, err := st.Put(context.Background(), "uploads/1/finished.txt", strings.NewReader("finished"), -1, "text/plain")
+	assert.NoError(t, err)
+	_, err = st.Put(context.Background(), "uploads/2/abandoned.txt", strings.NewReader("partial"), -1, "text/plain")
+	assert.NoError(t, err)
+
+	// Uploads that finish in time are left alone
+	done := h.drain.upload("uploads/1/finished.txt")
+	go done()
+	h.AbandonUploads(context.Background())
+
+	h.drain.upload("uploads/2/abandoned.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.AbandonUploads(ctx)
+
+	_, err = st.Stat(context.Background(), "uploads/1/finished.txt")
+	assert.NoError(t, err)
+	_, err = st.Stat(context.Background(), "uploads/2/abandoned.txt")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
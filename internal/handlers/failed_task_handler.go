@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// FailedTasksHandler lists the tasks that are waiting to be retried or were archived after using up their retries
+// The tasks can be filtered by state, task type and file with the query parameters of the same names
+func (h handler) FailedTasksHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := tasks.FailedTaskFilter{State: q.Get("state"), Type: q.Get("type"), FileID: q.Get("file_id")}
+	h.log.Info().Str("state", f.State).Str("type", f.Type).Str("file_id", f.FileID).Msg("Failed tasks request received")
+	if f.State != "" && !slices.Contains([]string{tasks.TaskRetry, tasks.TaskArchived}, f.State) {
+		http.Error(w, `{"error": "The state must be one of retry or archived"}`, http.StatusBadRequest)
+		return
+	}
+
+	fts, err := h.in.FailedTasks(f)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list failed tasks")
+		http.Error(w, `{"error": "Failed to list failed tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]tasks.FailedTask{"tasks": fts})
+}
+
+// FailedTaskHandler returns the payload and last error of a failed task
+func (h handler) FailedTaskHandler(w http.ResponseWriter, r *http.Request) {
+	queue, id := mux.Vars(r)["queue"], mux.Vars(r)["id"]
+	h.log.Info().Str("queue", queue).Str("task_id", id).Msg("Failed task request received")
+	ft, err := h.in.FailedTask(queue, id)
+	if err != nil {
+		h.failedTaskError(w, err, "Failed to get failed task")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ft)
+}
+
+// ReplayTaskHandler moves a failed task back to its queue to be processed straight away
+// The job of the task is queued again, and its file moves to processing once the task starts
+func (h handler) ReplayTaskHandler(w http.ResponseWriter, r *http.Request) {
+	queue, id := mux.Vars(r)["queue"], mux.Vars(r)["id"]
+	h.log.Info().Str("queue", queue).Str("task_id", id).Msg("Replay task request received")
+	if err := h.in.Replay(queue, id); err != nil {
+		h.failedTaskError(w, err, "Failed to replay task")
+		return
+	}
+
+	if j := h.taskJob(id); j != nil {
+		h.setJob(j.ID, map[string]interface{}{"state": models.JobQueued, "completed_at": nil})
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Task replayed", "task_id": id})
+}
+
+// DeleteTaskHandler deletes a failed task for good
+// A task that was still to be retried fails its job, and with it its file
+func (h handler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	queue, id := mux.Vars(r)["queue"], mux.Vars(r)["id"]
+	h.log.Info().Str("queue", queue).Str("task_id", id).Msg("Delete task request received")
+	if err := h.in.Delete(queue, id); err != nil {
+		h.failedTaskError(w, err, "Failed to delete task")
+		return
+	}
+
+	if j := h.taskJob(id); j != nil && j.State != models.JobCompleted && j.State != models.JobFailed {
+		h.setJob(j.ID, map[string]interface{}{"state": models.JobFailed, "error": "task deleted", "completed_at": time.Now()})
+		st := models.StatusTransition{JobID: j.ID, Reason: "task deleted"}
+		if err := h.db.TransitionFile(j.FileID, models.StatusFailed, st); err != nil {
+			h.log.Error().Err(err).Str("file_id", j.FileID).Msg("Failed to fail file of deleted task")
+		} else {
+			h.notifier.Notify(events.NewStatusChanged(j.FileID, models.StatusFailed, j.ID, st.Reason), nil)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// taskJob returns the job that tracks the task, if any
+// Webhook deliveries are not tracked as jobs
+func (h handler) taskJob(id string) *models.Job {
+	j, err := h.db.JobByID(id)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			h.log.Error().Err(err).Str("task_id", id).Msg("Failed to get job of task")
+		}
+		return nil
+	}
+
+	return j
+}
+
+// failedTaskError answers a request for a task that could not be inspected
+func (h handler) failedTaskError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, tasks.ErrTaskNotFound) {
+		http.Error(w, `{"error": "Failed task not found"}`, http.StatusNotFound)
+		return
+	}
+
+	h.log.Error().Err(err).Msg(msg)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": msg})
+}
@@ -0,0 +1,211 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestFailedTasksHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	var tests = []struct {
+		name           string
+		query          string
+		mockInspector  func(in *mocktasks.Inspector)
+		expectedStatus int
+		expectedTasks  int
+	}{
+		{
+			name:  "all failed tasks",
+			query: "",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("FailedTasks", tasks.FailedTaskFilter{}).Return([]tasks.FailedTask{{ID: "job-1"}, {ID: "job-2"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTasks:  2,
+		},
+		{
+			name:  "filtered by state, type and file",
+			query: "?state=archived&type=video:extract-metadata&file_id=file-id",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("FailedTasks", tasks.FailedTaskFilter{State: tasks.TaskArchived, Type: tasks.VideoMetadataTaskType, FileID: "file-id"}).Return([]tasks.FailedTask{{ID: "job-1"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedTasks:  1,
+		},
+		{
+			name:           "unknown state",
+			query:          "?state=pending",
+			mockInspector:  func(in *mocktasks.Inspector) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "redis error",
+			query: "",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("FailedTasks", tasks.FailedTaskFilter{}).Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := new(mocktasks.Inspector)
+			tt.mockInspector(in)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/admin/tasks"+tt.query, nil)
+			handlers.NewHandlers(conf, &log, new(mockdb.Database), new(mocktasks.Client), new(mockstorage.Storage), stream(), in).GetHandler("FailedTasksHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var body map[string][]tasks.FailedTask
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Len(t, body["tasks"], tt.expectedTasks)
+			}
+			in.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFailedTaskHandlers(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	var tests = []struct {
+		name           string
+		handler        string
+		method         string
+		mockInspector  func(in *mocktasks.Inspector)
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+	}{
+		{
+			name:    "inspect failed task",
+			handler: "FailedTaskHandler",
+			method:  "GET",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("FailedTask", "default", "job-id").Return(&tasks.FailedTask{ID: "job-id", LastError: "file is not a video"}, nil)
+			},
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "inspect missing task",
+			handler: "FailedTaskHandler",
+			method:  "GET",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("FailedTask", "default", "job-id").Return(nil, tasks.ErrTaskNotFound)
+			},
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "replay queues the job again",
+			handler: "ReplayTaskHandler",
+			method:  "POST",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Replay", "default", "job-id").Return(nil)
+			},
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "job-id").Return(&models.Job{ID: "job-id", FileID: "file-id", State: models.JobFailed}, nil)
+				db.On("UpdateJob", "job-id", map[string]interface{}{"state": models.JobQueued, "completed_at": nil}).Return(nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:    "replay webhook delivery",
+			handler: "ReplayTaskHandler",
+			method:  "POST",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Replay", "default", "job-id").Return(nil)
+			},
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "job-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:    "replay missing task",
+			handler: "ReplayTaskHandler",
+			method:  "POST",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Replay", "default", "job-id").Return(tasks.ErrTaskNotFound)
+			},
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "delete archived task",
+			handler: "DeleteTaskHandler",
+			method:  "DELETE",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Delete", "default", "job-id").Return(nil)
+			},
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "job-id").Return(&models.Job{ID: "job-id", FileID: "file-id", State: models.JobFailed}, nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "delete task awaiting retry fails its job and file",
+			handler: "DeleteTaskHandler",
+			method:  "DELETE",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Delete", "default", "job-id").Return(nil)
+			},
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "job-id").Return(&models.Job{ID: "job-id", FileID: "file-id", State: models.JobRetrying}, nil)
+				db.On("UpdateJob", "job-id", mock.MatchedBy(func(fields map[string]interface{}) bool {
+					return fields["state"] == models.JobFailed && fields["error"] == "task deleted"
+				})).Return(nil)
+				db.On("TransitionFile", "file-id", models.StatusFailed, models.StatusTransition{JobID: "job-id", Reason: "task deleted"}).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "delete fails",
+			handler: "DeleteTaskHandler",
+			method:  "DELETE",
+			mockInspector: func(in *mocktasks.Inspector) {
+				in.On("Delete", "default", "job-id").Return(fmt.Errorf("connection refused"))
+			},
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := new(mocktasks.Inspector)
+			db := new(mockdb.Database)
+			tt.mockInspector(in)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, "/admin/tasks/default/job-id", nil)
+			req = mux.SetURLVars(req, map[string]string{"queue": "default", "id": "job-id"})
+			handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), stream(), in).GetHandler(tt.handler)(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			in.AssertExpectations(t)
+			db.AssertExpectations(t)
+		})
+	}
+}
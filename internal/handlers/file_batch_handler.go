@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"simple-file-processor/internal/models"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	batchPending   = "pending"
+	batchCompleted = "completed"
+	batchFailed    = "failed"
+)
+
+type batchResponse struct {
+	BatchID string                   `json:"batch_id"`
+	FileID  string                   `json:"file_id"`
+	Status  string                   `json:"status"`          // e.g. pending until every variant of the batch is stored
+	Error   string                   `json:"error,omitempty"` // e.g. the error that the job of a failed batch ended with
+	Outputs []models.ProcessedOutput `json:"outputs"`
+}
+
+// FileBatchHandler returns the status of a batch resize, transcode or sprite sheet along with the outputs it produced
+// The variants of a batch are stored together, so a batch without outputs is pending until its job fails
+// The id of a batch is the id of its job, so batches are only found for the jobs of the file
+func (h handler) FileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fid, bid := vars["id"], vars["batchId"]
+	h.log.Info().Str("file_id", fid).Str("batch_id", bid).Msg("File batch request received")
+	if fid == "" || bid == "" {
+		h.log.Error().Msg("File ID and batch ID are required")
+		http.Error(w, `{"error": "File id and batch id are required path parameters"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	res := batchResponse{BatchID: bid, FileID: f.ID, Status: batchPending, Outputs: []models.ProcessedOutput{}}
+	for _, o := range f.ProcessedOutputs {
+		if o.BatchID == bid {
+			res.Outputs = append(res.Outputs, o)
+		}
+	}
+
+	if len(res.Outputs) > 0 {
+		res.Status = batchCompleted
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	j, err := h.db.JobByID(bid)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && j.FileID != f.ID) {
+		http.Error(w, `{"error": "Batch not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to get job of batch")
+		http.Error(w, `{"error": "Failed to get batch"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if j.State == models.JobFailed {
+		res.Status, res.Error = batchFailed, j.Error
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestFileBatchHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	outputs := []models.ProcessedOutput{
+		{Name: "resized.jpg", Type: models.ResizedImageType},
+		{Name: "small.webp", Type: models.ResizedImageType, BatchID: "batch-id"},
+		{Name: "large.webp", Type: models.ResizedImageType, BatchID: "batch-id"},
+		{Name: "other.png", Type: models.ResizedImageType, BatchID: "other-batch-id"},
+	}
+
+	var tests = []struct {
+		name            string
+		fileID          string
+		batchID         string
+		mockDB          func(db *mockdb.Database)
+		expectedStatus  int
+		expectedBatch   string
+		expectedError   string
+		expectedOutputs int
+	}{
+		{
+			name:    "completed batch",
+			fileID:  "valid-file-id",
+			batchID: "batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", ProcessedOutputs: outputs}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBatch:   "completed",
+			expectedOutputs: 2,
+		},
+		{
+			name:    "pending batch",
+			fileID:  "valid-file-id",
+			batchID: "pending-batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", ProcessedOutputs: outputs}, nil)
+				db.On("JobByID", "pending-batch-id").Return(&models.Job{ID: "pending-batch-id", FileID: "valid-file-id", State: models.JobRetrying}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBatch:   "pending",
+			expectedOutputs: 0,
+		},
+		{
+			name:    "failed batch",
+			fileID:  "valid-file-id",
+			batchID: "failed-batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", ProcessedOutputs: outputs}, nil)
+				db.On("JobByID", "failed-batch-id").Return(&models.Job{ID: "failed-batch-id", FileID: "valid-file-id", State: models.JobFailed, Error: "ffmpeg error"}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBatch:   "failed",
+			expectedError:   "ffmpeg error",
+			expectedOutputs: 0,
+		},
+		{
+			name:    "unknown batch",
+			fileID:  "valid-file-id",
+			batchID: "unknown-batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", ProcessedOutputs: outputs}, nil)
+				db.On("JobByID", "unknown-batch-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "batch of another file",
+			fileID:  "valid-file-id",
+			batchID: "other-file-batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", ProcessedOutputs: outputs}, nil)
+				db.On("JobByID", "other-file-batch-id").Return(&models.Job{ID: "other-file-batch-id", FileID: "other-file-id"}, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing batch ID",
+			fileID:         "valid-file-id",
+			batchID:        "",
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "file not found",
+			fileID:  "not-found-file-id",
+			batchID: "batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "not-found-file-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "database error",
+			fileID:  "valid-file-id",
+			batchID: "batch-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/file/"+tt.fileID+"/batches/"+tt.batchID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.fileID, "batchId": tt.batchID})
+
+			handler := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileBatchHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			db.AssertExpectations(t)
+			if tt.expectedStatus == http.StatusOK {
+				var res map[string]any
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, tt.batchID, res["batch_id"])
+				assert.Equal(t, tt.expectedBatch, res["status"])
+				if tt.expectedError != "" {
+					assert.Equal(t, tt.expectedError, res["error"])
+				}
+				assert.Len(t, res["outputs"], tt.expectedOutputs)
+			}
+		})
+	}
+}
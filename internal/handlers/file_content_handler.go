@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"simple-file-processor/internal/storage"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// FileContentHandler streams the originally uploaded file
+// Range, conditional and HEAD requests are supported
+func (h handler) FileContentHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File content request received")
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	h.serveContent(w, r, path.Join(f.StoragePath, f.GeneratedName), f.MimeType)
+}
+
+// OutputContentHandler streams a processed output of a file
+// Range, conditional and HEAD requests are supported
+func (h handler) OutputContentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fid, oid := vars["id"], vars["outputId"]
+	h.log.Info().Str("file_id", fid).Str("output_id", oid).Msg("Output content request received")
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	for _, po := range f.ProcessedOutputs {
+		if po.ID.String() != oid {
+			continue
+		}
+
+		name := po.Filename()
+		h.serveContent(w, r, path.Join(po.StoragePath, name), mime.TypeByExtension(path.Ext(name)))
+		return
+	}
+
+	http.Error(w, `{"error": "Output not found"}`, http.StatusNotFound)
+}
+
+// serveContent streams the object stored at the given key
+// http.ServeContent takes care of Content-Length, Last-Modified, Range and
+// the conditional request headers, the ETag is provided by the storage
+// and changes whenever the object does
+func (h handler) serveContent(w http.ResponseWriter, r *http.Request, key string, ct string) {
+	obj, err := h.st.Get(r.Context(), key)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to open file content at " + key)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, `{"error": "File content not found"}`, http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, `{"error": "Failed to read file content"}`, http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+
+	oi := obj.Info()
+	if ct == "" {
+		ct = oi.ContentType
+	}
+
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("ETag", oi.ETag)
+	http.ServeContent(w, r, path.Base(key), oi.LastModified, obj)
+}
+
+// fileLookupError maps a failed file lookup to the matching response
+func (h handler) fileLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error": "File not found"}`, http.StatusNotFound)
+		return
+	}
+
+	h.log.Error().Err(err).Msg("Failed to get file by ID")
+	http.Error(w, `{"error": "Failed to get file"}`, http.StatusInternalServerError)
+}
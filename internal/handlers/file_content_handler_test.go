@@ -0,0 +1,150 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestFileContentHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	if _, err := st.Put(context.Background(), "uploads/id/id_video.mp4", bytes.NewBufferString("0123456789"), 10, "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name           string
+		fileID         string
+		rangeHeader    string
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "full content",
+			fileID: "id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(&models.File{ID: "id", StoragePath: "uploads/id", GeneratedName: "id_video.mp4", MimeType: "video/mp4"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "0123456789",
+		},
+		{
+			name:        "range request",
+			fileID:      "id",
+			rangeHeader: "bytes=2-5",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(&models.File{ID: "id", StoragePath: "uploads/id", GeneratedName: "id_video.mp4", MimeType: "video/mp4"}, nil)
+			},
+			expectedStatus: http.StatusPartialContent,
+			expectedBody:   "2345",
+		},
+		{
+			name:   "file not found",
+			fileID: "missing",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "missing").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "content missing on disk",
+			fileID: "id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(&models.File{ID: "id", StoragePath: "uploads/id", GeneratedName: "gone.mp4"}, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/file/"+tt.fileID+"/content", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			req = mux.SetURLVars(req, map[string]string{"id": tt.fileID})
+
+			handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("FileContentHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Equal(t, "video/mp4", rec.Header().Get("Content-Type"))
+				assert.NotEmpty(t, rec.Header().Get("ETag"))
+				assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+				assert.NotEmpty(t, rec.Header().Get("Accept-Ranges"))
+			}
+		})
+	}
+}
+
+func TestOutputContentHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	oid := uuid.New()
+	if _, err := st.Put(context.Background(), "uploads/id/id-metadata.json", bytes.NewBufferString(`{}`), 2, "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &models.File{ID: "id", ProcessedOutputs: []models.ProcessedOutput{
+		{ID: oid, Name: "id-metadata", Extension: "json", StoragePath: "uploads/id", Type: models.VideoMetadataType},
+	}}
+
+	var tests = []struct {
+		name           string
+		outputID       string
+		expectedStatus int
+	}{
+		{
+			name:           "output found",
+			outputID:       oid.String(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "output not found",
+			outputID:       uuid.NewString(),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			db.On("FileByID", "id").Return(f, nil)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/file/id/outputs/"+tt.outputID+"/content", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "id", "outputId": tt.outputID})
+
+			handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("OutputContentHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, `{}`, rec.Body.String())
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"simple-file-processor/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// FileDeliveriesHandler returns the delivery log of the webhook events of a file from newest to oldest
+func (h handler) FileDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File deliveries request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, err := h.db.FileByID(fid); err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	ds, err := h.db.WebhookDeliveries(fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list webhook deliveries of file")
+		http.Error(w, `{"error": "Failed to list webhook deliveries"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]models.WebhookDelivery{"deliveries": ds})
+}
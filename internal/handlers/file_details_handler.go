@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// FileDetailsHandler returns the file record along with its processed outputs
+func (h handler) FileDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File details request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, f)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"simple-file-processor/internal/events"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How often a comment is sent on an idle stream so that proxies keep the connection open
+var keepAliveInterval = 15 * time.Second
+
+// FileEventsHandler streams the events of a file as server-sent events
+// The current status of the file is sent first, followed by its status changes,
+// processed outputs and failed jobs as the worker produces them
+func (h handler) FileEventsHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File events request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, `{"error": "Streaming is not supported"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the file so that no event between the two is missed
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	evs, err := h.es.Subscribe(ctx, fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to subscribe to events of file")
+		http.Error(w, `{"error": "Failed to subscribe to file events"}`, http.StatusInternalServerError)
+		return
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	// The stream outlives the write timeout of the server, so each write is given a deadline of its own
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(h.conf.WriteTimeout()))
+	writeEvent(w, events.NewStatusChanged(f.ID, f.Status, "", "current status"))
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.log.Info().Str("file_id", fid).Msg("File events stream closed")
+			return
+		case <-h.drain.streams:
+			h.log.Info().Str("file_id", fid).Msg("File events stream closed on shutdown")
+			return
+		case e, ok := <-evs:
+			if !ok {
+				return
+			}
+
+			rc.SetWriteDeadline(time.Now().Add(h.conf.WriteTimeout()))
+			writeEvent(w, e)
+		case <-keepAlive.C:
+			rc.SetWriteDeadline(time.Now().Add(h.conf.WriteTimeout()))
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
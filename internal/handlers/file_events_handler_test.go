@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockevents"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// stream returns an event stream that accepts every published event
+func stream() *mockevents.Stream {
+	s := new(mockevents.Stream)
+	s.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return s
+}
+
+// readEvent reads the next event of a text/event-stream, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, events.Event) {
+	var name string
+	var e events.Event
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return "", e
+		}
+
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, e
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+		}
+	}
+}
+
+func TestFileEventsHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	mr := miniredis.RunT(t)
+	es := events.NewRedisStream(redis.NewClient(&redis.Options{Addr: mr.Addr()}), &log)
+
+	t.Run("streams events", func(t *testing.T) {
+		db := new(mockdb.Database)
+		db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Status: models.StatusPending}, nil)
+
+		r := mux.NewRouter()
+		r.HandleFunc("/file/{id}/events", handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), es, new(mocktasks.Inspector)).GetHandler("FileEventsHandler"))
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL + "/file/valid-file-id/events")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		// The current status is sent once the stream is subscribed
+		body := bufio.NewReader(res.Body)
+		name, e := readEvent(t, body)
+		assert.Equal(t, events.StatusChanged, name)
+		assert.Equal(t, map[string]interface{}{"status": models.StatusPending, "job_id": "", "reason": "current status"}, e.Data)
+
+		// Events of other files are not streamed
+		assert.NoError(t, es.Publish(context.Background(), events.New(events.OutputCreated, "other-file-id", nil)))
+		assert.NoError(t, es.Publish(context.Background(), events.NewStatusChanged("valid-file-id", models.StatusProcessing, "job-id", "job started")))
+		assert.NoError(t, es.Publish(context.Background(), events.New(events.OutputCreated, "valid-file-id", models.ProcessedOutput{Name: "thumbnail"})))
+
+		name, e = readEvent(t, body)
+		assert.Equal(t, events.StatusChanged, name)
+		assert.Equal(t, models.StatusProcessing, e.Data.(map[string]interface{})["status"])
+
+		name, e = readEvent(t, body)
+		assert.Equal(t, events.OutputCreated, name)
+		assert.Equal(t, "valid-file-id", e.FileID)
+		assert.Equal(t, "thumbnail", e.Data.(map[string]interface{})["name"])
+	})
+
+	t.Run("closed on shutdown", func(t *testing.T) {
+		db := new(mockdb.Database)
+		db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Status: models.StatusPending}, nil)
+
+		h := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), es, new(mocktasks.Inspector))
+		r := mux.NewRouter()
+		r.HandleFunc("/file/{id}/events", h.GetHandler("FileEventsHandler"))
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL + "/file/valid-file-id/events")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body := bufio.NewReader(res.Body)
+		readEvent(t, body)
+
+		// The stream ends so that the server does not wait for it to shut down
+		h.CloseStreams()
+		h.CloseStreams()
+		_, err = io.ReadAll(body)
+		assert.NoError(t, err)
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		db := new(mockdb.Database)
+		db.On("FileByID", "not-found-file-id").Return(nil, gorm.ErrRecordNotFound)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/file/not-found-file-id/events", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "not-found-file-id"})
+		handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), es, new(mocktasks.Inspector)).GetHandler("FileEventsHandler")(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("stream unavailable", func(t *testing.T) {
+		s := new(mockevents.Stream)
+		s.On("Subscribe", mock.Anything, "valid-file-id").Return(nil, assert.AnError)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/file/valid-file-id/events", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "valid-file-id"})
+		handlers.NewHandlers(conf, &log, new(mockdb.Database), new(mocktasks.Client), new(mockstorage.Storage), s, new(mocktasks.Inspector)).GetHandler("FileEventsHandler")(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
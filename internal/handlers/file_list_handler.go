@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/models"
+	"strconv"
+	"time"
+)
+
+// FileListHandler lists files page by page, optionally filtered by
+// type, status, mime type, a creation time range and how long the status has not changed
+func (h handler) FileListHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ff, err := fileFilter(q)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Invalid file list query")
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.db.Files(ff)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+			return
+		}
+
+		h.log.Error().Err(err).Msg("Failed to list files")
+		http.Error(w, `{"error": "Failed to list files"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// fileFilter builds the database filter from the query parameters
+func fileFilter(q url.Values) (models.FileFilter, error) {
+	ff := models.FileFilter{
+		Type:     q.Get("type"),
+		Status:   q.Get("status"),
+		MimeType: q.Get("mime_type"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return ff, errors.New("limit must be a positive integer")
+		}
+
+		ff.Limit = limit
+	}
+
+	var err error
+	if ff.CreatedAfter, err = parseTime(q.Get("created_after")); err != nil {
+		return ff, errors.New("created_after must be an RFC 3339 timestamp")
+	}
+
+	if ff.CreatedBefore, err = parseTime(q.Get("created_before")); err != nil {
+		return ff, errors.New("created_before must be an RFC 3339 timestamp")
+	}
+
+	if ff.StatusChangedBefore, err = parseTime(q.Get("status_changed_before")); err != nil {
+		return ff, errors.New("status_changed_before must be an RFC 3339 timestamp")
+	}
+
+	return ff, nil
+}
+
+// parseTime parses an optional RFC 3339 timestamp
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFileListHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name           string
+		query          string
+		mockDB         func(d *mockdb.Database)
+		expectedStatus int
+	}{
+		{
+			name:  "filters are passed to the database",
+			query: "?type=image&status=pending&mime_type=image/png&created_after=2025-01-01T00:00:00Z&limit=5&cursor=abc",
+			mockDB: func(d *mockdb.Database) {
+				d.On("Files", mock.MatchedBy(func(ff models.FileFilter) bool {
+					return ff.Type == "image" && ff.Status == "pending" && ff.MimeType == "image/png" &&
+						ff.Limit == 5 && ff.Cursor == "abc" && ff.CreatedAfter.Equal(after) && ff.CreatedBefore == nil
+				})).Return(&models.FilePage{Files: []models.File{{ID: "1"}}, NextCursor: "next"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "stuck files",
+			query: "?status=processing&status_changed_before=2025-01-01T00:00:00Z",
+			mockDB: func(d *mockdb.Database) {
+				d.On("Files", mock.MatchedBy(func(ff models.FileFilter) bool {
+					return ff.Status == "processing" && ff.StatusChangedBefore.Equal(after)
+				})).Return(&models.FilePage{Files: []models.File{{ID: "1"}}, NextCursor: "next"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid status_changed_before",
+			query:          "?status_changed_before=an-hour-ago",
+			mockDB:         func(d *mockdb.Database) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			mockDB:         func(d *mockdb.Database) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid created_before",
+			query:          "?created_before=yesterday",
+			mockDB:         func(d *mockdb.Database) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=bad",
+			mockDB: func(d *mockdb.Database) {
+				d.On("Files", mock.Anything).Return(nil, db.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "database error",
+			query: "",
+			mockDB: func(d *mockdb.Database) {
+				d.On("Files", mock.Anything).Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := new(mockdb.Database)
+			tt.mockDB(d)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/files"+tt.query, nil)
+
+			handler := handlers.NewHandlers(conf, &log, d, new(mocktasks.Client), new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileListHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			d.AssertExpectations(t)
+			if tt.expectedStatus == http.StatusOK {
+				var page models.FilePage
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+				assert.Equal(t, "next", page.NextCursor)
+				assert.Len(t, page.Files, 1)
+			}
+		})
+	}
+}
@@ -1,18 +1,84 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
 	"simple-file-processor/internal/models"
 
 	"simple-file-processor/internal/tasks"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 )
 
+// The most variants that a single resize request may ask for
+const maxResizeVariants = 20
+
+type resizeVariant struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Mode       string `json:"mode"`       // e.g. fit, fill, pad, scale, stretch when empty
+	Gravity    string `json:"gravity"`    // e.g. center, north, southwest, the part kept by fill and pad
+	Background string `json:"background"` // e.g. #ffffff, the colour that pad fills with
+	Filter     string `json:"filter"`     // e.g. nearest, bilinear, bicubic, lanczos when empty
+	Format     string `json:"format"`     // e.g. jpeg, png, gif, webp, the format of the file when empty
+	Quality    int    `json:"quality"`    // e.g. 1 to 100, the quality of lossy formats
+}
+
 type fileResizeRequest struct {
-	Width  int `json:"width"`
-	Height int `json:"height"`
+	resizeVariant
+	Variants []resizeVariant `json:"variants"` // e.g. the sizes of a srcset, resized together in one batch
+	preset   string          // The name of the preset the options were taken from
+}
+
+// options returns the options the image is resized with
+func (v resizeVariant) options() lib.ResizeOptions {
+	return lib.ResizeOptions{
+		Width:      v.Width,
+		Height:     v.Height,
+		Mode:       v.Mode,
+		Gravity:    v.Gravity,
+		Background: v.Background,
+		Filter:     v.Filter,
+		Format:     v.Format,
+		Quality:    v.Quality,
+	}
+}
+
+// variantOf returns the variant that resizes with the given options
+func variantOf(o lib.ResizeOptions) resizeVariant {
+	return resizeVariant{
+		Width:      o.Width,
+		Height:     o.Height,
+		Mode:       o.Mode,
+		Gravity:    o.Gravity,
+		Background: o.Background,
+		Filter:     o.Filter,
+		Format:     o.Format,
+		Quality:    o.Quality,
+	}
+}
+
+// validate verifies the options of the request, or of each of its variants
+func (req fileResizeRequest) validate() error {
+	if len(req.Variants) > maxResizeVariants {
+		return fmt.Errorf("at most %d variants may be resized at once", maxResizeVariants)
+	}
+
+	if len(req.Variants) == 0 {
+		return req.options().Validate()
+	}
+
+	for i, v := range req.Variants {
+		if err := v.options().Validate(); err != nil {
+			return fmt.Errorf("variant %d: %v", i, err)
+		}
+	}
+
+	return nil
 }
 
 // FileResizeHandler handles the file resize request
@@ -26,6 +92,12 @@ func (h handler) FileResizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A preset replaces the options of the request body
+	if name := r.URL.Query().Get("preset"); name != "" {
+		h.resizeWithPreset(w, fid, name)
+		return
+	}
+
 	var req fileResizeRequest
 	if err := h.parseRequest(r, &req); err != nil {
 		h.log.Error().Err(err).Msg("Failed to parse file resize request")
@@ -33,13 +105,47 @@ func (h handler) FileResizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate width and height
-	if req.Width <= 0 || req.Height <= 0 {
-		h.log.Error().Msg("Invalid width or height values")
-		http.Error(w, `{"error": "Width and height must be greater than zero"}`, http.StatusBadRequest)
+	// Validate the size, mode and output format
+	if err := req.validate(); err != nil {
+		h.log.Error().Err(err).Msg("Invalid resize options")
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
 
+	h.resize(w, fid, req)
+}
+
+// FilePresetHandler resizes the file with a named preset from the configuration
+func (h handler) FilePresetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fid, name := vars["id"], vars["name"]
+	h.log.Info().Str("file_id", fid).Str("preset", name).Msg("File preset request received")
+	if fid == "" || name == "" {
+		h.log.Error().Msg("File ID and preset name are required")
+		http.Error(w, `{"error": "File id and preset name are required path parameters"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	h.resizeWithPreset(w, fid, name)
+}
+
+// resizeWithPreset resizes the file with the options of the named preset
+// The presets are validated when the configuration is loaded
+func (h handler) resizeWithPreset(w http.ResponseWriter, fid string, name string) {
+	o, ok := h.conf.Preset(name)
+	if !ok {
+		h.log.Error().Str("preset", name).Msg("Preset not found")
+		http.Error(w, `{"error": "Preset not found"}`, http.StatusNotFound)
+		return
+	}
+
+	req := fileResizeRequest{resizeVariant: variantOf(o), preset: name}
+
+	h.resize(w, fid, req)
+}
+
+// resize enqueues the validated request for the file
+func (h handler) resize(w http.ResponseWriter, fid string, req fileResizeRequest) {
 	// Get the file from the database
 	f, err := h.db.FileByID(fid)
 	if err != nil {
@@ -48,50 +154,71 @@ func (h handler) FileResizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If the file is not an image, return an error
-	if !f.IsImage() {
-		h.log.Error().Msg("File is not an image")
-		http.Error(w, `{"error": "File is not an image"}`, http.StatusUnprocessableEntity)
+	// If the file is not an image that can be resized, return an error
+	if !f.Supports(media.ProcessorResize) {
+		h.log.Error().Str("mime_type", f.MimeType).Msg("File is not an image that can be resized")
+		http.Error(w, `{"error": "File is not an image that can be resized"}`, http.StatusUnprocessableEntity)
 		return
 	}
 
 	// Create the payload for the image resize task if the file is an image
-	if err := h.ResizeImage(f, req, h.log); err != nil {
+	j, err := h.ResizeImage(f, req, h.log)
+	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to enqueue image resize task")
 		http.Error(w, `{"error": "Failed to enqueue resize task"}`, http.StatusUnprocessableEntity)
 		return
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	w.Write([]byte(`{"message": "Image resize task enqueued"}`))
+	// A batch is polled by its id until every variant is stored
+	if len(req.Variants) > 0 {
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "Image resize batch enqueued", "job_id": j.ID, "batch_id": j.ID})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Image resize task enqueued", "job_id": j.ID})
 }
 
 // ResizeImage enqueues the image resize task to be processed by the async worker
-func (h handler) ResizeImage(f *models.File, req fileResizeRequest, log *zerolog.Logger) error {
+// A resize with variants is a batch, whose id is the id of its job and is left out of the payload
+func (h handler) ResizeImage(f *models.File, req fileResizeRequest, log *zerolog.Logger) (*models.Job, error) {
 	// Enqueue the image resize task
 	// This will be handled by the async worker
 	// and will be processed in the background
 	payload := &tasks.ImageResizePayload{
 		Width:       req.Width,
 		Height:      req.Height,
+		Mode:        req.Mode,
+		Gravity:     req.Gravity,
+		Background:  req.Background,
+		Filter:      req.Filter,
+		Format:      req.Format,
+		Quality:     req.Quality,
+		Preset:      req.preset,
+		JobID:       uuid.New().String(),
 		FileID:      f.ID,
 		StoragePath: f.StoragePath,
 		Filename:    f.GeneratedName, // The name of the file in the storage path
 	}
 
-	t, err := tasks.NewImageResizeTask(h.ac, payload, log)
+	// The variants of a batch are resized by a single task, which decodes the image once
+	for _, v := range req.Variants {
+		payload.Variants = append(payload.Variants, v.options())
+	}
+
+	t, err := tasks.NewImageResizeTask(h.ac, payload, h.conf.TaskOptions(tasks.ImageResizeTaskType), log)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create image resize task")
-		return err
+		return nil, err
 	}
 
 	// Enqueue the image resize task
-	if err := t.Enqueue(); err != nil {
+	j, err := h.enqueue(f.ID, t)
+	if err != nil {
 		log.Error().Err(err).Msg("Failed to enqueue image resize task")
-		return err
+		return nil, err
 	}
 
 	// Log the image resize task
-	log.Info().Str("file_id", f.ID).Msg("Image resize task enqueued")
-	return nil
+	log.Info().Str("file_id", f.ID).Str("job_id", j.ID).Msg("Image resize task enqueued")
+	return j, nil
 }
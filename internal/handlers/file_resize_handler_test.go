@@ -2,23 +2,31 @@ package handlers_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/handlers"
 	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
 	"simple-file-processor/internal/mocks/mocktasks"
 	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 func TestFileResizeHandler(t *testing.T) {
 	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
 	var tests = []struct {
 		name           string
 		fileID         string
@@ -26,6 +34,11 @@ func TestFileResizeHandler(t *testing.T) {
 		mockClient     func(client *mocktasks.Client)
 		width          int
 		height         int
+		mode           string
+		gravity        string
+		filter         string
+		format         string
+		quality        int
 		expectedStatus int
 	}{
 		{
@@ -34,17 +47,145 @@ func TestFileResizeHandler(t *testing.T) {
 			mockDB: func(db *mockdb.Database) {
 				db.On("FileByID", "valid-file-id").Return(&models.File{
 					ID:                "valid-file-id",
-					Type:              "image/jpeg",
+					Type:              "image",
+					MimeType:          "image/jpeg",
 					UploadedExtension: "jpg",
 				}, nil)
 			},
 			mockClient: func(client *mocktasks.Client) {
-				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 			},
 			width:          100,
 			height:         100,
 			expectedStatus: http.StatusAccepted,
 		},
+		{
+			name:   "valid request with format and quality",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{
+					ID:       "valid-file-id",
+					Type:     "image",
+					MimeType: "image/png",
+				}, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.MatchedBy(func(t *asynq.Task) bool {
+					var p tasks.ImageResizePayload
+					return json.Unmarshal(t.Payload(), &p) == nil && p.Format == "webp" && p.Quality == 70 && p.Filter == "bilinear"
+				}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			width:          100,
+			height:         100,
+			filter:         "bilinear",
+			format:         "webp",
+			quality:        70,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "scale by width",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{
+					ID:       "valid-file-id",
+					Type:     "image",
+					MimeType: "image/jpeg",
+				}, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.MatchedBy(func(t *asynq.Task) bool {
+					var p tasks.ImageResizePayload
+					return json.Unmarshal(t.Payload(), &p) == nil && p.Mode == "scale" && p.Width == 100 && p.Height == 0
+				}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			width:          100,
+			mode:           "scale",
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "scale by width and height",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			height:         100,
+			mode:           "scale",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "fit without height",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			mode:           "fit",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported gravity",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			height:         100,
+			mode:           "fill",
+			gravity:        "up",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported filter",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			height:         100,
+			filter:         "box",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported format",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			height:         100,
+			format:         "bmp",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid quality",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100,
+			height:         100,
+			quality:        101,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "invalid file ID",
 			fileID: "",
@@ -71,6 +212,19 @@ func TestFileResizeHandler(t *testing.T) {
 			height:         -1,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "width and height beyond the maximum",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				// no database call needed
+			},
+			mockClient: func(client *mocktasks.Client) {
+				// no client call needed
+			},
+			width:          100000,
+			height:         100000,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "file not found",
 			fileID: "not-found-file-id",
@@ -90,7 +244,8 @@ func TestFileResizeHandler(t *testing.T) {
 			mockDB: func(db *mockdb.Database) {
 				db.On("FileByID", "not-an-image-file-id").Return(&models.File{
 					ID:                "not-an-image-file-id",
-					Type:              "text/plain",
+					Type:              "other",
+					MimeType:          "text/plain; charset=utf-8",
 					UploadedExtension: "txt",
 				}, nil)
 			},
@@ -107,12 +262,16 @@ func TestFileResizeHandler(t *testing.T) {
 			mockDB: func(db *mockdb.Database) {
 				db.On("FileByID", "valid-file-id").Return(&models.File{
 					ID:                "valid-file-id",
-					Type:              "image/jpeg",
+					Type:              "image",
+					MimeType:          "image/jpeg",
 					UploadedExtension: "jpg",
 				}, nil)
+				db.On("UpdateJob", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+					return fields["state"] == models.JobFailed
+				})).Return(nil)
 			},
 			mockClient: func(client *mocktasks.Client) {
-				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("failed to enqueue task"))
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("failed to enqueue task"))
 			},
 			width:          100,
 			height:         100,
@@ -128,6 +287,7 @@ func TestFileResizeHandler(t *testing.T) {
 			client := new(mocktasks.Client)
 
 			// Set up the mocks
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
 			tt.mockDB(db)
 			tt.mockClient(client)
 
@@ -135,19 +295,323 @@ func TestFileResizeHandler(t *testing.T) {
 			rec := httptest.NewRecorder()
 
 			// create a new request with body
-			body := bytes.NewBuffer([]byte(fmt.Sprintf(`{"width": %d, "height": %d}`, tt.width, tt.height)))
+			body := bytes.NewBuffer([]byte(fmt.Sprintf(`{"width": %d, "height": %d, "mode": %q, "gravity": %q, "filter": %q, "format": %q, "quality": %d}`,
+				tt.width, tt.height, tt.mode, tt.gravity, tt.filter, tt.format, tt.quality)))
 			req := httptest.NewRequest("PUT", "/file/"+tt.fileID+"/resize", body)
 			req.Header.Set("Content-Type", "application/json")
 			req = mux.SetURLVars(req, map[string]string{"id": tt.fileID})
 
 			// Create a new handler
-			handler := handlers.NewHandlers(&log, db, client).GetHandler("FileResizeHandler")
+			handler := handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileResizeHandler")
 
 			// Call the handler
 			handler(rec, req)
 
 			// Check the status code
 			assert.Equal(t, tt.expectedStatus, rec.Code)
+			client.AssertExpectations(t)
 		})
 	}
 }
+
+func TestFileResizeHandlerVariants(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	image := &models.File{ID: "valid-file-id", Type: "image", MimeType: "image/jpeg"}
+	var tests = []struct {
+		name           string
+		body           string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+	}{
+		{
+			name: "variants enqueued as one batch",
+			body: `{"variants": [{"width": 320, "mode": "scale", "format": "webp"}, {"width": 640, "height": 480, "mode": "fit"}]}`,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(image, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.MatchedBy(func(t *asynq.Task) bool {
+					var p tasks.ImageResizePayload
+					return json.Unmarshal(t.Payload(), &p) == nil && p.JobID == "" && len(p.Variants) == 2 &&
+						p.Variants[0].Width == 320 && p.Variants[0].Format == "webp" && p.Variants[1].Mode == "fit"
+				}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "invalid variant",
+			body:           `{"variants": [{"width": 320, "mode": "scale"}, {"width": 0, "height": 0}]}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many variants",
+			body:           `{"variants": [` + strings.Repeat(`{"width": 100, "height": 100},`, 20) + `{"width": 100, "height": 100}]}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/file/valid-file-id/resize", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req = mux.SetURLVars(req, map[string]string{"id": "valid-file-id"})
+
+			handler := handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileResizeHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			client.AssertExpectations(t)
+			if tt.expectedStatus == http.StatusAccepted {
+				var res map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.NotEmpty(t, res["batch_id"])
+				assert.Equal(t, res["job_id"], res["batch_id"])
+			}
+		})
+	}
+}
+
+func TestFilePresetHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, err := config.FromJSON([]byte(`{"presets": {"thumbnail": {"width": 150, "height": 150, "mode": "fill", "format": "webp"}}}`))
+	assert.NoError(t, err)
+	image := &models.File{ID: "valid-file-id", Type: "image", MimeType: "image/jpeg"}
+	thumbnail := func(client *mocktasks.Client) {
+		client.On("Enqueue", mock.MatchedBy(func(t *asynq.Task) bool {
+			var p tasks.ImageResizePayload
+			return json.Unmarshal(t.Payload(), &p) == nil && p.Preset == "thumbnail" &&
+				p.Width == 150 && p.Height == 150 && p.Mode == "fill" && p.Format == "webp"
+		}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	}
+
+	var tests = []struct {
+		name           string
+		handler        string
+		method         string
+		target         string
+		vars           map[string]string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+	}{
+		{
+			name:    "preset path",
+			handler: "FilePresetHandler",
+			method:  "POST",
+			target:  "/file/valid-file-id/presets/thumbnail",
+			vars:    map[string]string{"id": "valid-file-id", "name": "thumbnail"},
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(image, nil)
+			},
+			mockClient:     thumbnail,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown preset path",
+			handler:        "FilePresetHandler",
+			method:         "POST",
+			target:         "/file/valid-file-id/presets/banner",
+			vars:           map[string]string{"id": "valid-file-id", "name": "banner"},
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "preset query parameter",
+			handler: "FileResizeHandler",
+			method:  "PUT",
+			target:  "/file/valid-file-id/resize?preset=thumbnail",
+			vars:    map[string]string{"id": "valid-file-id"},
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(image, nil)
+			},
+			mockClient:     thumbnail,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown preset query parameter",
+			handler:        "FileResizeHandler",
+			method:         "PUT",
+			target:         "/file/valid-file-id/resize?preset=banner",
+			vars:           map[string]string{"id": "valid-file-id"},
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:    "file is not an image",
+			handler: "FilePresetHandler",
+			method:  "POST",
+			target:  "/file/valid-file-id/presets/thumbnail",
+			vars:    map[string]string{"id": "valid-file-id", "name": "thumbnail"},
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Type: "video", MimeType: "video/mp4"}, nil)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req = mux.SetURLVars(req, tt.vars)
+
+			handler := handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler(tt.handler)
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			client.AssertExpectations(t)
+		})
+	}
+}
+
+// TestFileResizeHandlerUnique tests that identical resizes of a file collapse into the job that is already enqueued
+func TestFileResizeHandlerUnique(t *testing.T) {
+	log := zerolog.Nop()
+	conf, err := config.FromJSON([]byte(`{"tasks": {"image:resize": {"unique": 300}}}`))
+	assert.NoError(t, err)
+	active := &models.Job{ID: "active-job-id", FileID: "valid-file-id", TaskType: tasks.ImageResizeTaskType, State: models.JobQueued}
+
+	var tests = []struct {
+		name           string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+		expectedJob    string
+	}{
+		{
+			name: "first resize",
+			mockDB: func(db *mockdb.Database) {
+				db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+				db.On("InsertJob", mock.Anything).Return(nil).Once()
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "resize already enqueued",
+			mockDB: func(db *mockdb.Database) {
+				db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(active, nil).Once()
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusAccepted,
+			expectedJob:    "active-job-id",
+		},
+		{
+			name: "resize enqueued concurrently",
+			mockDB: func(db *mockdb.Database) {
+				db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+				db.On("InsertJob", mock.Anything).Return(nil).Once()
+				db.On("DeleteJob", mock.Anything).Return(nil).Once()
+				db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(active, nil).Once()
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, asynq.ErrDuplicateTask).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedJob:    "active-job-id",
+		},
+		{
+			name: "duplicate without an active job",
+			mockDB: func(db *mockdb.Database) {
+				db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Twice()
+				db.On("InsertJob", mock.Anything).Return(nil).Twice()
+				db.On("DeleteJob", mock.Anything).Return(nil).Once()
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, asynq.ErrDuplicateTask).Once()
+				// The stale lock is left out, so the task is enqueued with one option less
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Type: "image", MimeType: "image/jpeg", UploadedExtension: "jpg"}, nil)
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/file/valid-file-id/resize", bytes.NewBufferString(`{"width": 100, "height": 100}`))
+			req = mux.SetURLVars(req, map[string]string{"id": "valid-file-id"})
+			handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileResizeHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedJob != "" {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.expectedJob, body["job_id"])
+			}
+			db.AssertExpectations(t)
+			client.AssertExpectations(t)
+		})
+	}
+}
+
+// TestFileResizeHandlerUniqueBatch tests that the same batch enqueued twice collapses into the job of the first
+func TestFileResizeHandlerUniqueBatch(t *testing.T) {
+	log := zerolog.Nop()
+	conf, err := config.FromJSON([]byte(`{"tasks": {"image:resize": {"unique": 300}}}`))
+	assert.NoError(t, err)
+	body := `{"variants": [{"width": 320, "mode": "scale", "format": "webp"}, {"width": 640, "height": 480, "mode": "fit"}]}`
+
+	db := new(mockdb.Database)
+	client := new(mocktasks.Client)
+	db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Type: "image", MimeType: "image/jpeg"}, nil)
+
+	// The first batch is enqueued and its job recorded with the payload that is hashed for uniqueness
+	var first *models.Job
+	db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.Anything).Return(nil, gorm.ErrRecordNotFound).Once()
+	db.On("InsertJob", mock.Anything).Run(func(args mock.Arguments) { first = args.Get(0).(*models.Job) }).Return(nil).Once()
+	client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+
+	// The second batch only matches the job of the first when their payloads are identical
+	db.On("ActiveJob", "valid-file-id", tasks.ImageResizeTaskType, mock.MatchedBy(func(p []byte) bool {
+		return first != nil && bytes.Equal(p, first.Payload)
+	})).Return(func(string, string, []byte) *models.Job { return first }, nil).Once()
+
+	batch := func() map[string]string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("PUT", "/file/valid-file-id/resize", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "valid-file-id"})
+		handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileResizeHandler")(rec, req)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		var res map[string]string
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+
+	res := batch()
+	assert.Equal(t, first.ID, res["batch_id"])
+	assert.Equal(t, res, batch())
+	db.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
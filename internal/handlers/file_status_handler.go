@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"simple-file-processor/internal/models"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type statusResponse struct {
+	FileID          string                    `json:"file_id"`
+	Status          string                    `json:"status"`
+	StatusChangedAt *time.Time                `json:"status_changed_at,omitempty"`
+	Transitions     []models.StatusTransition `json:"transitions"` // e.g. every change of the status from oldest to newest
+}
+
+// FileStatusHandler returns the status of a file along with the history of its transitions
+func (h handler) FileStatusHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File status request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	sts, err := h.db.StatusTransitions(fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list status transitions of file")
+		http.Error(w, `{"error": "Failed to list status transitions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statusResponse{
+		FileID:          f.ID,
+		Status:          f.Status,
+		StatusChangedAt: f.StatusChangedAt,
+		Transitions:     sts,
+	})
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestFileStatusHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	var tests = []struct {
+		name           string
+		fileID         string
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+	}{
+		{
+			name:   "status found",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id", Status: models.StatusCompleted}, nil)
+				db.On("StatusTransitions", "valid-file-id").Return([]models.StatusTransition{
+					{FileID: "valid-file-id", From: models.StatusPending, To: models.StatusProcessing, JobID: "job-id"},
+					{FileID: "valid-file-id", From: models.StatusProcessing, To: models.StatusCompleted, JobID: "job-id"},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "file not found",
+			fileID: "not-found-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "not-found-file-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "database error",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id"}, nil)
+				db.On("StatusTransitions", "valid-file-id").Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/file/"+tt.fileID+"/status", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.fileID})
+
+			handler := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileStatusHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var res struct {
+					Status      string                    `json:"status"`
+					Transitions []models.StatusTransition `json:"transitions"`
+				}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, models.StatusCompleted, res.Status)
+				assert.Len(t, res.Transitions, 2)
+				assert.Equal(t, models.StatusProcessing, res.Transitions[0].To)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type filePosterRequest struct {
+	Timestamp *float64 `json:"timestamp"` // e.g. 12.5, the second of the video the poster is taken at
+	Percent   *float64 `json:"percent"`   // e.g. 25, the percentage of the duration, 10 when neither is given
+	Width     int      `json:"width"`     // e.g. 640, the width of the video when zero
+}
+
+type fileSpriteRequest struct {
+	Frames  int `json:"frames"`  // e.g. 20, the number of evenly spaced frames
+	Columns int `json:"columns"` // e.g. 5, the square root of the frames when zero
+	Width   int `json:"width"`   // e.g. 160, the width of each frame
+}
+
+// options returns the options the poster is taken with
+func (req filePosterRequest) options() (lib.PosterOptions, error) {
+	if req.Timestamp != nil && req.Percent != nil {
+		return lib.PosterOptions{}, errors.New("a poster is taken at either a timestamp or a percentage")
+	}
+
+	o := lib.PosterOptions{Percent: lib.DefaultPosterPercent, Width: req.Width}
+	if req.Timestamp != nil {
+		o.Timestamp, o.Percent = *req.Timestamp, 0
+	}
+
+	if req.Percent != nil {
+		o.Percent = *req.Percent
+	}
+
+	return o, o.Validate()
+}
+
+// options returns the options the sprite sheet is made with
+func (req fileSpriteRequest) options() lib.SpriteOptions {
+	return lib.SpriteOptions{Frames: req.Frames, Columns: req.Columns, Width: req.Width}
+}
+
+// FilePosterHandler takes a frame of a video as its poster, at a timestamp or at a percentage of its duration
+func (h handler) FilePosterHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File poster request received")
+
+	// The body may be left out to take the poster at the default percentage
+	var req filePosterRequest
+	if err := h.parseRequest(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Error().Err(err).Msg("Failed to parse file poster request")
+		http.Error(w, `{"error": "Failed to parse request"}`, http.StatusBadRequest)
+		return
+	}
+
+	o, err := req.options()
+	if err != nil {
+		h.log.Error().Err(err).Msg("Invalid poster options")
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	f, ok := h.video(w, fid, media.ProcessorPoster)
+	if !ok {
+		return
+	}
+
+	j, err := h.poster(f, o)
+	if err != nil {
+		h.thumbnailError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Video poster task enqueued", "job_id": j.ID})
+}
+
+// FileSpriteHandler tiles evenly spaced frames of a video into a sprite sheet with a WebVTT index
+// The sheet and its index are stored in a batch, whose id is the id of its job
+func (h handler) FileSpriteHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File sprite request received")
+
+	// The body may be left out to make the sheet with the default options
+	var req fileSpriteRequest
+	if err := h.parseRequest(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Error().Err(err).Msg("Failed to parse file sprite request")
+		http.Error(w, `{"error": "Failed to parse request"}`, http.StatusBadRequest)
+		return
+	}
+
+	o := req.options()
+	if err := o.Validate(); err != nil {
+		h.log.Error().Err(err).Msg("Invalid sprite options")
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	f, ok := h.video(w, fid, media.ProcessorSprite)
+	if !ok {
+		return
+	}
+
+	j, err := h.sprite(f, o)
+	if err != nil {
+		h.thumbnailError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Video sprite task enqueued", "job_id": j.ID, "batch_id": j.ID})
+}
+
+// video returns the file, unless it is not a video that may be run through the processor
+func (h handler) video(w http.ResponseWriter, fid string, processor string) (*models.File, bool) {
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to get file by ID")
+		http.Error(w, `{"error": "File not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	if !f.Supports(processor) {
+		h.log.Error().Str("mime_type", f.MimeType).Str("processor", processor).Msg("File is not a video that supports the processor")
+		http.Error(w, `{"error": "File is not a video that supports the processor"}`, http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	return f, true
+}
+
+// thumbnailError answers a poster or sprite request whose task could not be enqueued
+func (h handler) thumbnailError(w http.ResponseWriter, err error) {
+	h.log.Error().Err(err).Msg("Failed to enqueue video thumbnail task")
+	http.Error(w, `{"error": "Failed to enqueue task"}`, http.StatusUnprocessableEntity)
+}
+
+// poster enqueues the video poster task to be processed by the async worker
+func (h handler) poster(f *models.File, o lib.PosterOptions) (*models.Job, error) {
+	payload := &tasks.VideoPosterPayload{
+		Timestamp:   o.Timestamp,
+		Percent:     o.Percent,
+		Width:       o.Width,
+		JobID:       uuid.New().String(),
+		FileID:      f.ID,
+		StoragePath: f.StoragePath,
+		Filename:    f.GeneratedName, // The name of the file in the storage path
+	}
+
+	t, err := tasks.NewVideoPosterTask(h.ac, payload, h.conf.TaskOptions(tasks.VideoPosterTaskType), h.log)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to create video poster task")
+		return nil, err
+	}
+
+	j, err := h.enqueue(f.ID, t)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to enqueue video poster task")
+		return nil, err
+	}
+
+	h.log.Info().Str("file_id", f.ID).Str("job_id", j.ID).Msg("Video poster task enqueued")
+	return j, nil
+}
+
+// sprite enqueues the video sprite sheet task to be processed by the async worker
+func (h handler) sprite(f *models.File, o lib.SpriteOptions) (*models.Job, error) {
+	payload := &tasks.VideoSpritePayload{
+		Frames:      o.Frames,
+		Columns:     o.Columns,
+		Width:       o.Width,
+		JobID:       uuid.New().String(),
+		FileID:      f.ID,
+		StoragePath: f.StoragePath,
+		Filename:    f.GeneratedName, // The name of the file in the storage path
+	}
+
+	t, err := tasks.NewVideoSpriteTask(h.ac, payload, h.conf.TaskOptions(tasks.VideoSpriteTaskType), h.log)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to create video sprite task")
+		return nil, err
+	}
+
+	j, err := h.enqueue(f.ID, t)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to enqueue video sprite task")
+		return nil, err
+	}
+
+	h.log.Info().Str("file_id", f.ID).Str("job_id", j.ID).Msg("Video sprite task enqueued")
+	return j, nil
+}
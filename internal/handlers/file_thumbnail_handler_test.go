@@ -0,0 +1,276 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestFilePosterHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	video := &models.File{ID: "file-id", Type: "video", MimeType: "video/mp4", StoragePath: "videos", GeneratedName: "file-id.mp4"}
+
+	// poster matches a poster task of the video with the given payload
+	poster := func(expected tasks.VideoPosterPayload) interface{} {
+		return mock.MatchedBy(func(t *asynq.Task) bool {
+			var p tasks.VideoPosterPayload
+			if t.Type() != tasks.VideoPosterTaskType || json.Unmarshal(t.Payload(), &p) != nil {
+				return false
+			}
+
+			expected.FileID, expected.StoragePath, expected.Filename = "file-id", "videos", "file-id.mp4"
+			return p == expected
+		})
+	}
+
+	var tests = []struct {
+		name           string
+		body           string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+	}{
+		{
+			name: "default percentage",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", poster(tasks.VideoPosterPayload{Percent: 10}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "timestamp and width",
+			body: `{"timestamp": 12.5, "width": 640}`,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", poster(tasks.VideoPosterPayload{Timestamp: 12.5, Width: 640}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "percentage",
+			body: `{"percent": 50}`,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", poster(tasks.VideoPosterPayload{Percent: 50}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "timestamp and percentage",
+			body:           `{"timestamp": 12.5, "percent": 50}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "percentage out of range",
+			body:           `{"percent": 100}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "width too large",
+			body:           `{"width": 10000}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			body:           `{"timestamp": "12.5"}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "file not found",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "file is not a video",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(&models.File{ID: "file-id", Type: "image", MimeType: "image/png"}, nil)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "enqueue error",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+				db.On("UpdateJob", mock.Anything, mock.Anything).Return(nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/file/file-id/poster", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "file-id"})
+			handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FilePosterHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedStatus == http.StatusAccepted {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["job_id"])
+			}
+			db.AssertExpectations(t)
+			client.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFileSpriteHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	video := &models.File{ID: "file-id", Type: "video", MimeType: "video/mp4", StoragePath: "videos", GeneratedName: "file-id.mp4"}
+
+	// sprite matches a sprite task of the video with the given payload
+	sprite := func(expected tasks.VideoSpritePayload) interface{} {
+		return mock.MatchedBy(func(t *asynq.Task) bool {
+			var p tasks.VideoSpritePayload
+			if t.Type() != tasks.VideoSpriteTaskType || json.Unmarshal(t.Payload(), &p) != nil {
+				return false
+			}
+
+			expected.FileID, expected.StoragePath, expected.Filename = "file-id", "videos", "file-id.mp4"
+			return p == expected
+		})
+	}
+
+	var tests = []struct {
+		name           string
+		body           string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+	}{
+		{
+			name: "default options",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", sprite(tasks.VideoSpritePayload{}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "frames, columns and width",
+			body: `{"frames": 50, "columns": 10, "width": 120}`,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", sprite(tasks.VideoSpritePayload{Frames: 50, Columns: 10, Width: 120}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "too many frames",
+			body:           `{"frames": 1000}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "sheet too large",
+			body:           `{"frames": 400, "width": 640}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "file is not a video",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(&models.File{ID: "file-id", Type: "image", MimeType: "image/png"}, nil)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "enqueue error",
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+				db.On("UpdateJob", mock.Anything, mock.Anything).Return(nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/file/file-id/sprite", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "file-id"})
+			handlers.NewHandlers(conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileSpriteHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedStatus == http.StatusAccepted {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["job_id"])
+				assert.Equal(t, body["job_id"], body["batch_id"])
+			}
+			db.AssertExpectations(t)
+			client.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type fileTranscodeRequest struct {
+	Profiles []string `json:"profiles"` // e.g. 720p, 480p-webm, every configured profile when empty
+}
+
+// profiles returns the configured profiles that the request names, or every profile when it names none
+func (h handler) profiles(req fileTranscodeRequest) ([]lib.TranscodeProfile, error) {
+	if len(req.Profiles) == 0 {
+		return h.conf.TranscodeProfiles(), nil
+	}
+
+	profiles := make([]lib.TranscodeProfile, 0, len(req.Profiles))
+	for _, name := range req.Profiles {
+		p, ok := h.conf.TranscodeProfile(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown transcode profile %q", name)
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	return profiles, nil
+}
+
+// FileTranscodeHandler transcodes a video to the configured profiles that the request names
+// Every rendition is transcoded by a single task and stored in a batch, whose id is the id of its job
+func (h handler) FileTranscodeHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File transcode request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The body may be left out to transcode to every profile
+	var req fileTranscodeRequest
+	if err := h.parseRequest(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Error().Err(err).Msg("Failed to parse file transcode request")
+		http.Error(w, `{"error": "Failed to parse request"}`, http.StatusBadRequest)
+		return
+	}
+
+	profiles, err := h.profiles(req)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Invalid transcode profiles")
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if len(profiles) == 0 {
+		h.log.Error().Msg("No transcode profiles are configured")
+		http.Error(w, `{"error": "No transcode profiles are configured"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	f, err := h.db.FileByID(fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to get file by ID")
+		http.Error(w, `{"error": "File not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if !f.Supports(media.ProcessorTranscode) {
+		h.log.Error().Str("mime_type", f.MimeType).Msg("File is not a video that can be transcoded")
+		http.Error(w, `{"error": "File is not a video that can be transcoded"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	j, err := h.transcode(f, profiles)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to enqueue video transcode task")
+		http.Error(w, `{"error": "Failed to enqueue transcode task"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Video transcode task enqueued", "job_id": j.ID, "batch_id": j.ID})
+}
+
+// transcode enqueues the video transcode task to be processed by the async worker
+func (h handler) transcode(f *models.File, profiles []lib.TranscodeProfile) (*models.Job, error) {
+	payload := &tasks.VideoTranscodePayload{
+		Profiles:    profiles,
+		JobID:       uuid.New().String(),
+		FileID:      f.ID,
+		StoragePath: f.StoragePath,
+		Filename:    f.GeneratedName, // The name of the file in the storage path
+	}
+
+	t, err := tasks.NewVideoTranscodeTask(h.ac, payload, h.conf.TaskOptions(tasks.VideoTranscodeTaskType), h.log)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to create video transcode task")
+		return nil, err
+	}
+
+	j, err := h.enqueue(f.ID, t)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to enqueue video transcode task")
+		return nil, err
+	}
+
+	h.log.Info().Str("file_id", f.ID).Str("job_id", j.ID).Msg("Video transcode task enqueued")
+	return j, nil
+}
@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestFileTranscodeHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{"transcode_profiles": {
+		"720p": {"format": "mp4", "width": 1280, "height": 720, "video_bitrate": 2500},
+		"480p-webm": {"format": "webm", "height": 480, "video_bitrate": 800, "audio_bitrate": 96}
+	}}`))
+	empty, _ := config.FromJSON([]byte(`{}`))
+	video := &models.File{ID: "file-id", Type: "video", MimeType: "video/mp4", StoragePath: "videos", GeneratedName: "file-id.mp4"}
+
+	// profiles matches a transcode task of the video to the named profiles
+	profiles := func(names ...string) interface{} {
+		return mock.MatchedBy(func(t *asynq.Task) bool {
+			var p tasks.VideoTranscodePayload
+			if t.Type() != tasks.VideoTranscodeTaskType || json.Unmarshal(t.Payload(), &p) != nil || len(p.Profiles) != len(names) {
+				return false
+			}
+
+			for i, name := range names {
+				if p.Profiles[i].Name != name {
+					return false
+				}
+			}
+
+			return p.FileID == "file-id" && p.Filename == "file-id.mp4" && p.JobID == ""
+		})
+	}
+
+	var tests = []struct {
+		name           string
+		conf           config.Config
+		body           string
+		mockDB         func(db *mockdb.Database)
+		mockClient     func(client *mocktasks.Client)
+		expectedStatus int
+	}{
+		{
+			name: "every profile",
+			conf: conf,
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", profiles("480p-webm", "720p"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "named profiles",
+			conf: conf,
+			body: `{"profiles": ["720p"]}`,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", profiles("720p"), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown profile",
+			conf:           conf,
+			body:           `{"profiles": ["4k"]}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			conf:           conf,
+			body:           `{"profiles": "720p"}`,
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no profiles configured",
+			conf:           empty,
+			body:           "",
+			mockDB:         func(db *mockdb.Database) {},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "file not found",
+			conf: conf,
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "file is not a video",
+			conf: conf,
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(&models.File{ID: "file-id", Type: "image", MimeType: "image/png"}, nil)
+			},
+			mockClient:     func(client *mocktasks.Client) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "enqueue error",
+			conf: conf,
+			body: "",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "file-id").Return(video, nil)
+				db.On("UpdateJob", mock.Anything, mock.Anything).Return(nil)
+			},
+			mockClient: func(client *mocktasks.Client) {
+				client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			client := new(mocktasks.Client)
+			db.On("InsertJob", mock.Anything).Return(nil).Maybe()
+			tt.mockDB(db)
+			tt.mockClient(client)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/file/file-id/transcode", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "file-id"})
+			handlers.NewHandlers(tt.conf, &log, db, client, new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileTranscodeHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			if tt.expectedStatus == http.StatusAccepted {
+				var body map[string]string
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.NotEmpty(t, body["job_id"])
+				assert.Equal(t, body["job_id"], body["batch_id"])
+			}
+			db.AssertExpectations(t)
+			client.AssertExpectations(t)
+		})
+	}
+}
@@ -1,90 +1,111 @@
 package handlers
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"fmt"
 	"io"
 	"mime"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
 	"simple-file-processor/internal/models"
 	"simple-file-processor/internal/tasks"
 
 	"github.com/google/uuid"
-	"github.com/rs/zerolog"
 )
 
 var uploadBase = "uploads"
 
+const (
+	webhookHeader = "X-Webhook-URL" // The header that registers a webhook for the events of an upload
+	webhookField  = "webhook_url"   // The form field that registers a webhook, sent before the file
+	maxFieldSize  = 4 << 10         // The largest form field value that is kept, 4 KB
+)
+
+// The room left for the multipart boundaries and headers on top of the file itself
+const multipartOverhead = 1 << 20 // 1 MB
+
 // FileUploadHandler handles the file upload request
+// The file is streamed from the multipart body straight to the storage
+// while its size and checksum are computed, so it is never buffered in
+// memory or spilled to a temporary file
 func (h handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse the multipart form data
-	err := r.ParseMultipartForm(10 << 20) // 10 MB limit
-	if err != nil {
+	// Reject bodies that can never fit before reading them
+	ceil := h.conf.UploadSizeCeiling() + multipartOverhead
+	if r.ContentLength > ceil {
 		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	// Get the file from the form data
-	f, inf, err := r.FormFile("file")
+	r.Body = http.MaxBytesReader(w, r.Body, ceil)
+	mr, err := r.MultipartReader()
 	if err != nil {
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
-	defer f.Close()
-	// Create the upload directory if it doesn't exist
-	if err := os.MkdirAll(uploadBase, os.ModePerm); err != nil {
-		h.log.Error().Err(err).Msg("Failed to create upload directory")
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// Get the file from the form data, along with the fields sent before it
+	part, fields, err := filePart(mr, "file")
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to read the file from the form data")
+		http.Error(w, http.StatusText(requestErrorStatus(err)), requestErrorStatus(err))
 		return
 	}
 
-	// Generate unique id for the file and name
-	id := uuid.New().String() // construct unique id for the file to be stored in the database and on the file system
-	ext := filepath.Ext(inf.Filename)
-	var tExt string
-	if len(ext) > 1 {
-		tExt = ext[1:]
-	} else {
-		tExt = "unknown" // if no extension is provided
-	}
+	defer part.Close()
 
-	gn := id + "_" + inf.Filename                          // construct unique name for the file to be stored on the file system
-	up := filepath.Join(uploadBase, filepath.Join(id, gn)) // construct unique path for the file to be stored on the file system
-	sp := filepath.Join(uploadBase, id)
-	mt := mime.TypeByExtension(ext)
-	if mt == "" {
-		mt = "application/octet-stream" // default mime type
+	// A webhook may be registered for the events of this file alone
+	wh, err := webhookURL(r.Context(), r.Header.Get(webhookHeader), fields.Get(webhookField))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
 
-	// Create the upload directory for the file
-	if err := os.MkdirAll(filepath.Join(uploadBase, id), os.ModePerm); err != nil {
-		h.log.Error().Err(err).Msg("Failed to create upload directory")
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// Stream the file to the storage, the size is not known up front
+	// The content type is detected from the first bytes rather than the extension
+	file := newFile(part.FileName())
+	file.WebhookURL = wh
+	body, mt := sniff(part)
+	if err := h.setContentType(file, mt); err != nil {
+		h.log.Error().Err(err).Str("mime_type", mt).Msg("Rejected uploaded file " + file.OriginalName)
+		http.Error(w, `{"error": "File content does not match its extension"}`, http.StatusUnsupportedMediaType)
 		return
 	}
 
-	// Create the file on the "server" (file system)
-	if err := CreateFile(up, f, h.log); err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// Uploads in flight are waited for on shutdown, and removed when abandoned
+	up := path.Join(file.StoragePath, file.GeneratedName)
+	defer h.drain.upload(up)()
+
+	// The request is cancelled once its connection closes, while the cleanup must still run
+	cleanup := context.WithoutCancel(r.Context())
+	ur := newUploadReader(body, h.conf.MaxUploadSize(file.Type))
+	if _, err := h.st.Put(r.Context(), up, ur, -1, file.MimeType); err != nil {
+		status := ur.status()
+		h.log.Error().Err(err).Int("status", status).Msg("Failed to store uploaded file")
+		h.st.Delete(cleanup, up) // remove anything the failed upload left behind
+		if status == http.StatusRequestEntityTooLarge {
+			http.Error(w, "File is too large", status)
+			return
+		}
+
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
 
 	// Track upload info for database
-	file := &models.File{
-		ID:                id,
-		GeneratedName:     gn,
-		MimeType:          mt,
-		OriginalName:      inf.Filename,
-		Size:              inf.Size,
-		StoragePath:       sp,
-		UploadedExtension: tExt,
-	}
+	file.Checksum = ur.Checksum()
+	file.Size = ur.Size()
 
 	// Insert the file metadata info into the database
 	if err := h.db.InsertFileMetadata(file); err != nil {
 		h.log.Error().Err(err).Msg("Failed to insert file content into the database")
+		h.st.Delete(cleanup, up) // a file without a record is never served or processed
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -92,39 +113,177 @@ func (h handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 	// Return success response
 	Success(w, file)
 
-	// Generate metadata for the file
-	generateVideoMetadata(h, file)
+	// Kick off the processing of the file
+	afterUpload(h, file)
 
 	// Log the file upload
-	h.log.Info().Str("file_id", id).
-		Str("file_name", inf.Filename).
-		Str("stored_path", sp).
+	h.log.Info().Str("file_id", file.ID).
+		Str("file_name", file.OriginalName).
+		Str("stored_path", file.StoragePath).
 		Msg("File uploaded successfully")
 
 }
 
-func generateVideoMetadata(h handler, f *models.File) {
-	if !f.IsVideo() {
-		return
+// newFile builds the record of a new upload with the given file name
+// The file is given a unique id and name so that uploads never collide
+func newFile(filename string) *models.File {
+	filename = filepath.Base(filename)
+
+	// Generate unique id for the file and name
+	id := uuid.New().String() // construct unique id for the file to be stored in the database and on the file system
+	ext := filepath.Ext(filename)
+	var tExt string
+	if len(ext) > 1 {
+		tExt = ext[1:]
+	} else {
+		tExt = "unknown" // if no extension is provided
+	}
+
+	// The mime type is replaced by the one detected from the content once it is read
+	mt := media.Default().MimeTypeByExtension(ext)
+	if mt == "" {
+		mt = mime.TypeByExtension(ext)
+	}
+
+	if mt == "" {
+		mt = "application/octet-stream" // default mime type
 	}
 
+	return &models.File{
+		ID:                id,
+		GeneratedName:     id + "_" + filename, // construct unique name for the file to be stored in the storage
+		MimeType:          mt,
+		OriginalName:      filename,
+		StoragePath:       path.Join(uploadBase, id),
+		Type:              models.FileType(mt),
+		UploadedExtension: tExt,
+	}
+}
+
+// sniff detects the content type of a file from its first bytes
+// The returned reader still yields every byte of the file
+func sniff(r io.Reader) (io.Reader, string) {
+	br := bufio.NewReaderSize(r, lib.SniffLen)
+	head, _ := br.Peek(lib.SniffLen) // read errors are returned again by the reader
+	return br, lib.DetectContentType(head)
+}
+
+// setContentType records the detected content type on the file, along with
+// whether it mismatches the extension, which is rejected when configured so
+func (h handler) setContentType(f *models.File, mt string) error {
+	f.MimeType = mt
+	f.Type = models.FileType(mt)
+	f.ExtensionMismatch = !media.Default().ExtensionMatches(mt, f.UploadedExtension)
+	if f.ExtensionMismatch && h.conf.RejectExtensionMismatch() {
+		return errExtensionMismatch
+	}
+
+	return nil
+}
+
+// filePart advances the multipart reader to the file with the given field name
+// The values of the fields sent before the file are returned along with it,
+// while other files and oversized values are skipped
+func filePart(mr *multipart.Reader, field string) (*multipart.Part, url.Values, error) {
+	fields := url.Values{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("form data does not contain the %q field", field)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == field && part.FileName() != "" {
+			return part, fields, nil
+		}
+
+		if part.FileName() == "" {
+			v, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if len(v) <= maxFieldSize {
+				fields.Add(part.FormName(), string(v))
+			}
+		}
+
+		part.Close()
+	}
+}
+
+// webhookURL returns the webhook registered for an upload through its header or form field
+// The header takes precedence, and no webhook is registered when both are empty
+// Webhooks that resolve to loopback or private addresses are rejected, as the worker posts to them
+func webhookURL(ctx context.Context, header string, field string) (string, error) {
+	u := header
+	if u == "" {
+		u = field
+	}
+
+	if u == "" {
+		return "", nil
+	}
+
+	return u, events.ValidatePublicURL(ctx, u)
+}
+
+// afterUpload announces a fully uploaded file and runs its processing pipeline
+// Every upload flow calls this once the file is stored and recorded
+func afterUpload(h handler, f *models.File) {
+	h.notifier.Notify(events.New(events.FileUploaded, f.ID, f), f)
+
+	for _, st := range h.conf.Pipeline(f.MimeType, f.Type) {
+		// A pipeline may list processors that some of the types of its category do not allow
+		if !f.Supports(st.Processor) {
+			h.log.Debug().Str("file_id", f.ID).Str("processor", st.Processor).Msg("Skipping processor the file does not support")
+			continue
+		}
+
+		switch st.Processor {
+		case media.ProcessorResize:
+			req := fileResizeRequest{resizeVariant: variantOf(st.Options), preset: st.Preset}
+			if _, err := h.ResizeImage(f, req, h.log); err != nil {
+				h.log.Error().Err(err).Str("file_id", f.ID).Msg("Failed to enqueue upload resize task")
+			}
+		case media.ProcessorVideoMetadata:
+			generateVideoMetadata(h, f)
+		case media.ProcessorTranscode:
+			transcodeUpload(h, f, st.Preset)
+		case media.ProcessorPoster:
+			if _, err := h.poster(f, lib.PosterOptions{Percent: lib.DefaultPosterPercent}); err != nil {
+				h.log.Error().Err(err).Str("file_id", f.ID).Msg("Failed to enqueue upload poster task")
+			}
+		case media.ProcessorSprite:
+			if _, err := h.sprite(f, lib.SpriteOptions{}); err != nil {
+				h.log.Error().Err(err).Str("file_id", f.ID).Msg("Failed to enqueue upload sprite task")
+			}
+		}
+	}
+}
+
+func generateVideoMetadata(h handler, f *models.File) {
+
 	// Create a task to generate metadata for the file
 	p := &tasks.VideoMetadataTaskPayload{
+		JobID:       uuid.New().String(),
 		FileID:      f.ID,
 		StoragePath: f.StoragePath,
 		Filename:    f.GeneratedName,
 	}
 
 	// Create a new video metadata task
-	task, err := tasks.NewVideoMetadataTask(h.ac, p, h.log)
+	task, err := tasks.NewVideoMetadataTask(h.ac, p, h.conf.TaskOptions(tasks.VideoMetadataTaskType), h.log)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to create video metadata task")
 		return
 	}
 
 	// Enqueue the task
-	err = task.Enqueue()
-	if err != nil {
+	if _, err := h.enqueue(f.ID, task); err != nil {
 		h.log.Error().Err(err).Msg("Failed to enqueue video metadata task")
 		return
 	}
@@ -132,27 +291,25 @@ func generateVideoMetadata(h handler, f *models.File) {
 	h.log.Info().Msg("Video metadata task enqueued successfully for file: " + f.ID)
 }
 
-func Success(w http.ResponseWriter, f *models.File) {
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	resp, _ := json.Marshal(f)
-	w.Write(resp)
-}
+// transcodeUpload transcodes the video to the named profile, or to every profile when none is named
+// The profiles of pipelines are validated when the configuration is loaded
+func transcodeUpload(h handler, f *models.File, name string) {
+	var req fileTranscodeRequest
+	if name != "" {
+		req.Profiles = []string{name}
+	}
 
-func CreateFile(path string, f io.Reader, log *zerolog.Logger) error {
-	// Create the file on the "server" (file system)
-	dst, err := os.Create(path)
+	profiles, err := h.profiles(req)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create file")
-		return err
+		h.log.Error().Err(err).Str("file_id", f.ID).Msg("Failed to get transcode profiles of upload")
+		return
 	}
-	defer dst.Close() // close the file
 
-	// Copy the uploaded file to the destination file
-	if _, err := io.Copy(dst, f); err != nil {
-		log.Error().Err(err).Msg("Failed to copy file")
-		return err
+	if _, err := h.transcode(f, profiles); err != nil {
+		h.log.Error().Err(err).Str("file_id", f.ID).Msg("Failed to enqueue upload transcode task")
 	}
+}
 
-	return nil
+func Success(w http.ResponseWriter, f *models.File) {
+	writeJSON(w, http.StatusOK, f)
 }
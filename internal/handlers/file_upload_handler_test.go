@@ -2,16 +2,24 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
 	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"simple-file-processor/internal/tasks"
 
 	"github.com/hibiken/asynq"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +30,9 @@ const (
 	hKey          = "FileUploadHandler"
 	testTxtFile   = "test.txt"
 	testVideoFile = "test.mp4"
+	testContent   = "This is a test file"
+	// The leading bytes of an mp4 file, which is all that is needed to detect its type
+	testVideoContent = "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"
 )
 
 func ResponseRecorder() *httptest.ResponseRecorder {
@@ -29,7 +40,7 @@ func ResponseRecorder() *httptest.ResponseRecorder {
 	return rec
 }
 
-func MultiPartFormRequest(t *testing.T, fieldname string, filename string) *http.Request {
+func MultiPartFormRequest(t *testing.T, fieldname string, filename string, content string) *http.Request {
 	// Create a multipart form file
 	body := new(bytes.Buffer)
 	w := multipart.NewWriter(body)
@@ -41,7 +52,7 @@ func MultiPartFormRequest(t *testing.T, fieldname string, filename string) *http
 		return nil
 	}
 
-	c := bytes.NewBufferString("This is a test file")
+	c := bytes.NewBufferString(content)
 	_, _ = io.Copy(f, c)
 	_ = w.Close() // Close the writer to finalize the multipart form
 
@@ -59,10 +70,9 @@ func Test_FileUploadHandler_WhenFileTooLarge_Expect413(t *testing.T) {
 	req.Header.Set("Content-Type", "multipart/form-data")
 	req.ContentLength = 1000000000   // 1GB
 	req.ParseMultipartForm(10 << 20) // 10MB limit
-	h := NewHandlers(&log, db, ac)
+	h := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
 	h.GetHandler(hKey)(rec, req)
 	assert.Equal(t, rec.Code, 413)
-	os.RemoveAll("uploads") // clean up
 }
 
 // Verifies that when field name is incorrect, the handler returns a 400 status code
@@ -71,11 +81,10 @@ func Test_FileUploadHandler_WhenFieldNameIncorrect_Expect400(t *testing.T) {
 	fn := "test-file" // incorrect field name
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
-	req := MultiPartFormRequest(t, fn, testTxtFile)
-	hand := NewHandlers(&log, db, ac)
+	req := MultiPartFormRequest(t, fn, testTxtFile, testContent)
+	hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
 	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
 	assert.Equal(t, rr.Code, 400)
-	os.RemoveAll("uploads") // clean up
 }
 
 // Verifies that when the file is successfully uploaded and the database returns an error, the handler returns a 500 status code
@@ -84,12 +93,17 @@ func Test_FileUploadHandler_WhenFileUploaded_WhenErrorSavingMetadata_Expect500(t
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
 	fn := "file"
-	req := MultiPartFormRequest(t, fn, testTxtFile)
-	hand := NewHandlers(&log, db, ac)
+	req := MultiPartFormRequest(t, fn, testTxtFile, testContent)
+	st := storage.NewLocal(t.TempDir(), &log)
+	hand := NewHandlers(conf, &log, db, ac, st, stream(), new(mocktasks.Inspector))
 	db.On("InsertFileMetadata", mock.Anything).Return(errors.New("error saving metadata"))
 	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
 	assert.Equal(t, rr.Code, 500)
-	os.RemoveAll("uploads") // clean up
+
+	// The stored file is removed since it has no record
+	objs, err := st.List(context.Background(), uploadBase+"/")
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
 }
 
 // Verifies that the file upload handler correctly enqueues a video metadata task when a video file is uploaded
@@ -98,14 +112,219 @@ func Test_FileUploadHandler_WhenSuccessfulVideoUpload_ExpectVideoMetadataTaskEnq
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
 	fn := "file"
-	req := MultiPartFormRequest(t, fn, testVideoFile)
-	hand := NewHandlers(&log, db, ac)
+	req := MultiPartFormRequest(t, fn, testVideoFile, testVideoContent)
+	hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
 	db.On("InsertFileMetadata", mock.Anything).Return(nil)
-	ac.On("Enqueue", mock.Anything, mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
-		Payload: []byte("test"),
+	db.On("InsertJob", mock.MatchedBy(func(j *models.Job) bool {
+		return j.ID != "" && j.TaskType == tasks.VideoMetadataTaskType && j.State == models.JobQueued
+	})).Return(nil)
+	db.On("UpdateJob", mock.Anything, map[string]interface{}{"queue": "default", "max_retry": 3}).Return(nil)
+	ac.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+		Payload:  []byte("test"),
+		Queue:    "default",
+		MaxRetry: 3,
 	}, nil)
 	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
 
 	assert.Equal(t, rr.Code, 200)
-	os.RemoveAll("uploads") // clean up
+	ac.AssertNumberOfCalls(t, "Enqueue", 1)
+	db.AssertExpectations(t)
+}
+
+// Verifies that every step of the pipeline of an uploaded image is enqueued
+func Test_FileUploadHandler_WhenImageUploaded_ExpectPipelineEnqueued(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	c, err := config.FromJSON([]byte(`{
+		"presets": {"thumbnail": {"width": 256, "height": 256, "mode": "fill"}},
+		"pipelines": {"image": [
+			{"processor": "resize", "preset": "thumbnail"},
+			{"processor": "resize", "options": {"width": 1024, "mode": "scale", "format": "webp"}},
+			{"processor": "video_metadata"}
+		]}
+	}`))
+	assert.NoError(t, err)
+	req := MultiPartFormRequest(t, "file", "photo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	hand := NewHandlers(c, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+	db.On("InsertFileMetadata", mock.Anything).Return(nil)
+	db.On("InsertJob", mock.Anything).Return(nil).Twice()
+	db.On("UpdateJob", mock.Anything, mock.Anything).Return(nil).Twice()
+	resize := func(match func(p tasks.ImageResizePayload) bool) interface{} {
+		return mock.MatchedBy(func(t *asynq.Task) bool {
+			var p tasks.ImageResizePayload
+			return t.Type() == tasks.ImageResizeTaskType && json.Unmarshal(t.Payload(), &p) == nil && match(p)
+		})
+	}
+	ac.On("Enqueue", resize(func(p tasks.ImageResizePayload) bool {
+		return p.Preset == "thumbnail" && p.Width == 256 && p.Mode == "fill"
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&asynq.TaskInfo{}, nil).Once()
+	ac.On("Enqueue", resize(func(p tasks.ImageResizePayload) bool {
+		return p.Preset == "" && p.Width == 1024 && p.Format == "webp"
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&asynq.TaskInfo{}, nil).Once()
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	ac.AssertExpectations(t)
+	ac.AssertNumberOfCalls(t, "Enqueue", 2) // images do not support the video metadata processor
+}
+
+// Verifies that a file larger than the maximum size of its type is rejected with a 413 status code
+func Test_FileUploadHandler_WhenFileExceedsTypeLimit_Expect413(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	c, _ := config.FromJSON([]byte(`{"uploads": {"max_size": 1024, "max_size_by_type": {"video": 8}}}`))
+	req := MultiPartFormRequest(t, "file", testVideoFile, testVideoContent)
+	hand := NewHandlers(c, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 413, rr.Code)
+	db.AssertNotCalled(t, "InsertFileMetadata", mock.Anything)
+}
+
+// Verifies that a body larger than the route's limit is rejected with a 413 status code
+func Test_FileUploadHandler_WhenBodyExceedsRouteLimit_Expect413(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	req := MultiPartFormRequest(t, "file", testTxtFile, testContent)
+	req.Body = http.MaxBytesReader(rr, req.Body, 64)
+	hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 413, rr.Code)
+}
+
+// Verifies that a truncated multipart body is rejected with a 400 status code
+func Test_FileUploadHandler_WhenBodyMalformed_Expect400(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	req := MultiPartFormRequest(t, "file", testTxtFile, testContent)
+	b, _ := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(b[:len(b)-20])) // cut off the closing boundary
+	req.ContentLength = -1
+	hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 400, rr.Code)
+}
+
+// Verifies that a failure writing to the storage returns a 500 status code
+func Test_FileUploadHandler_WhenStorageFails_Expect500(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	st := new(mockstorage.Storage)
+	st.On("Put", mock.Anything, mock.Anything, mock.Anything, int64(-1), "text/plain; charset=utf-8").Return(storage.ObjectInfo{}, errors.New("disk full"))
+	st.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	req := MultiPartFormRequest(t, "file", testTxtFile, testContent)
+	hand := NewHandlers(conf, &log, db, ac, st, stream(), new(mocktasks.Inspector))
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 500, rr.Code)
+}
+
+// Verifies that the size and checksum of a streamed upload are recorded
+func Test_FileUploadHandler_WhenFileUploaded_ExpectSizeAndChecksumRecorded(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	st := storage.NewLocal(t.TempDir(), &log)
+	req := MultiPartFormRequest(t, "file", testTxtFile, testContent)
+	hand := NewHandlers(conf, &log, db, ac, st, stream(), new(mocktasks.Inspector))
+	db.On("InsertFileMetadata", mock.MatchedBy(func(f *models.File) bool {
+		sum := sha256.Sum256([]byte(testContent))
+		return f.Size == 19 && f.Checksum == hex.EncodeToString(sum[:]) && f.Type == "other"
+	})).Return(nil)
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	db.AssertExpectations(t)
+
+	var f models.File
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &f))
+	info, err := st.Stat(context.Background(), f.StoragePath+"/"+f.GeneratedName)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(19), info.Size)
+}
+
+// Verifies that the type of an upload is detected from its content and a mismatching extension is recorded
+func Test_FileUploadHandler_WhenContentMismatchesExtension_ExpectMismatchRecorded(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	req := MultiPartFormRequest(t, "file", testVideoFile, "MZ\x90\x00\x03\x00\x00\x00") // an executable named as a video
+	hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+	db.On("InsertFileMetadata", mock.MatchedBy(func(f *models.File) bool {
+		return f.Type == "other" && f.MimeType == "application/octet-stream" && f.ExtensionMismatch
+	})).Return(nil)
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+	db.AssertExpectations(t)
+	ac.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Verifies that an upload whose content mismatches its extension is rejected with a 415 status code when configured so
+func Test_FileUploadHandler_WhenContentMismatchesExtensionAndRejected_Expect415(t *testing.T) {
+	rr := ResponseRecorder()
+	db := new(mockdb.Database)
+	ac := new(mocktasks.Client)
+	st := storage.NewLocal(t.TempDir(), &log)
+	c, _ := config.FromJSON([]byte(`{"uploads": {"extension_mismatch": "reject"}}`))
+	req := MultiPartFormRequest(t, "file", "photo.jpg", testVideoContent)
+	hand := NewHandlers(c, &log, db, ac, st, stream(), new(mocktasks.Inspector))
+	http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+	assert.Equal(t, 415, rr.Code)
+	db.AssertNotCalled(t, "InsertFileMetadata", mock.Anything)
+
+	objs, err := st.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+}
+
+// Verifies that a webhook sent in a form field before the file is registered for the upload
+// and that the uploaded event is enqueued for it
+func Test_FileUploadHandler_WhenWebhookField_ExpectFileUploadedDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		webhook  string
+		expected int
+	}{
+		{name: "valid webhook", webhook: "https://203.0.113.7/hooks", expected: 200},
+		{name: "invalid webhook", webhook: "ftp://203.0.113.7/hooks", expected: 400},
+		{name: "loopback webhook", webhook: "http://127.0.0.1:6379", expected: 400},
+		{name: "metadata endpoint webhook", webhook: "http://169.254.169.254/latest/meta-data", expected: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := ResponseRecorder()
+			db := new(mockdb.Database)
+			ac := new(mocktasks.Client)
+			body := new(bytes.Buffer)
+			w := multipart.NewWriter(body)
+			w.WriteField("webhook_url", tt.webhook)
+			f, _ := w.CreateFormFile("file", testTxtFile)
+			f.Write([]byte(testContent))
+			w.Close()
+
+			req := httptest.NewRequest("POST", "/upload", body)
+			req.Header.Set("Content-Type", w.FormDataContentType())
+			db.On("InsertFileMetadata", mock.MatchedBy(func(f *models.File) bool {
+				return f.WebhookURL == tt.webhook
+			})).Return(nil)
+			ac.On("Enqueue", mock.MatchedBy(func(t *asynq.Task) bool {
+				var p tasks.WebhookPayload
+				return t.Type() == tasks.WebhookTaskType && json.Unmarshal(t.Payload(), &p) == nil &&
+					p.URL == tt.webhook && p.Event.Type == "file.uploaded"
+			}), mock.Anything, mock.Anything, mock.Anything).Return(&asynq.TaskInfo{}, nil)
+
+			hand := NewHandlers(conf, &log, db, ac, storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+			http.HandlerFunc(hand.GetHandler(hKey)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+			if tt.expected == 200 {
+				ac.AssertNumberOfCalls(t, "Enqueue", 1)
+			} else {
+				ac.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
 }
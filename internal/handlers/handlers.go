@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/storage"
 	"simple-file-processor/internal/tasks"
 
 	"github.com/rs/zerolog"
@@ -11,21 +15,35 @@ import (
 
 type handler struct {
 	Handlers map[string]func(w http.ResponseWriter, r *http.Request)
+	conf     config.Config
 	log      *zerolog.Logger
 	db       db.Database
 	ac       tasks.Client
+	st       storage.Storage
+	es       events.Stream
+	notifier tasks.Notifier
+	in       tasks.Inspector
+	drain    *drain
 }
 
 type Handlers interface {
 	GetHandler(name string) func(w http.ResponseWriter, r *http.Request)
+	CloseStreams()
+	AbandonUploads(ctx context.Context)
 }
 
 // Configures handlers for the server
-func NewHandlers(log *zerolog.Logger, db db.Database, ac tasks.Client) Handlers {
+func NewHandlers(c config.Config, log *zerolog.Logger, db db.Database, ac tasks.Client, st storage.Storage, es events.Stream, in tasks.Inspector) Handlers {
 	h := &handler{
-		log: log,
-		db:  db,
-		ac:  ac,
+		conf:     c,
+		log:      log,
+		db:       db,
+		ac:       ac,
+		st:       st,
+		es:       es,
+		in:       in,
+		drain:    newDrain(),
+		notifier: tasks.NewNotifier(ac, db, es, c.WebhookEndpoints(), c.TaskOptions(tasks.WebhookTaskType), log),
 	}
 
 	// Initialize the handlers map
@@ -36,6 +54,29 @@ func NewHandlers(log *zerolog.Logger, db db.Database, ac tasks.Client) Handlers
 	h.Handlers["HealthCheckHandler"] = http.HandlerFunc(h.HealthCheckHandler)
 	h.Handlers["FileUploadHandler"] = http.HandlerFunc(h.FileUploadHandler)
 	h.Handlers["FileResizeHandler"] = http.HandlerFunc(h.FileResizeHandler)
+	h.Handlers["FilePresetHandler"] = http.HandlerFunc(h.FilePresetHandler)
+	h.Handlers["FileTranscodeHandler"] = http.HandlerFunc(h.FileTranscodeHandler)
+	h.Handlers["FilePosterHandler"] = http.HandlerFunc(h.FilePosterHandler)
+	h.Handlers["FileSpriteHandler"] = http.HandlerFunc(h.FileSpriteHandler)
+	h.Handlers["FileDetailsHandler"] = http.HandlerFunc(h.FileDetailsHandler)
+	h.Handlers["FileBatchHandler"] = http.HandlerFunc(h.FileBatchHandler)
+	h.Handlers["FileJobsHandler"] = http.HandlerFunc(h.FileJobsHandler)
+	h.Handlers["FileStatusHandler"] = http.HandlerFunc(h.FileStatusHandler)
+	h.Handlers["FileEventsHandler"] = http.HandlerFunc(h.FileEventsHandler)
+	h.Handlers["FileDeliveriesHandler"] = http.HandlerFunc(h.FileDeliveriesHandler)
+	h.Handlers["JobHandler"] = http.HandlerFunc(h.JobHandler)
+	h.Handlers["FileListHandler"] = http.HandlerFunc(h.FileListHandler)
+	h.Handlers["FileContentHandler"] = http.HandlerFunc(h.FileContentHandler)
+	h.Handlers["OutputContentHandler"] = http.HandlerFunc(h.OutputContentHandler)
+	h.Handlers["TusOptionsHandler"] = http.HandlerFunc(h.TusOptionsHandler)
+	h.Handlers["TusCreateHandler"] = http.HandlerFunc(h.TusCreateHandler)
+	h.Handlers["TusHeadHandler"] = http.HandlerFunc(h.TusHeadHandler)
+	h.Handlers["TusPatchHandler"] = http.HandlerFunc(h.TusPatchHandler)
+	h.Handlers["TusDeleteHandler"] = http.HandlerFunc(h.TusDeleteHandler)
+	h.Handlers["FailedTasksHandler"] = http.HandlerFunc(h.FailedTasksHandler)
+	h.Handlers["FailedTaskHandler"] = http.HandlerFunc(h.FailedTaskHandler)
+	h.Handlers["ReplayTaskHandler"] = http.HandlerFunc(h.ReplayTaskHandler)
+	h.Handlers["DeleteTaskHandler"] = http.HandlerFunc(h.DeleteTaskHandler)
 	return h
 }
 
@@ -55,3 +96,10 @@ func (h handler) parseRequest(r *http.Request, v interface{}) error {
 	defer r.Body.Close()
 	return decoder.Decode(v)
 }
+
+// writeJSON writes the given value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
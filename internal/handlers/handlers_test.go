@@ -4,21 +4,33 @@ import (
 	"os"
 	"testing"
 
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockevents"
+	"simple-file-processor/internal/mocks/mockstorage"
 	"simple-file-processor/internal/mocks/mocktasks"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var (
-	log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
+	log     = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
+	conf, _ = config.FromJSON([]byte(`{}`))
 )
 
+// stream returns an event stream that accepts every published event
+func stream() *mockevents.Stream {
+	s := new(mockevents.Stream)
+	s.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return s
+}
+
 func TestNewHandlers(t *testing.T) {
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
-	h := NewHandlers(&log, db, ac)
+	h := NewHandlers(conf, &log, db, ac, new(mockstorage.Storage), stream(), new(mocktasks.Inspector))
 	assert.NotNil(t, h)
 }
 
@@ -26,7 +38,7 @@ func TestNewHandlers(t *testing.T) {
 func TestGetHandler(t *testing.T) {
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
-	h := NewHandlers(&log, db, ac)
+	h := NewHandlers(conf, &log, db, ac, new(mockstorage.Storage), stream(), new(mocktasks.Inspector))
 	assert.NotNil(t, h)
 	handler := h.GetHandler("HealthCheckHandler")
 	assert.NotNil(t, handler)
@@ -35,7 +47,7 @@ func TestGetHandler(t *testing.T) {
 func TestGetHandlerNotFound(t *testing.T) {
 	db := new(mockdb.Database)
 	ac := new(mocktasks.Client)
-	h := NewHandlers(&log, db, ac)
+	h := NewHandlers(conf, &log, db, ac, new(mockstorage.Storage), stream(), new(mocktasks.Inspector))
 	assert.NotNil(t, h)
 	handler := h.GetHandler("NotFoundHandler")
 	assert.Nil(t, handler)
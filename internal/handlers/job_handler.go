@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+
+	"github.com/gorilla/mux"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// JobHandler returns the job of an enqueued task along with its state
+func (h handler) JobHandler(w http.ResponseWriter, r *http.Request) {
+	jid := mux.Vars(r)["id"]
+	h.log.Info().Str("job_id", jid).Msg("Job request received")
+	if jid == "" {
+		h.log.Error().Msg("Job ID is required")
+		http.Error(w, `{"error": "Job id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	j, err := h.db.JobByID(jid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, `{"error": "Job not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to get job by ID")
+		http.Error(w, `{"error": "Failed to get job"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j)
+}
+
+// FileJobsHandler returns the jobs of a file from newest to oldest
+func (h handler) FileJobsHandler(w http.ResponseWriter, r *http.Request) {
+	fid := mux.Vars(r)["id"]
+	h.log.Info().Str("file_id", fid).Msg("File jobs request received")
+	if fid == "" {
+		h.log.Error().Msg("File ID is required")
+		http.Error(w, `{"error": "File id is a required path parameter"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, err := h.db.FileByID(fid); err != nil {
+		h.fileLookupError(w, err)
+		return
+	}
+
+	jobs, err := h.db.JobsByFileID(fid)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list jobs of file")
+		http.Error(w, `{"error": "Failed to list jobs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]models.Job{"jobs": jobs})
+}
+
+// enqueue records the job of the task and enqueues the task
+// The job is recorded first so that the worker always finds it to update
+// A task of a unique type whose payload is already queued or running returns the job of that task instead,
+// and is enqueued without the uniqueness lock when the lock outlived the task that took it
+func (h handler) enqueue(fid string, t tasks.Task) (*models.Job, error) {
+	unique := h.conf.TaskOptions(t.Type()).Unique > 0
+	if unique {
+		if j := h.activeJob(fid, t); j != nil {
+			return j, nil
+		}
+	}
+
+	j := &models.Job{
+		ID:       t.ID(),
+		FileID:   fid,
+		TaskType: t.Type(),
+		Payload:  t.Payload(),
+		State:    models.JobQueued,
+	}
+
+	if err := h.db.InsertJob(j); err != nil {
+		h.log.Error().Err(err).Msg("Failed to record job of task " + t.Type())
+		return nil, err
+	}
+
+	ti, err := t.Enqueue()
+	if unique && errors.Is(err, asynq.ErrDuplicateTask) {
+		// An identical task holds the lock, the job of this one is dropped in favour of the job of that task
+		if err := h.db.DeleteJob(j.ID); err != nil {
+			h.log.Error().Err(err).Str("job_id", j.ID).Msg("Failed to delete job of duplicate task")
+		}
+
+		if j := h.activeJob(fid, t); j != nil {
+			return j, nil
+		}
+
+		// The identical task failed or was archived, but its lock has yet to expire
+		h.log.Info().Str("job_id", j.ID).Msg("Uniqueness lock of task " + t.Type() + " outlived its task, enqueuing without it")
+		if err := h.db.InsertJob(j); err != nil {
+			h.log.Error().Err(err).Msg("Failed to record job of task " + t.Type())
+			return nil, err
+		}
+
+		ti, err = t.Unlocked().Enqueue()
+	}
+
+	if err != nil {
+		// The task never reached the queue, so nothing else will update its job
+		h.setJob(j.ID, map[string]interface{}{"state": models.JobFailed, "error": err.Error()})
+		return nil, err
+	}
+
+	if ti != nil {
+		j.Queue, j.MaxRetry = ti.Queue, ti.MaxRetry
+		h.setJob(j.ID, map[string]interface{}{"queue": ti.Queue, "max_retry": ti.MaxRetry})
+	}
+
+	return j, nil
+}
+
+// activeJob returns the job of the identical task that is yet to complete or fail, if any
+func (h handler) activeJob(fid string, t tasks.Task) *models.Job {
+	j, err := h.db.ActiveJob(fid, t.Type(), t.Payload())
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			h.log.Error().Err(err).Msg("Failed to look up active job of task " + t.Type())
+		}
+		return nil
+	}
+
+	h.log.Info().Str("job_id", j.ID).Msg("Task " + t.Type() + " is already enqueued")
+	return j
+}
+
+func (h handler) setJob(id string, fields map[string]interface{}) {
+	if err := h.db.UpdateJob(id, fields); err != nil {
+		h.log.Error().Err(err).Str("job_id", id).Msg("Failed to update job")
+	}
+}
@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestJobHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	var tests = []struct {
+		name           string
+		jobID          string
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+	}{
+		{
+			name:  "job found",
+			jobID: "valid-job-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "valid-job-id").Return(&models.Job{ID: "valid-job-id", State: models.JobCompleted, Attempts: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing job ID",
+			jobID:          "",
+			mockDB:         func(db *mockdb.Database) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:  "job not found",
+			jobID: "not-found-job-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "not-found-job-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "database error",
+			jobID: "valid-job-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("JobByID", "valid-job-id").Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/jobs/"+tt.jobID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.jobID})
+
+			handler := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("JobHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var j models.Job
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &j))
+				assert.Equal(t, tt.jobID, j.ID)
+				assert.Equal(t, models.JobCompleted, j.State)
+			}
+		})
+	}
+}
+
+func TestFileJobsHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	var tests = []struct {
+		name           string
+		fileID         string
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+		expectedJobs   int
+	}{
+		{
+			name:   "jobs found",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id"}, nil)
+				db.On("JobsByFileID", "valid-file-id").Return([]models.Job{{ID: "job-1"}, {ID: "job-2"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedJobs:   2,
+		},
+		{
+			name:   "file not found",
+			fileID: "not-found-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "not-found-file-id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "database error",
+			fileID: "valid-file-id",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "valid-file-id").Return(&models.File{ID: "valid-file-id"}, nil)
+				db.On("JobsByFileID", "valid-file-id").Return(nil, fmt.Errorf("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			tt.mockDB(db)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/file/"+tt.fileID+"/jobs", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.fileID})
+
+			handler := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), new(mockstorage.Storage), stream(), new(mocktasks.Inspector)).GetHandler("FileJobsHandler")
+			handler(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var res struct {
+					Jobs []models.Job `json:"jobs"`
+				}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Len(t, res.Jobs, tt.expectedJobs)
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Resumable uploads follow the tus 1.0 protocol along with its creation and
+// termination extensions, see https://tus.io/protocols/resumable-upload
+// Each PATCH request stores its chunk as a separate part object, so that
+// every storage driver is supported, and the parts are joined together
+// into the uploaded file once the final chunk arrives
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination"
+	tusContentType = "application/offset+octet-stream"
+	tusBase        = "/file/tus"
+	tusPartsDir    = ".parts" // The directory of the upload that holds the received chunks
+)
+
+// TusOptionsHandler advertises the tus protocol version and the supported extensions
+func (h handler) TusOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.conf.UploadSizeCeiling(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusCreateHandler creates a new resumable upload
+// The file is recorded with the uploading status until every byte is received
+func (h handler) TusCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.tusResumable(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, `{"error": "Upload-Length must be a non-negative integer"}`, http.StatusBadRequest)
+		return
+	}
+
+	meta, err := tusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, `{"error": "Invalid Upload-Metadata"}`, http.StatusBadRequest)
+		return
+	}
+
+	filename := meta["filename"]
+	if filename == "" {
+		filename = meta["name"]
+	}
+
+	if filename == "" {
+		http.Error(w, `{"error": "Upload-Metadata must contain the filename"}`, http.StatusBadRequest)
+		return
+	}
+
+	wh, err := webhookURL(r.Context(), r.Header.Get(webhookHeader), meta[webhookField])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	file := newFile(filename)
+	file.WebhookURL = wh
+	if length > h.conf.MaxUploadSize(file.Type) {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file.Status = models.StatusUploading
+	file.UploadLength = length
+	if err := h.db.InsertFileMetadata(file); err != nil {
+		h.log.Error().Err(err).Msg("Failed to insert resumable upload into the database")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// An empty upload is complete as soon as it is created
+	if length == 0 {
+		if err := h.completeUpload(context.WithoutCancel(r.Context()), file); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.log.Info().Str("file_id", file.ID).Int64("upload_length", length).Msg("Resumable upload created")
+	w.Header().Set("Location", tusBase+"/"+file.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// TusHeadHandler returns the offset of a resumable upload so the client knows where to resume
+func (h handler) TusHeadHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.tusResumable(w, r) {
+		return
+	}
+
+	f, ok := h.tusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(f.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(f.UploadLength, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// TusPatchHandler appends a chunk to a resumable upload at the given offset
+// Once the final chunk is received the upload goes through the same
+// processing as a regular upload
+func (h handler) TusPatchHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.tusResumable(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != tusContentType {
+		http.Error(w, `{"error": "Content-Type must be `+tusContentType+`"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, `{"error": "Upload-Offset must be a non-negative integer"}`, http.StatusBadRequest)
+		return
+	}
+
+	f, ok := h.tusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if f.Status != models.StatusUploading || offset != f.UploadOffset {
+		http.Error(w, `{"error": "Upload-Offset does not match the offset of the upload"}`, http.StatusConflict)
+		return
+	}
+
+	// Every byte was received by an earlier request whose join failed, so the join is retried
+	if offset == f.UploadLength {
+		h.finishUpload(w, r, f)
+		return
+	}
+
+	// Store the chunk, a chunk may not go past the announced length
+	// The bytes received before the body breaks off are kept, so that the client resumes after them,
+	// which is why the chunk is stored with an unknown size and past the cancellation of the request
+	ctx := context.WithoutCancel(r.Context())
+	key := path.Join(f.StoragePath, tusPartsDir, fmt.Sprintf("%020d-%s", offset, uuid.New().String()))
+	body := &partialReader{r: r.Body}
+	ur := newUploadReader(body, f.UploadLength-offset)
+	if _, err := h.st.Put(ctx, key, ur, -1, tusContentType); err != nil {
+		status := ur.status()
+		h.log.Error().Err(err).Int("status", status).Msg("Failed to store chunk of resumable upload " + f.ID)
+		h.st.Delete(ctx, key)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	if body.err != nil {
+		h.log.Error().Err(body.err).Int64("received", ur.Size()).Msg("Chunk of resumable upload " + f.ID + " was interrupted")
+		if ur.Size() == 0 {
+			h.st.Delete(ctx, key)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Move the offset forward, a concurrent request for the same offset loses
+	next := offset + ur.Size()
+	if err := h.db.AdvanceUploadOffset(f.ID, offset, next); err != nil {
+		h.st.Delete(ctx, key)
+		if errors.Is(err, db.ErrOffsetConflict) {
+			http.Error(w, `{"error": "Upload-Offset does not match the offset of the upload"}`, http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	f.UploadOffset = next
+	if next == f.UploadLength {
+		h.finishUpload(w, r, f)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(next, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finishUpload completes an upload whose every byte was received and answers the final chunk
+// The join goes on when the client disconnects, and an upload whose join failed keeps its chunks
+// and its offset, so that the client completes it by sending the final chunk again without a body
+func (h handler) finishUpload(w http.ResponseWriter, r *http.Request, f *models.File) {
+	if err := h.completeUpload(context.WithoutCancel(r.Context()), f); err != nil {
+		if errors.Is(err, errExtensionMismatch) {
+			http.Error(w, `{"error": "File content does not match its extension"}`, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if errors.Is(err, errUploadTooLarge) {
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(f.UploadLength, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusDeleteHandler terminates a resumable upload and frees everything stored for it
+// Only uploads that are still in progress can be terminated, so that completed files and their outputs are kept
+func (h handler) TusDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.tusResumable(w, r) {
+		return
+	}
+
+	f, ok := h.tusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if f.Status != models.StatusUploading {
+		h.log.Error().Str("file_id", f.ID).Str("status", f.Status).Msg("Refused to terminate a file that is not an upload in progress")
+		http.Error(w, `{"error": "Upload not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if err := h.removeUpload(r.Context(), f); err != nil {
+		h.log.Error().Err(err).Msg("Failed to terminate resumable upload " + f.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info().Str("file_id", f.ID).Msg("Resumable upload terminated")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeUpload joins the received chunks into the uploaded file, records
+// its size and checksum, and starts the processing of the file
+// The chunks are only deleted once the file is recorded, so a failed join can be retried
+func (h handler) completeUpload(ctx context.Context, f *models.File) error {
+	parts, err := h.st.List(ctx, path.Join(f.StoragePath, tusPartsDir)+"/")
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to list chunks of resumable upload " + f.ID)
+		return err
+	}
+
+	// Part names start with their zero padded offset, so they sort in order
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Key < parts[j].Key })
+	pr := &partsReader{ctx: ctx, st: h.st}
+	for _, p := range parts {
+		pr.keys = append(pr.keys, p.Key)
+	}
+	defer pr.Close()
+
+	// Detect the content type now that the start of the file is known
+	body, mt := sniff(pr)
+	if err := h.setContentType(f, mt); err != nil {
+		h.log.Error().Err(err).Str("mime_type", mt).Msg("Rejected resumable upload " + f.ID)
+		pr.Close()
+		if rerr := h.removeUpload(ctx, f); rerr != nil {
+			h.log.Error().Err(rerr).Msg("Failed to remove rejected resumable upload " + f.ID)
+		}
+
+		return err
+	}
+
+	// The size was checked against the type guessed from the extension, which the content may not be
+	if max := h.conf.MaxUploadSize(f.Type); f.UploadLength > max {
+		h.log.Error().Str("mime_type", f.MimeType).Int64("max_size", max).Msg("Rejected resumable upload " + f.ID + " larger than the maximum size of its type")
+		pr.Close()
+		if rerr := h.removeUpload(ctx, f); rerr != nil {
+			h.log.Error().Err(rerr).Msg("Failed to remove rejected resumable upload " + f.ID)
+		}
+
+		return errUploadTooLarge
+	}
+
+	// A join that is still running on shutdown is removed, and retried from the chunks by the client
+	up := path.Join(f.StoragePath, f.GeneratedName)
+	defer h.drain.upload(up)()
+	ur := newUploadReader(body, f.UploadLength)
+	if _, err := h.st.Put(ctx, up, ur, f.UploadLength, f.MimeType); err != nil {
+		h.log.Error().Err(err).Msg("Failed to join chunks of resumable upload " + f.ID)
+		return err
+	}
+
+	if ur.Size() != f.UploadLength {
+		h.st.Delete(ctx, up)
+		return fmt.Errorf("joined %d of the %d bytes of resumable upload %s", ur.Size(), f.UploadLength, f.ID)
+	}
+
+	f.Checksum = ur.Checksum()
+	f.Size = ur.Size()
+	if err := h.db.UpdateFile(f.ID, map[string]interface{}{
+		"checksum":           f.Checksum,
+		"extension_mismatch": f.ExtensionMismatch,
+		"mime_type":          f.MimeType,
+		"size":               f.Size,
+		"type":               f.Type,
+	}); err != nil {
+		return err
+	}
+
+	// The upload is complete and its file is ready to be processed
+	if err := h.db.TransitionFile(f.ID, models.StatusPending, models.StatusTransition{Reason: "upload completed"}); err != nil {
+		return err
+	}
+
+	f.Status = models.StatusPending
+	h.notifier.Notify(events.NewStatusChanged(f.ID, models.StatusPending, "", "upload completed"), f)
+
+	for _, p := range parts {
+		h.st.Delete(ctx, p.Key)
+	}
+
+	h.log.Info().Str("file_id", f.ID).Msg("Resumable upload completed")
+	afterUpload(h, f)
+	return nil
+}
+
+// removeUpload deletes everything stored for the upload along with its record
+func (h handler) removeUpload(ctx context.Context, f *models.File) error {
+	objs, err := h.st.List(ctx, f.StoragePath+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objs {
+		if err := h.st.Delete(ctx, o.Key); err != nil {
+			return err
+		}
+	}
+
+	return h.db.DeleteFile(f.ID)
+}
+
+// partsReader reads the chunks of an upload one after another
+// Each chunk is opened once it is reached and closed once it is read, so only one is open at a time
+type partsReader struct {
+	ctx  context.Context
+	st   storage.Storage
+	keys []string       // The chunks yet to be opened, in order
+	cur  storage.Object // The chunk being read
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.keys) == 0 {
+				return 0, io.EOF
+			}
+
+			obj, err := p.st.Get(p.ctx, p.keys[0])
+			if err != nil {
+				return 0, err
+			}
+
+			p.cur, p.keys = obj, p.keys[1:]
+		}
+
+		n, err := p.cur.Read(b)
+		if err == io.EOF {
+			p.Close()
+			if n == 0 {
+				continue
+			}
+
+			err = nil
+		}
+
+		return n, err
+	}
+}
+
+// Close closes the chunk being read, if any
+func (p *partsReader) Close() error {
+	if p.cur == nil {
+		return nil
+	}
+
+	err := p.cur.Close()
+	p.cur = nil
+	return err
+}
+
+// partialReader ends a request body that breaks off as if it was complete, keeping the error that broke it
+// Bodies over the size limit of the route are still failed, as their bytes were not asked for
+type partialReader struct {
+	r   io.Reader
+	err error
+}
+
+func (p *partialReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	var mbe *http.MaxBytesError
+	if err != nil && err != io.EOF && !errors.As(err, &mbe) {
+		p.err = err
+		return n, io.EOF
+	}
+
+	return n, err
+}
+
+// tusResumable verifies that the client speaks the supported protocol version
+func (h handler) tusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, `{"error": "Unsupported tus version"}`, http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// tusUpload looks up the upload addressed by the request
+func (h handler) tusUpload(w http.ResponseWriter, r *http.Request) (*models.File, bool) {
+	f, err := h.db.FileByID(mux.Vars(r)["id"])
+	if err != nil {
+		h.fileLookupError(w, err)
+		return nil, false
+	}
+
+	return f, true
+}
+
+// tusMetadata decodes the Upload-Metadata header
+// The header is a comma separated list of keys with base64 encoded values
+func tusMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		k, v, _ := strings.Cut(pair, " ")
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+
+		meta[k] = string(b)
+	}
+
+	return meta, nil
+}
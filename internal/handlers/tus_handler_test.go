@@ -0,0 +1,492 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func tusRequest(method, target string, body io.Reader, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if i := strings.LastIndex(target, "/file/tus/"); i >= 0 {
+		req = mux.SetURLVars(req, map[string]string{"id": target[i+len("/file/tus/"):]})
+	}
+
+	return req
+}
+
+func TestTusOptionsHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{"uploads": {"max_size": 100, "max_size_by_type": {"video": 1000}}}`))
+	h := handlers.NewHandlers(conf, &log, new(mockdb.Database), new(mocktasks.Client), storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+
+	rec := httptest.NewRecorder()
+	h.GetHandler("TusOptionsHandler")(rec, httptest.NewRequest("OPTIONS", "/file/tus", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "1.0.0", rec.Header().Get("Tus-Resumable"))
+	assert.Equal(t, "1.0.0", rec.Header().Get("Tus-Version"))
+	assert.Equal(t, "creation,termination", rec.Header().Get("Tus-Extension"))
+	assert.Equal(t, "1000", rec.Header().Get("Tus-Max-Size"))
+}
+
+func TestTusCreateHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{"uploads": {"max_size": 100}}`))
+	name := "filename " + base64.StdEncoding.EncodeToString([]byte("notes.txt"))
+
+	var tests = []struct {
+		name           string
+		headers        map[string]string
+		noVersion      bool
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+	}{
+		{
+			name:    "upload created",
+			headers: map[string]string{"Upload-Length": "10", "Upload-Metadata": name},
+			mockDB: func(db *mockdb.Database) {
+				db.On("InsertFileMetadata", mock.MatchedBy(func(f *models.File) bool {
+					return f.Status == models.StatusUploading && f.UploadLength == 10 && f.OriginalName == "notes.txt"
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unsupported version",
+			headers:        map[string]string{"Upload-Length": "10", "Upload-Metadata": name},
+			noVersion:      true,
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "missing upload length",
+			headers:        map[string]string{"Upload-Metadata": name},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing filename",
+			headers:        map[string]string{"Upload-Length": "10"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid metadata",
+			headers:        map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename !!"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "upload too large",
+			headers:        map[string]string{"Upload-Length": "101", "Upload-Metadata": name},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "database failure",
+			headers: map[string]string{"Upload-Length": "10", "Upload-Metadata": name},
+			mockDB: func(db *mockdb.Database) {
+				db.On("InsertFileMetadata", mock.Anything).Return(gorm.ErrInvalidDB)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			if tt.mockDB != nil {
+				tt.mockDB(db)
+			}
+
+			rec := httptest.NewRecorder()
+			req := tusRequest("POST", "/file/tus", nil, tt.headers)
+			if tt.noVersion {
+				req.Header.Del("Tus-Resumable")
+			}
+
+			handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector)).GetHandler("TusCreateHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, "1.0.0", rec.Header().Get("Tus-Resumable"))
+			if tt.expectedStatus == http.StatusCreated {
+				assert.True(t, strings.HasPrefix(rec.Header().Get("Location"), "/file/tus/"))
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTusHeadHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	db := new(mockdb.Database)
+	db.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusUploading, UploadLength: 10, UploadOffset: 4}, nil)
+	db.On("FileByID", "missing").Return(nil, gorm.ErrRecordNotFound)
+	h := handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), storage.NewLocal(t.TempDir(), &log), stream(), new(mocktasks.Inspector))
+
+	rec := httptest.NewRecorder()
+	h.GetHandler("TusHeadHandler")(rec, tusRequest("HEAD", "/file/tus/id", nil, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "4", rec.Header().Get("Upload-Offset"))
+	assert.Equal(t, "10", rec.Header().Get("Upload-Length"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	rec = httptest.NewRecorder()
+	h.GetHandler("TusHeadHandler")(rec, tusRequest("HEAD", "/file/tus/missing", nil, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTusPatchHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	octet := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	uploading := func() *models.File {
+		return &models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_notes.txt", MimeType: "text/plain", UploadLength: 10}
+	}
+
+	var tests = []struct {
+		name           string
+		headers        map[string]string
+		body           string
+		mockDB         func(db *mockdb.Database)
+		expectedStatus int
+		expectedOffset string
+	}{
+		{
+			name:    "partial chunk",
+			headers: octet,
+			body:    "01234",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(uploading(), nil)
+				db.On("AdvanceUploadOffset", "id", int64(0), int64(5)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedOffset: "5",
+		},
+		{
+			name:           "wrong content type",
+			headers:        map[string]string{"Content-Type": "text/plain", "Upload-Offset": "0"},
+			body:           "01234",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:    "offset mismatch",
+			headers: map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "3"},
+			body:    "01234",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(uploading(), nil)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:    "concurrent chunk",
+			headers: octet,
+			body:    "01234",
+			mockDB: func(mdb *mockdb.Database) {
+				mdb.On("FileByID", "id").Return(uploading(), nil)
+				mdb.On("AdvanceUploadOffset", "id", int64(0), int64(5)).Return(db.ErrOffsetConflict)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:    "chunk past upload length",
+			headers: octet,
+			body:    "0123456789a",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(uploading(), nil)
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "upload not found",
+			headers: octet,
+			body:    "01234",
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "id").Return(nil, gorm.ErrRecordNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			if tt.mockDB != nil {
+				tt.mockDB(db)
+			}
+
+			st := storage.NewLocal(t.TempDir(), &log)
+			rec := httptest.NewRecorder()
+			req := tusRequest("PATCH", "/file/tus/id", strings.NewReader(tt.body), tt.headers)
+			handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedOffset, rec.Header().Get("Upload-Offset"))
+			db.AssertExpectations(t)
+
+			// Rejected chunks must not be kept
+			if tt.expectedStatus != http.StatusNoContent {
+				parts, err := st.List(context.Background(), "uploads/id/")
+				assert.NoError(t, err)
+				assert.Empty(t, parts)
+			}
+		})
+	}
+}
+
+func TestTusPatchHandler_WhenChunkInterrupted_ExpectReceivedBytesKept(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_notes.txt", UploadLength: 10}, nil)
+	mdb.On("AdvanceUploadOffset", "id", int64(0), int64(3)).Return(nil)
+
+	// The connection drops after the first 3 bytes of the chunk
+	rec := httptest.NewRecorder()
+	body := io.MultiReader(strings.NewReader("012"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	req := tusRequest("PATCH", "/file/tus/id", body, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "0",
+	})
+	handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Upload-Offset"))
+	parts, err := st.List(context.Background(), "uploads/id/.parts/")
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 1) {
+		assert.Equal(t, int64(3), parts[0].Size)
+	}
+	mdb.AssertExpectations(t)
+}
+
+func TestTusPatchHandler_WhenFinalChunk_ExpectFileAssembled(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	f := &models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_notes.txt", MimeType: "text/plain", UploadLength: 10}
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(f, nil)
+	mdb.On("AdvanceUploadOffset", "id", int64(0), int64(6)).Return(nil).Run(func(args mock.Arguments) { f.UploadOffset = 6 })
+	mdb.On("AdvanceUploadOffset", "id", int64(6), int64(10)).Return(nil)
+	mdb.On("UpdateFile", "id", map[string]interface{}{
+		"checksum":           "84d89877f0d4041efb6bf91a16f0248f2fd573e6af05c19f96bedb9f882f7882",
+		"extension_mismatch": false,
+		"mime_type":          "text/plain; charset=utf-8",
+		"size":               int64(10),
+		"type":               "other",
+	}).Return(nil)
+	mdb.On("TransitionFile", "id", models.StatusPending, models.StatusTransition{Reason: "upload completed"}).Return(nil)
+	h := handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector))
+
+	for _, chunk := range []struct{ offset, body string }{{"0", "012345"}, {"6", "6789"}} {
+		rec := httptest.NewRecorder()
+		req := tusRequest("PATCH", "/file/tus/id", bytes.NewBufferString(chunk.body), map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": chunk.offset,
+		})
+		h.GetHandler("TusPatchHandler")(rec, req)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	obj, err := st.Get(context.Background(), "uploads/id/id_notes.txt")
+	assert.NoError(t, err)
+	defer obj.Close()
+	b, _ := io.ReadAll(obj)
+	assert.Equal(t, "0123456789", string(b))
+
+	// Only the assembled file is left behind
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	mdb.AssertExpectations(t)
+}
+
+// The client may disconnect while the final chunk is being joined, which must not abandon the join
+func TestTusPatchHandler_WhenFinalChunkCancelled_ExpectFileAssembled(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	if _, err := st.Put(context.Background(), "uploads/id/.parts/00000000000000000000-a", bytes.NewBufferString("012345"), 6, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_notes.txt", UploadLength: 10, UploadOffset: 6}, nil)
+	mdb.On("AdvanceUploadOffset", "id", int64(6), int64(10)).Return(nil)
+	mdb.On("UpdateFile", "id", mock.Anything).Return(nil)
+	mdb.On("TransitionFile", "id", models.StatusPending, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	req := tusRequest("PATCH", "/file/tus/id", bytes.NewBufferString("6789"), map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "6",
+	})
+	req = mux.SetURLVars(req.WithContext(ctx), map[string]string{"id": "id"})
+	handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	obj, err := st.Get(context.Background(), "uploads/id/id_notes.txt")
+	if assert.NoError(t, err) {
+		defer obj.Close()
+		b, _ := io.ReadAll(obj)
+		assert.Equal(t, "0123456789", string(b))
+	}
+	mdb.AssertExpectations(t)
+}
+
+// An upload whose join failed has every byte received, and is joined again by a final chunk without a body
+func TestTusPatchHandler_WhenJoinRetried_ExpectFileAssembled(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	for key, chunk := range map[string]string{"00000000000000000000-a": "012345", "00000000000000000006-b": "6789"} {
+		if _, err := st.Put(context.Background(), "uploads/id/.parts/"+key, bytes.NewBufferString(chunk), int64(len(chunk)), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_notes.txt", UploadLength: 10, UploadOffset: 10}, nil)
+	mdb.On("UpdateFile", "id", mock.Anything).Return(nil)
+	mdb.On("TransitionFile", "id", models.StatusPending, mock.Anything).Return(nil)
+
+	rec := httptest.NewRecorder()
+	req := tusRequest("PATCH", "/file/tus/id", http.NoBody, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "10",
+	})
+	handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get("Upload-Offset"))
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1, "the chunks are removed once joined") {
+		assert.Equal(t, "uploads/id/id_notes.txt", objs[0].Key)
+	}
+	mdb.AssertExpectations(t)
+}
+
+func TestTusPatchHandler_WhenContentMismatchesExtensionAndRejected_ExpectUploadRemoved(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{"uploads": {"extension_mismatch": "reject"}}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	f := &models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_movie.mp4", UploadedExtension: "mp4", UploadLength: 8}
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(f, nil)
+	mdb.On("AdvanceUploadOffset", "id", int64(0), int64(8)).Return(nil)
+	mdb.On("DeleteFile", "id").Return(nil)
+
+	rec := httptest.NewRecorder()
+	req := tusRequest("PATCH", "/file/tus/id", strings.NewReader("MZ\x90\x00\x03\x00\x00\x00"), map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "0",
+	})
+	handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	mdb.AssertExpectations(t)
+}
+
+func TestTusPatchHandler_WhenContentLargerThanItsTypeAllows_ExpectUploadRemoved(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{"uploads": {"max_size_by_type": {"video": 1000, "image": 10}}}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	f := &models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", GeneratedName: "id_movie.mp4", UploadedExtension: "mp4", MimeType: "video/mp4", Type: "video", UploadLength: 20}
+
+	mdb := new(mockdb.Database)
+	mdb.On("FileByID", "id").Return(f, nil)
+	mdb.On("AdvanceUploadOffset", "id", int64(0), int64(20)).Return(nil)
+	mdb.On("DeleteFile", "id").Return(nil)
+
+	// The upload was created as a video, but holds an image that is larger than images may be
+	rec := httptest.NewRecorder()
+	req := tusRequest("PATCH", "/file/tus/id", strings.NewReader("\x89PNG\r\n\x1a\n"+strings.Repeat("\x00", 12)), map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": "0",
+	})
+	handlers.NewHandlers(conf, &log, mdb, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusPatchHandler")(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	mdb.AssertExpectations(t)
+}
+
+func TestTusDeleteHandler(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	if _, err := st.Put(context.Background(), "uploads/id/.parts/00000000000000000000-a", bytes.NewBufferString("01234"), 5, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	db := new(mockdb.Database)
+	db.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusUploading, StoragePath: "uploads/id", UploadLength: 10}, nil)
+	db.On("DeleteFile", "id").Return(nil)
+
+	rec := httptest.NewRecorder()
+	handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusDeleteHandler")(rec, tusRequest("DELETE", "/file/tus/id", nil, nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	db.AssertExpectations(t)
+}
+
+func TestTusDeleteHandler_WhenUploadCompleted_ExpectFileKept(t *testing.T) {
+	log := zerolog.Nop()
+	conf, _ := config.FromJSON([]byte(`{}`))
+	st := storage.NewLocal(t.TempDir(), &log)
+	for _, key := range []string{"uploads/id/id.jpg", "uploads/id/resized_a.jpg"} {
+		if _, err := st.Put(context.Background(), key, bytes.NewBufferString("01234"), 5, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db := new(mockdb.Database)
+	db.On("FileByID", "id").Return(&models.File{ID: "id", Status: models.StatusCompleted, StoragePath: "uploads/id", UploadLength: 5, UploadOffset: 5}, nil)
+
+	rec := httptest.NewRecorder()
+	handlers.NewHandlers(conf, &log, db, new(mocktasks.Client), st, stream(), new(mocktasks.Inspector)).GetHandler("TusDeleteHandler")(rec, tusRequest("DELETE", "/file/tus/id", nil, nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	objs, err := st.List(context.Background(), "uploads/id/")
+	assert.NoError(t, err)
+	assert.Len(t, objs, 2, "the file and its outputs are kept")
+	db.AssertNotCalled(t, "DeleteFile", mock.Anything)
+	db.AssertExpectations(t)
+}
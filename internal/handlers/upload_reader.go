@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// Returned once an upload grows past the maximum size of its file type
+var errUploadTooLarge = errors.New("upload exceeds the maximum size")
+
+// Returned when the content of an upload does not match its extension and mismatches are rejected
+var errExtensionMismatch = errors.New("upload content does not match its extension")
+
+// uploadReader counts and hashes the bytes of an upload while they are
+// streamed to the storage, and stops the upload once it grows too large
+// The first error of the request body is kept so that a failed write can
+// be attributed to the client rather than the storage
+type uploadReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+	hash  hash.Hash
+	err   error
+}
+
+func newUploadReader(r io.Reader, limit int64) *uploadReader {
+	return &uploadReader{
+		r:     r,
+		limit: limit,
+		hash:  sha256.New(),
+	}
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.n += int64(n)
+	u.hash.Write(p[:n])
+	if u.n > u.limit {
+		u.err = errUploadTooLarge
+		return n, u.err
+	}
+
+	if err != nil && err != io.EOF && u.err == nil {
+		u.err = err
+	}
+
+	return n, err
+}
+
+// Size returns the number of bytes read so far
+func (u *uploadReader) Size() int64 {
+	return u.n
+}
+
+// Checksum returns the hex encoded sha256 of the bytes read so far
+func (u *uploadReader) Checksum() string {
+	return hex.EncodeToString(u.hash.Sum(nil))
+}
+
+// status maps a failed upload to its response status code
+// oversized bodies are rejected with 413, malformed bodies with 400
+// and anything else is a failure of the storage
+func (u *uploadReader) status() int {
+	if u.err == nil {
+		return http.StatusInternalServerError
+	}
+
+	return requestErrorStatus(u.err)
+}
+
+// requestErrorStatus maps an error reading the request body to its response status code
+func requestErrorStatus(err error) int {
+	var mbe *http.MaxBytesError
+	if errors.Is(err, errUploadTooLarge) || errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
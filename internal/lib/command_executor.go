@@ -2,7 +2,10 @@ package lib
 
 import (
 	"bytes"
+	"context"
+	"net/url"
 	"os/exec"
+	"strings"
 
 	"github.com/rs/zerolog"
 )
@@ -13,6 +16,7 @@ type commandExecutor struct {
 
 type CommandExecutor interface {
 	Command(name string, args ...string) ([]byte, error)
+	CommandContext(ctx context.Context, name string, args ...string) ([]byte, error)
 }
 
 // A thin wrapper around exec.Command
@@ -24,18 +28,48 @@ func NewCommandExecutor(l *zerolog.Logger) CommandExecutor {
 
 // Executes a command and returns the output as a buffer of bytes
 func (c *commandExecutor) Command(name string, args ...string) ([]byte, error) {
-	cmd := exec.Command(name, args...)
+	return c.CommandContext(context.Background(), name, args...)
+}
+
+// Executes a command that is killed once the context is done, such as when a task times out
+func (c *commandExecutor) CommandContext(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
 
-	c.log.Info().Msgf("Executing command: %s %s", cmd, args)
+	line := name
+	for _, a := range args {
+		line += " " + redact(a)
+	}
+
+	c.log.Info().Msg("Executing command: " + line)
 	if err := cmd.Run(); err != nil {
-		c.log.Error().Err(err).Msgf("Failed to execute command: %s %s", cmd, args)
+		c.log.Error().Err(err).Msg("Failed to execute command: " + line)
 		return nil, err
 	}
 
-	c.log.Info().Msgf("Command executed successfully: %s %s", cmd, args)
+	c.log.Info().Msg("Command executed successfully: " + line)
 
 	return out.Bytes(), nil
 }
+
+// redact removes the query and credentials of a URL so that it can be logged
+// Presigned URLs carry their signature in the query, which grants access to the object until it expires
+func redact(arg string) string {
+	if !strings.Contains(arg, "://") {
+		return arg
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil {
+		return "[redacted url]"
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = "redacted"
+	}
+
+	u.User = nil
+	return u.String()
+}
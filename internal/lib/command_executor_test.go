@@ -0,0 +1,29 @@
+package lib_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"simple-file-processor/internal/lib"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// Verifies that the signatures of presigned URLs given to commands are left out of the logs
+func TestCommandContext_WhenPresignedURL_ExpectQueryRedacted(t *testing.T) {
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("true is not installed")
+	}
+
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	in := "https://minio:9000/files/uploads/id/video.mp4?X-Amz-Credential=minioadmin&X-Amz-Signature=abcdef"
+	_, err := lib.NewCommandExecutor(&l).CommandContext(context.Background(), "true", "-i", in)
+	assert.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "true -i https://minio:9000/files/uploads/id/video.mp4?redacted")
+	assert.NotContains(t, buf.String(), "X-Amz-Signature")
+	assert.NotContains(t, buf.String(), "minioadmin")
+}
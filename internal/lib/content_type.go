@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// The number of leading bytes of a file that are used to detect its content type
+const SniffLen = 512
+
+// signature identifies a file format by the leading bytes of its content
+type signature struct {
+	mimeType string
+	match    func(b []byte) bool
+}
+
+// Signatures of the formats that the service processes
+// Formats that are not listed fall back to http.DetectContentType, while the
+// extensions expected for each format are kept in the media registry
+var signatures = []signature{
+	{"image/jpeg", prefix("\xFF\xD8\xFF")},
+	{"image/png", prefix("\x89PNG\r\n\x1A\n")},
+	{"image/gif", func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a"))
+	}},
+	{"image/webp", riff("WEBP")},
+	{"video/x-msvideo", riff("AVI ")},
+	{"video/quicktime", quickTime},
+	{"video/mp4", isoBrand("isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "dash", "M4V ", "M4VH", "M4VP", "f4v ")},
+	{"image/heic", isoBrand("heic", "heix", "hevc", "hevx")},
+	{"image/heif", isoBrand("mif1", "msf1", "heim", "heis")},
+	{"image/avif", isoBrand("avif", "avis")},
+	{"audio/mp4", isoBrand("M4A ", "M4B ", "M4P ")},
+	{"video/3gpp", isoBrand("3gp4", "3gp5", "3gp6", "3gp7", "3ge6", "3ge7", "3gg6")},
+	{"video/3gpp2", isoBrand("3g2a", "3g2b", "3g2c")},
+	// Other ISO base media files are not sniffed any further, since the fallback
+	// would take any file with an mp4 compatible brand for an mp4 video
+	{"application/octet-stream", isoMedia},
+	{"video/webm", func(b []byte) bool {
+		return matroska(b) && bytes.Contains(b, []byte("webm"))
+	}},
+	{"video/x-matroska", matroska},
+	{"application/pdf", prefix("%PDF-")},
+}
+
+// DetectContentType returns the mime type of a file given its leading bytes
+// At most SniffLen bytes are considered
+func DetectContentType(b []byte) string {
+	if len(b) > SniffLen {
+		b = b[:SniffLen]
+	}
+
+	for _, s := range signatures {
+		if s.match(b) {
+			return s.mimeType
+		}
+	}
+
+	return http.DetectContentType(b)
+}
+
+func prefix(p string) func([]byte) bool {
+	return func(b []byte) bool {
+		return bytes.HasPrefix(b, []byte(p))
+	}
+}
+
+// riff matches RIFF containers of the given form type e.g. WEBP, AVI
+func riff(form string) func([]byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= 12 && string(b[:4]) == "RIFF" && string(b[8:12]) == form
+	}
+}
+
+// isoMedia matches ISO base media files, which start with an ftyp box
+func isoMedia(b []byte) bool {
+	return len(b) >= 12 && string(b[4:8]) == "ftyp"
+}
+
+// isoBrand matches ISO base media files whose major brand is one of the given brands
+// The major brand follows the ftyp box type, and tells e.g. MP4 videos apart from HEIC images
+func isoBrand(brands ...string) func([]byte) bool {
+	return func(b []byte) bool {
+		return isoMedia(b) && slices.Contains(brands, string(b[8:12]))
+	}
+}
+
+// quickTime matches QuickTime movies, either by the brand of their ftyp box
+// or, for older movies without one, by the type of their first atom
+func quickTime(b []byte) bool {
+	if isoMedia(b) {
+		return string(b[8:12]) == "qt  "
+	}
+
+	if len(b) < 8 {
+		return false
+	}
+
+	switch string(b[4:8]) {
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+
+	return false
+}
+
+// matroska matches Matroska and WebM files, which start with an EBML header
+func matroska(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("\x1A\x45\xDF\xA3"))
+}
@@ -0,0 +1,43 @@
+package lib_test
+
+import (
+	"simple-file-processor/internal/lib"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"jpeg", "\xFF\xD8\xFF\xE0\x00\x10JFIF", "image/jpeg"},
+		{"png", "\x89PNG\r\n\x1A\n\x00\x00\x00\x0DIHDR", "image/png"},
+		{"gif", "GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"avi", "RIFF\x24\x00\x00\x00AVI LIST", "video/x-msvideo"},
+		{"mp4", "\x00\x00\x00\x20ftypisom\x00\x00\x02\x00", "video/mp4"},
+		{"m4v", "\x00\x00\x00\x1CftypM4V \x00\x00\x00\x01isom", "video/mp4"},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic", "image/heic"},
+		{"avif", "\x00\x00\x00\x1Cftypavif\x00\x00\x00\x00avifmif1miaf", "image/avif"},
+		{"m4a", "\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00M4A mp42isom", "audio/mp4"},
+		{"3gp", "\x00\x00\x00\x14ftyp3gp4\x00\x00\x00\x00isom", "video/3gpp"},
+		{"unknown brand with mp4 compatibility", "\x00\x00\x00\x18ftypabcd\x00\x00\x00\x00mp42", "application/octet-stream"},
+		{"mov", "\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00", "video/quicktime"},
+		{"legacy mov", "\x00\x00\x00\x08wide\x00\x00\x00\x00mdat", "video/quicktime"},
+		{"mkv", "\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x88matroska", "video/x-matroska"},
+		{"webm", "\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm", "video/webm"},
+		{"pdf", "%PDF-1.7\n", "application/pdf"},
+		{"executable", "MZ\x90\x00\x03\x00\x00\x00", "application/octet-stream"},
+		{"text", "hello world", "text/plain; charset=utf-8"},
+		{"truncated", "\x00\x00\x00", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, lib.DetectContentType([]byte(tt.content)))
+		})
+	}
+}
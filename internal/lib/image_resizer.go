@@ -1,97 +1,248 @@
 package lib
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
 	"image/jpeg"
-	"os"
-	"path/filepath"
+	"image/png"
+	"io"
+	"path"
 	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"slices"
 
+	"github.com/HugoSmits86/nativewebp"
 	"github.com/google/uuid"
-	"github.com/nfnt/resize"
 	"github.com/rs/zerolog"
+	_ "golang.org/x/image/webp" // register the webp decoder
 )
 
+// Output formats of resized images
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp" // encoded losslessly, so the quality does not apply
+)
+
+// The quality of JPEG images when none is given
+const DefaultQuality = 85
+
+var formats = []string{FormatJPEG, FormatPNG, FormatGIF, FormatWebP}
+
+// ResizeOptions describes how an image is resized
+type ResizeOptions struct {
+	Width      int    // The width of the resized image in pixels, zero to scale by the height
+	Height     int    // The height of the resized image in pixels, zero to scale by the width
+	Mode       string // How the image is fitted into the size e.g. fit, fill, pad, stretch when empty
+	Gravity    string // The part of the image kept when cropping or padding e.g. north, center when empty
+	Background string // The hex colour that padded images are filled with, DefaultBackground when empty
+	Filter     string // The resampling filter e.g. nearest, bilinear, bicubic, lanczos when empty
+	Format     string // The format of the resized image, the format of the source image when empty
+	Quality    int    // The quality of lossy formats from 1 to 100, DefaultQuality when zero
+}
+
+// Validate verifies that the options describe a resize that can be done
+func (o ResizeOptions) Validate() error {
+	if err := o.validateGeometry(); err != nil {
+		return err
+	}
+
+	if o.Filter != "" && !slices.Contains(filters, o.Filter) {
+		return fmt.Errorf("unsupported filter %q, must be one of %v", o.Filter, filters)
+	}
+
+	if o.Format != "" && !slices.Contains(formats, o.Format) {
+		return fmt.Errorf("unsupported format %q, must be one of %v", o.Format, formats)
+	}
+
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+
+	return nil
+}
+
 type imageResizer struct {
+	st  storage.Storage
 	log *zerolog.Logger
 }
 
 type Resizer interface {
-	ResizeImage(sp string, fn string, w, h int) (models.ProcessedOutput, error)
+	ResizeImage(ctx context.Context, sp string, fn string, o ResizeOptions) (models.ProcessedOutput, error)
+	ResizeVariants(ctx context.Context, sp string, fn string, opts []ResizeOptions) ([]models.ProcessedOutput, error)
 }
 
-func NewResizer(l *zerolog.Logger) Resizer {
+// NewResizer constructs an image resizer that reads the source images
+// from and writes the resized images to the given storage
+func NewResizer(st storage.Storage, l *zerolog.Logger) Resizer {
 	return &imageResizer{
+		st:  st,
 		log: l,
 	}
 }
 
-// Resizes the image with the given payload
-func (r *imageResizer) ResizeImage(sp string, fn string, w, h int) (models.ProcessedOutput, error) {
+// Resizes the image with the given options
+func (r *imageResizer) ResizeImage(ctx context.Context, sp string, fn string, o ResizeOptions) (models.ProcessedOutput, error) {
+	pos, err := r.ResizeVariants(ctx, sp, fn, []ResizeOptions{o})
+	if err != nil {
+		return models.ProcessedOutput{}, err
+	}
+
+	return pos[0], nil
+}
+
+// Resizes the image once for each of the options, decoding the image only once
+// Either every variant is stored or, when one of them fails, none of them are
+func (r *imageResizer) ResizeVariants(ctx context.Context, sp string, fn string, opts []ResizeOptions) ([]models.ProcessedOutput, error) {
 	// Validate the input parameters
-	if w <= 0 || h <= 0 || fn == "" {
-		r.log.Error().Msg(fmt.Sprintf("Invalid width or height for image %s at storage path %s", fn, sp))
-		return models.ProcessedOutput{}, fmt.Errorf("invalid width or height")
+	if len(opts) == 0 || fn == "" {
+		r.log.Error().Msg(fmt.Sprintf("No resize options for image %s at storage path %s", fn, sp))
+		return nil, fmt.Errorf("invalid resize options: no variants")
+	}
+
+	for _, o := range opts {
+		if err := o.Validate(); err != nil {
+			r.log.Error().Err(err).Msg(fmt.Sprintf("Invalid resize options for image %s at storage path %s", fn, sp))
+			return nil, fmt.Errorf("invalid resize options: %v", err)
+		}
 	}
 
 	// Open the image file
-	f, err := os.Open(fmt.Sprintf("%s/%s", sp, fn))
+	f, err := r.st.Get(ctx, path.Join(sp, fn))
 	if err != nil {
-		return models.ProcessedOutput{}, err
+		return nil, err
 	}
 	defer f.Close()
 
-	// Decode the image
-	img, err := decode(f)
+	// Check the declared size before decoding, a small file can declare a canvas too large to allocate
+	cfg, _, err := image.DecodeConfig(f)
 	if err != nil {
 		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to open image %s at storage path %s", fn, sp))
-		return models.ProcessedOutput{}, err
+		return nil, err
 	}
 
-	// Resize the image
-	out := resize.Resize(uint(w), uint(h), img, resize.Lanczos3)
+	if int64(cfg.Width)*int64(cfg.Height) > MaxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrSourceTooLarge, cfg.Width, cfg.Height)
+	}
 
-	// Create the output file with a unique ID
-	poid := uuid.New()
-	ofp := fmt.Sprintf("%s/%s", sp, "resized_"+poid.String()+filepath.Ext(f.Name()))
-	of, err := os.Create(ofp)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Decode the image
+	img, sf, err := image.Decode(f)
 	if err != nil {
-		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to create resized image %s at storage path: %s", ofp, sp))
+		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to open image %s at storage path %s", fn, sp))
+		return nil, err
+	}
+
+	pos := make([]models.ProcessedOutput, 0, len(opts))
+	for _, o := range opts {
+		po, err := r.variant(ctx, sp, img, sf, o)
+		if err != nil {
+			// Remove the variants that were already stored
+			for _, p := range pos {
+				r.st.Delete(ctx, path.Join(sp, p.Name))
+			}
+
+			return nil, err
+		}
+
+		pos = append(pos, po)
+	}
+
+	return pos, nil
+}
+
+// variant resizes the decoded image with the given options and stores the result
+func (r *imageResizer) variant(ctx context.Context, sp string, img image.Image, sf string, o ResizeOptions) (models.ProcessedOutput, error) {
+	// Resize the image, unless it would be scaled beyond the maximum dimension
+	if w, h := scaledSize(img.Bounds().Dx(), img.Bounds().Dy(), o); w > MaxDimension || h > MaxDimension {
+		r.log.Error().Msg(fmt.Sprintf("Resized image of %dx%d would exceed the maximum dimension at storage path: %s", w, h, sp))
+		return models.ProcessedOutput{}, fmt.Errorf("%w: %dx%d", ErrTooLarge, w, h)
+	}
+
+	out := transform(img, o)
+
+	// Encode the image in the requested format, keeping the format of the source by default
+	format := o.Format
+	if format == "" {
+		format = sf
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, out, format, o.Quality); err != nil {
+		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to encode resized image at storage path: %s", sp))
 		return models.ProcessedOutput{}, err
 	}
-	defer of.Close()
 
-	// Encode the image to the output file
-	if err := jpeg.Encode(of, out, nil); err != nil {
-		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to encode resized image %s at storage path: %s", ofp, sp))
+	// Store the output file with a unique ID
+	poid := uuid.New()
+	name := "resized_" + poid.String() + "." + extension(format)
+	ofp := path.Join(sp, name)
+	oi, err := r.st.Put(ctx, ofp, &buf, int64(buf.Len()), "image/"+format)
+	if err != nil {
+		r.log.Error().Err(err).Msg(fmt.Sprintf("Failed to store resized image %s at storage path: %s", ofp, sp))
 		return models.ProcessedOutput{}, err
 	}
 
 	// Create the processed output
-	fi, _ := os.Stat(ofp)
 	po := models.ProcessedOutput{
 		ID:          poid,
 		StoragePath: sp,
-		Name:        fi.Name(),
-		Width:       w,
-		Height:      h,
+		Name:        name,
+		Width:       out.Bounds().Dx(),
+		Height:      out.Bounds().Dy(),
 		Type:        models.ResizedImageType,
-		Extension:   filepath.Ext(fi.Name()),
-		Size:        fi.Size(),
+		Extension:   path.Ext(name),
+		Format:      format,
+		Size:        oi.Size,
 	}
 
 	r.log.Info().Msg(fmt.Sprintf("Resized image %s at storage path: %s", ofp, sp))
 	return po, nil
 }
 
-// obtains an image from the storage path
-// and returns the image object
-func decode(f *os.File) (image.Image, error) {
-	img, _, err := image.Decode(f)
-	if err != nil {
-		return nil, err
+// encode writes the image in the given format
+// Images are flattened onto a white background for formats without transparency
+func encode(w io.Writer, img image.Image, format string, quality int) error {
+	if quality == 0 {
+		quality = DefaultQuality
+	}
+
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	case FormatWebP:
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// flatten draws the image onto a white background
+func flatten(img image.Image) image.Image {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
+
+// extension returns the file extension of the given format
+func extension(format string) string {
+	if format == FormatJPEG {
+		return "jpg"
 	}
 
-	return img, nil
+	return format
 }
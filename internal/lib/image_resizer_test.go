@@ -1,10 +1,21 @@
 package lib_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/jpeg"
+	"image/png"
+	"io"
 	"os"
+	"path/filepath"
 	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/storage"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -21,39 +32,78 @@ func TestResizeImage(t *testing.T) {
 
 	// test cases
 	tests := []struct {
-		name      string
-		sp        string
-		fn        string
-		w         int
-		h         int
-		expectErr bool
+		name           string
+		sp             string
+		fn             string
+		opts           lib.ResizeOptions
+		expectErr      bool
+		expectedFormat string
 	}{
 		{
-			name:      "valid resize",
-			fn:        "test.jpg",
-			w:         100,
-			h:         100,
-			expectErr: false,
+			name:           "valid resize",
+			fn:             "test.jpg",
+			opts:           lib.ResizeOptions{Width: 100, Height: 100},
+			expectErr:      false,
+			expectedFormat: "jpeg",
+		},
+		{
+			name:           "source format is kept",
+			fn:             "test.png",
+			opts:           lib.ResizeOptions{Width: 50, Height: 50},
+			expectedFormat: "png",
+		},
+		{
+			name:           "png to jpeg with quality",
+			fn:             "test.png",
+			opts:           lib.ResizeOptions{Width: 50, Height: 50, Format: lib.FormatJPEG, Quality: 40},
+			expectedFormat: "jpeg",
+		},
+		{
+			name:           "jpeg to gif",
+			fn:             "test.jpg",
+			opts:           lib.ResizeOptions{Width: 50, Height: 50, Format: lib.FormatGIF},
+			expectedFormat: "gif",
+		},
+		{
+			name:           "jpeg to webp",
+			fn:             "test.jpg",
+			opts:           lib.ResizeOptions{Width: 50, Height: 50, Format: lib.FormatWebP},
+			expectedFormat: "webp",
 		},
 		{
 			name:      "invalid width",
 			fn:        "test.jpg",
-			w:         -1,
-			h:         100,
+			opts:      lib.ResizeOptions{Width: -1, Height: 100},
 			expectErr: true,
 		},
 		{
 			name:      "invalid height",
 			fn:        "test.jpg",
-			w:         100,
-			h:         -1,
+			opts:      lib.ResizeOptions{Width: 100, Height: -1},
+			expectErr: true,
+		},
+		{
+			name:      "size beyond the maximum",
+			fn:        "test.jpg",
+			opts:      lib.ResizeOptions{Width: 100000, Height: 100000},
+			expectErr: true,
+		},
+		{
+			name:      "unsupported format",
+			fn:        "test.jpg",
+			opts:      lib.ResizeOptions{Width: 100, Height: 100, Format: "bmp"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid quality",
+			fn:        "test.jpg",
+			opts:      lib.ResizeOptions{Width: 100, Height: 100, Quality: 101},
 			expectErr: true,
 		},
 		{
 			name:      "invalid file",
 			fn:        "invalid.jpg",
-			w:         100,
-			h:         100,
+			opts:      lib.ResizeOptions{Width: 100, Height: 100},
 			expectErr: true,
 		},
 	}
@@ -72,29 +122,337 @@ func TestResizeImage(t *testing.T) {
 			}
 
 			// Create a new image resizer
-			resizer := lib.NewResizer(&logger)
+			st := storage.NewLocal(".", &logger)
+			resizer := lib.NewResizer(st, &logger)
 			// Call the ResizeImage method
-			output, err := resizer.ResizeImage(dir, tt.fn, tt.w, tt.h)
+			output, err := resizer.ResizeImage(context.Background(), dir, tt.fn, tt.opts)
 			if (tt.expectErr && err == nil) || (!tt.expectErr && err != nil) {
 				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
 			}
 			if tt.expectErr {
 				assert.Error(t, err)
-			} else {
-				assert.NotNil(t, output)
+				return
 			}
+
+			assert.Equal(t, tt.expectedFormat, output.Format)
+			assert.Equal(t, tt.opts.Width, output.Width)
+
+			// The stored output must be encoded in the recorded format
+			obj, err := st.Get(context.Background(), dir+"/"+output.Filename())
+			assert.NoError(t, err)
+			defer obj.Close()
+			assert.Equal(t, "image/"+tt.expectedFormat, obj.Info().ContentType)
+			cfg, format, err := image.DecodeConfig(obj)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFormat, format)
+			assert.Equal(t, tt.opts.Width, cfg.Width)
 		})
 	}
 }
 
 func createTestImage(dir, filename string, rect image.Rectangle) {
-	img := image.NewRGBA(rect)
+	img := image.NewNRGBA(rect)
+	img.Set(0, 0, color.NRGBA{R: 255, A: 128})
 	f, err := os.Create(dir + "/" + filename)
 	if err != nil {
 		panic(err)
 	}
 	defer f.Close()
-	if err := jpeg.Encode(f, img, nil); err != nil {
+
+	encode := func() error { return jpeg.Encode(f, img, nil) }
+	if filepath.Ext(filename) == ".png" {
+		encode = func() error { return png.Encode(f, img) }
+	}
+
+	if err := encode(); err != nil {
 		panic(err)
 	}
 }
+
+// Verifies that each resize mode produces an image of the expected size
+func TestResizeImageModes(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	tests := []struct {
+		name      string
+		opts      lib.ResizeOptions
+		expectErr bool
+		width     int
+		height    int
+		pixels    map[image.Point]color.NRGBA // Expected colours of some pixels of the output
+	}{
+		{
+			name:   "stretch by default",
+			opts:   lib.ResizeOptions{Width: 30, Height: 70},
+			width:  30,
+			height: 70,
+		},
+		{
+			name:   "fit",
+			opts:   lib.ResizeOptions{Width: 100, Height: 100, Mode: lib.ModeFit},
+			width:  100,
+			height: 50,
+		},
+		{
+			name:   "fill",
+			opts:   lib.ResizeOptions{Width: 100, Height: 100, Mode: lib.ModeFill},
+			width:  100,
+			height: 100,
+		},
+		{
+			name:   "fill with gravity",
+			opts:   lib.ResizeOptions{Width: 50, Height: 50, Mode: lib.ModeFill, Gravity: lib.GravityWest},
+			width:  50,
+			height: 50,
+			pixels: map[image.Point]color.NRGBA{{X: 40, Y: 25}: red},
+		},
+		{
+			name:   "pad",
+			opts:   lib.ResizeOptions{Width: 100, Height: 100, Mode: lib.ModePad, Background: "#00ff00"},
+			width:  100,
+			height: 100,
+			pixels: map[image.Point]color.NRGBA{
+				{X: 50, Y: 5}:  {G: 255, A: 255},
+				{X: 10, Y: 50}: red,
+				{X: 90, Y: 50}: blue,
+				{X: 50, Y: 95}: {G: 255, A: 255},
+			},
+		},
+		{
+			name:   "pad with gravity",
+			opts:   lib.ResizeOptions{Width: 100, Height: 100, Mode: lib.ModePad, Gravity: lib.GravityNorth},
+			width:  100,
+			height: 100,
+			pixels: map[image.Point]color.NRGBA{{X: 10, Y: 5}: red, {X: 50, Y: 95}: {R: 255, G: 255, B: 255, A: 255}},
+		},
+		{
+			name:      "fill scaled beyond the maximum",
+			opts:      lib.ResizeOptions{Width: lib.MaxDimension, Height: lib.MaxDimension, Mode: lib.ModeFill},
+			expectErr: true,
+		},
+		{
+			name:      "scale beyond the maximum",
+			opts:      lib.ResizeOptions{Height: lib.MaxDimension, Mode: lib.ModeScale},
+			expectErr: true,
+		},
+		{
+			name:   "scale by width",
+			opts:   lib.ResizeOptions{Width: 50, Mode: lib.ModeScale},
+			width:  50,
+			height: 25,
+		},
+		{
+			name:   "scale by height",
+			opts:   lib.ResizeOptions{Height: 50, Mode: lib.ModeScale},
+			width:  100,
+			height: 50,
+		},
+		{
+			name:      "scale with width and height",
+			opts:      lib.ResizeOptions{Width: 50, Height: 50, Mode: lib.ModeScale},
+			expectErr: true,
+		},
+		{
+			name:      "fit without height",
+			opts:      lib.ResizeOptions{Width: 50, Mode: lib.ModeFit},
+			expectErr: true,
+		},
+		{
+			name:      "unsupported mode",
+			opts:      lib.ResizeOptions{Width: 50, Height: 50, Mode: "squash"},
+			expectErr: true,
+		},
+		{
+			name:      "unsupported gravity",
+			opts:      lib.ResizeOptions{Width: 50, Height: 50, Mode: lib.ModeFill, Gravity: "up"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid background",
+			opts:      lib.ResizeOptions{Width: 50, Height: 50, Mode: lib.ModePad, Background: "green"},
+			expectErr: true,
+		},
+	}
+
+	// A 200x100 image with a red left half and a blue right half
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(src, image.Rect(0, 0, 100, 100), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(100, 0, 200, 100), image.NewUniform(blue), image.Point{}, draw.Src)
+	st := storage.NewLocal(t.TempDir(), &logger)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Put(context.Background(), "images/test.png", &buf, int64(buf.Len()), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := lib.NewResizer(st, &logger).ResizeImage(context.Background(), "images", "test.png", tt.opts)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.width, output.Width)
+			assert.Equal(t, tt.height, output.Height)
+
+			obj, err := st.Get(context.Background(), "images/"+output.Filename())
+			assert.NoError(t, err)
+			defer obj.Close()
+			img, err := png.Decode(obj)
+			assert.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, tt.width, tt.height), img.Bounds())
+			for p, c := range tt.pixels {
+				assert.Equal(t, c, color.NRGBAModel.Convert(img.At(p.X, p.Y)), "pixel %v", p)
+			}
+		})
+	}
+}
+
+// Verifies that every filter resizes the image, including large reductions
+func TestResizeImageFilters(t *testing.T) {
+	st := storage.NewLocal(t.TempDir(), &logger)
+	putTestJPEG(t, st, "images/test.jpg", 800, 400, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+
+	for _, filter := range []string{"", lib.FilterNearest, lib.FilterBilinear, lib.FilterBicubic, lib.FilterLanczos} {
+		t.Run("filter "+filter, func(t *testing.T) {
+			opts := lib.ResizeOptions{Width: 50, Height: 25, Filter: filter, Format: lib.FormatPNG}
+			output, err := lib.NewResizer(st, &logger).ResizeImage(context.Background(), "images", "test.jpg", opts)
+			assert.NoError(t, err)
+
+			obj, err := st.Get(context.Background(), "images/"+output.Filename())
+			assert.NoError(t, err)
+			defer obj.Close()
+			img, err := png.Decode(obj)
+			assert.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, 50, 25), img.Bounds())
+
+			// The colour survives the resize, give or take the JPEG compression
+			c := color.RGBAModel.Convert(img.At(25, 12)).(color.RGBA)
+			assert.InDelta(t, 200, int(c.R), 8)
+			assert.InDelta(t, 40, int(c.G), 8)
+			assert.InDelta(t, 40, int(c.B), 8)
+		})
+	}
+
+	_, err := lib.NewResizer(st, &logger).ResizeImage(context.Background(), "images", "test.jpg", lib.ResizeOptions{Width: 50, Height: 25, Filter: "box"})
+	assert.Error(t, err)
+}
+
+// Benchmarks creating a thumbnail of a 20 megapixel photo with every filter
+func BenchmarkResizeImage(b *testing.B) {
+	st := storage.NewLocal(b.TempDir(), &logger)
+	putTestJPEG(b, st, "images/photo.jpg", 5472, 3648, color.RGBA{R: 90, G: 120, B: 200, A: 255})
+	resizer := lib.NewResizer(st, &logger)
+
+	for _, filter := range []string{lib.FilterNearest, lib.FilterBilinear, lib.FilterBicubic, lib.FilterLanczos} {
+		b.Run(filter, func(b *testing.B) {
+			opts := lib.ResizeOptions{Width: 320, Height: 213, Filter: filter}
+			for i := 0; i < b.N; i++ {
+				if _, err := resizer.ResizeImage(context.Background(), "images", "photo.jpg", opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// putTestJPEG stores a JPEG image of the given size, filled with the colour and some noise
+func putTestJPEG(tb testing.TB, st storage.Storage, key string, w, h int, c color.RGBA) {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		n := uint8(i / 4 % 3) // a little noise so that the encoder has something to do
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R+n, c.G+n, c.B+n, c.A
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		tb.Fatal(err)
+	}
+
+	if _, err := st.Put(context.Background(), key, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// failingStorage fails every write after the given number of successful writes
+type failingStorage struct {
+	storage.Storage
+	puts int
+}
+
+func (f *failingStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (storage.ObjectInfo, error) {
+	if f.puts == 0 {
+		return storage.ObjectInfo{}, errors.New("disk full")
+	}
+
+	f.puts--
+	return f.Storage.Put(ctx, key, r, size, contentType)
+}
+
+// Verifies that every variant of a batch is resized from the same image
+func TestResizeVariants(t *testing.T) {
+	st := storage.NewLocal(t.TempDir(), &logger)
+	putTestJPEG(t, st, "images/test.jpg", 400, 200, color.RGBA{R: 200, A: 255})
+	variants := []lib.ResizeOptions{
+		{Width: 320, Mode: lib.ModeScale, Format: lib.FormatWebP},
+		{Width: 160, Mode: lib.ModeScale},
+		{Width: 64, Height: 64, Mode: lib.ModeFill, Format: lib.FormatPNG},
+	}
+
+	outputs, err := lib.NewResizer(st, &logger).ResizeVariants(context.Background(), "images", "test.jpg", variants)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, 3)
+	for i, size := range [][2]int{{320, 160}, {160, 80}, {64, 64}} {
+		assert.Equal(t, size[0], outputs[i].Width)
+		assert.Equal(t, size[1], outputs[i].Height)
+		_, err := st.Stat(context.Background(), "images/"+outputs[i].Filename())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"webp", "jpeg", "png"}, []string{outputs[0].Format, outputs[1].Format, outputs[2].Format})
+
+	t.Run("invalid variant", func(t *testing.T) {
+		_, err := lib.NewResizer(st, &logger).ResizeVariants(context.Background(), "images", "test.jpg", append(variants, lib.ResizeOptions{Width: -1}))
+		assert.Error(t, err)
+	})
+
+	t.Run("no variants", func(t *testing.T) {
+		_, err := lib.NewResizer(st, &logger).ResizeVariants(context.Background(), "images", "test.jpg", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("stored variants removed on failure", func(t *testing.T) {
+		st := storage.NewLocal(t.TempDir(), &logger)
+		putTestJPEG(t, st, "images/test.jpg", 400, 200, color.RGBA{R: 200, A: 255})
+
+		_, err := lib.NewResizer(&failingStorage{Storage: st, puts: 2}, &logger).ResizeVariants(context.Background(), "images", "test.jpg", variants)
+		assert.Error(t, err)
+		objs, err := st.List(context.Background(), "images/")
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1) // only the source image is left
+	})
+
+	t.Run("source too large", func(t *testing.T) {
+		st := storage.NewLocal(t.TempDir(), &logger)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+			t.Fatal(err)
+		}
+
+		// Declare a 50000x50000 canvas in the IHDR chunk and fix up its checksum
+		b := buf.Bytes()
+		binary.BigEndian.PutUint32(b[16:], 50000)
+		binary.BigEndian.PutUint32(b[20:], 50000)
+		binary.BigEndian.PutUint32(b[29:], crc32.ChecksumIEEE(b[12:29]))
+		if _, err := st.Put(context.Background(), "images/bomb.png", &buf, int64(buf.Len()), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := lib.NewResizer(st, &logger).ResizeVariants(context.Background(), "images", "bomb.png", variants)
+		assert.ErrorIs(t, err, lib.ErrSourceTooLarge)
+	})
+}
@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// Resampling filters, from the fastest to the sharpest
+const (
+	FilterNearest  = "nearest"
+	FilterBilinear = "bilinear"
+	FilterBicubic  = "bicubic"
+	FilterLanczos  = "lanczos" // The default filter
+)
+
+var filters = []string{FilterNearest, FilterBilinear, FilterBicubic, FilterLanczos}
+
+// lanczos3 is the Lanczos kernel with a support of 3, which x/image/draw does not provide
+var lanczos3 = &draw.Kernel{Support: 3, At: func(t float64) float64 {
+	t = math.Abs(t)
+	if t == 0 {
+		return 1
+	}
+
+	if t >= 3 {
+		return 0
+	}
+
+	x := math.Pi * t
+	return 3 * math.Sin(x) * math.Sin(x/3) / (x * x)
+}}
+
+// interpolator returns the interpolator of the given filter
+func interpolator(filter string) draw.Interpolator {
+	switch filter {
+	case FilterNearest:
+		return draw.NearestNeighbor
+	case FilterBilinear:
+		return draw.BiLinear
+	case FilterBicubic:
+		return draw.CatmullRom
+	default:
+		return lanczos3
+	}
+}
+
+// scale resizes the image to w x h with the given filter
+// The cost of a filter grows with the reduction, so large reductions first
+// halve the image with a box filter until it is within twice the target
+// size, which leaves the filter only the final step
+func scale(img image.Image, w, h int, filter string) *image.RGBA {
+	if filter != FilterNearest {
+		img = shrink(img, w, h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	interpolator(filter).Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// shrink halves the image while it stays at least twice as large as w x h
+func shrink(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	if b.Dx() < 4*w || b.Dy() < 4*h {
+		return img
+	}
+
+	// JPEG images are halved straight from their planes to save converting every pixel
+	var m *image.RGBA
+	if y, ok := img.(*image.YCbCr); ok {
+		m = halveYCbCr(y)
+	} else {
+		m = halveRGBA(toRGBA(img))
+	}
+
+	for m.Bounds().Dx() >= 4*w && m.Bounds().Dy() >= 4*h {
+		m = halveRGBA(m)
+	}
+
+	return m
+}
+
+// halveRGBA averages every 2x2 block of pixels into one
+// The pixels are premultiplied by their alpha, so transparency is averaged correctly
+func halveRGBA(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		r0 := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+2*y):]
+		r1 := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+2*y+1):]
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			i := 8 * x
+			for c := 0; c < 4; c++ {
+				sum := uint32(r0[i+c]) + uint32(r0[i+4+c]) + uint32(r1[i+c]) + uint32(r1[i+4+c])
+				out[4*x+c] = uint8((sum + 2) >> 2)
+			}
+		}
+	}
+
+	return dst
+}
+
+// halveYCbCr averages every 2x2 block of pixels into one RGBA pixel
+func halveYCbCr(src *image.YCbCr) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		sy := b.Min.Y + 2*y
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			sx := b.Min.X + 2*x
+			yi := src.YOffset(sx, sy)
+			lum := uint32(src.Y[yi]) + uint32(src.Y[yi+1]) + uint32(src.Y[yi+src.YStride]) + uint32(src.Y[yi+src.YStride+1])
+
+			// Chroma is subsampled for most JPEG images, in which case the offsets repeat
+			c00, c01, c10, c11 := src.COffset(sx, sy), src.COffset(sx+1, sy), src.COffset(sx, sy+1), src.COffset(sx+1, sy+1)
+			cb := uint32(src.Cb[c00]) + uint32(src.Cb[c01]) + uint32(src.Cb[c10]) + uint32(src.Cb[c11])
+			cr := uint32(src.Cr[c00]) + uint32(src.Cr[c01]) + uint32(src.Cr[c10]) + uint32(src.Cr[c11])
+
+			r, g, bl := color.YCbCrToRGB(uint8((lum+2)>>2), uint8((cb+2)>>2), uint8((cr+2)>>2))
+			out[4*x], out[4*x+1], out[4*x+2], out[4*x+3] = r, g, bl, 0xff
+		}
+	}
+
+	return dst
+}
+
+// toRGBA converts the image to RGBA unless it already is
+func toRGBA(img image.Image) *image.RGBA {
+	if m, ok := img.(*image.RGBA); ok {
+		return m
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(m, m.Bounds(), img, img.Bounds().Min, draw.Src)
+	return m
+}
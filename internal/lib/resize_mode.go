@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Resize modes, which decide how the image is fitted into the requested size
+const (
+	ModeStretch = "stretch" // Resizes to exactly the requested size, ignoring the aspect ratio
+	ModeFit     = "fit"     // Keeps the aspect ratio, resizing the image to fit inside the requested size
+	ModeFill    = "fill"    // Keeps the aspect ratio, resizing the image to cover the requested size and cropping the rest
+	ModePad     = "pad"     // Keeps the aspect ratio, fitting the image and filling the rest with the background colour
+	ModeScale   = "scale"   // Keeps the aspect ratio, resizing to the requested width or height only
+)
+
+// Gravities, which decide which part of the image is kept when it is cropped or padded
+const (
+	GravityCenter    = "center"
+	GravityNorth     = "north"
+	GravitySouth     = "south"
+	GravityEast      = "east"
+	GravityWest      = "west"
+	GravityNorthEast = "northeast"
+	GravityNorthWest = "northwest"
+	GravitySouthEast = "southeast"
+	GravitySouthWest = "southwest"
+)
+
+// The background colour of padded images when none is given
+const DefaultBackground = "#ffffff"
+
+// The largest width or height in pixels that an image is resized to
+// This bounds the canvas of a resize to 256MB, however large the requested or scaled size is
+const MaxDimension = 8192
+
+// ErrTooLarge is returned when the source image would be scaled beyond MaxDimension to fit the requested size
+var ErrTooLarge = errors.New("resized image would exceed the maximum dimension")
+
+// The largest number of pixels of a source image that is decoded
+// This bounds the decoded source to 400MB, whatever the size of the file
+const MaxSourcePixels = 100_000_000
+
+// ErrSourceTooLarge is returned when the source image declares more than MaxSourcePixels
+var ErrSourceTooLarge = errors.New("source image exceeds the maximum number of pixels")
+
+var (
+	modes     = []string{ModeStretch, ModeFit, ModeFill, ModePad, ModeScale}
+	gravities = []string{
+		GravityCenter, GravityNorth, GravitySouth, GravityEast, GravityWest,
+		GravityNorthEast, GravityNorthWest, GravitySouthEast, GravitySouthWest,
+	}
+)
+
+// validateGeometry verifies the size, mode, gravity and background of the options
+func (o ResizeOptions) validateGeometry() error {
+	if o.Mode != "" && !slices.Contains(modes, o.Mode) {
+		return fmt.Errorf("unsupported mode %q, must be one of %v", o.Mode, modes)
+	}
+
+	if o.Mode == ModeScale {
+		if o.Width < 0 || o.Height < 0 || (o.Width > 0) == (o.Height > 0) {
+			return fmt.Errorf("the scale mode takes either a width or a height greater than zero")
+		}
+	} else if o.Width <= 0 || o.Height <= 0 {
+		return fmt.Errorf("width and height must be greater than zero")
+	}
+
+	if o.Width > MaxDimension || o.Height > MaxDimension {
+		return fmt.Errorf("width and height must be at most %d", MaxDimension)
+	}
+
+	if o.Gravity != "" && !slices.Contains(gravities, o.Gravity) {
+		return fmt.Errorf("unsupported gravity %q, must be one of %v", o.Gravity, gravities)
+	}
+
+	if o.Background != "" {
+		if _, err := parseColor(o.Background); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transform resizes the image according to the mode of the options
+func transform(img image.Image, o ResizeOptions) image.Image {
+	w, h := scaledSize(img.Bounds().Dx(), img.Bounds().Dy(), o)
+	out := scale(img, w, h, o.Filter)
+	switch o.Mode {
+	case ModeFill:
+		return crop(out, anchor(out.Bounds(), o.Width, o.Height, o.Gravity))
+	case ModePad:
+		bg, _ := parseColor(o.Background)
+		canvas := image.NewNRGBA(image.Rect(0, 0, o.Width, o.Height))
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+		r := anchor(canvas.Bounds(), w, h, o.Gravity)
+		draw.Draw(canvas, r, out, out.Bounds().Min, draw.Over)
+		return canvas
+	default:
+		return out
+	}
+}
+
+// scaledSize returns the size that a sw x sh image is scaled to before it is cropped or padded
+// Filling or scaling an image with an extreme aspect ratio may exceed the requested size by far
+func scaledSize(sw, sh int, o ResizeOptions) (int, int) {
+	switch o.Mode {
+	case ModeFit, ModePad:
+		return fitSize(sw, sh, o.Width, o.Height)
+	case ModeFill:
+		return coverSize(sw, sh, o.Width, o.Height)
+	case ModeScale:
+		return scaleSize(sw, sh, o.Width, o.Height)
+	default:
+		return o.Width, o.Height
+	}
+}
+
+// scaleSize returns the size with the aspect ratio of the source given either its width or height
+func scaleSize(sw, sh, w, h int) (int, int) {
+	if w > 0 {
+		return w, max(1, int(math.Round(float64(sh)*float64(w)/float64(sw))))
+	}
+
+	return max(1, int(math.Round(float64(sw)*float64(h)/float64(sh)))), h
+}
+
+// fitSize returns the largest size with the aspect ratio of the source that fits inside w x h
+func fitSize(sw, sh, w, h int) (int, int) {
+	scale := math.Min(float64(w)/float64(sw), float64(h)/float64(sh))
+	return max(1, int(math.Round(float64(sw)*scale))), max(1, int(math.Round(float64(sh)*scale)))
+}
+
+// coverSize returns the smallest size with the aspect ratio of the source that covers w x h
+func coverSize(sw, sh, w, h int) (int, int) {
+	scale := math.Max(float64(w)/float64(sw), float64(h)/float64(sh))
+	return max(w, int(math.Round(float64(sw)*scale))), max(h, int(math.Round(float64(sh)*scale)))
+}
+
+// anchor places a w x h rectangle inside the bounds according to the gravity
+func anchor(b image.Rectangle, w, h int, gravity string) image.Rectangle {
+	x := b.Min.X + (b.Dx()-w)/2
+	y := b.Min.Y + (b.Dy()-h)/2
+	if strings.Contains(gravity, "west") {
+		x = b.Min.X
+	} else if strings.Contains(gravity, "east") {
+		x = b.Max.X - w
+	}
+
+	if strings.HasPrefix(gravity, "north") {
+		y = b.Min.Y
+	} else if strings.HasPrefix(gravity, "south") {
+		y = b.Max.Y - h
+	}
+
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// crop copies the given rectangle of the image into a new image
+func crop(img image.Image, r image.Rectangle) image.Image {
+	out := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	return out
+}
+
+// parseColor parses a hex colour such as #fff, #ffffff or #ffffff80
+func parseColor(s string) (color.NRGBA, error) {
+	if s == "" {
+		s = DefaultBackground
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid background colour %q, must be a hex colour such as #ffffff", s)
+	}
+
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
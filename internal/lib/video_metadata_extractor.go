@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -16,7 +17,7 @@ type videoMetadataExtractor struct {
 
 // Extractor interface defines the methods that the metadata extractor should implement
 type MetadataExtractor interface {
-	ExtractVideoMetadata(path string) (*VideoMetadata, error)
+	ExtractVideoMetadata(ctx context.Context, path string) (*VideoMetadata, error)
 }
 
 // NewMetadataExtractor constructs a new metadata extractor
@@ -60,10 +61,12 @@ type ffprobeOutput struct {
 }
 
 // ExtractMetadata extracts metadata from the file
-func (e *videoMetadataExtractor) ExtractVideoMetadata(path string) (*VideoMetadata, error) {
+// ffprobe is killed once the context is done, as it may be reading the file over the network
+func (e *videoMetadataExtractor) ExtractVideoMetadata(ctx context.Context, path string) (*VideoMetadata, error) {
 	// Shell out to ffprobe to get the metadata
 	// ffprobe -v error -print_format json -show_format -show_streams <file>
-	out, err := e.exec.Command(
+	out, err := e.exec.CommandContext(
+		ctx,
 		"ffprobe",
 		"-v", "error",
 		"-print_format", "json",
@@ -72,13 +75,13 @@ func (e *videoMetadataExtractor) ExtractVideoMetadata(path string) (*VideoMetada
 		path,
 	)
 	if err != nil {
-		e.log.Error().Err(err).Msg("Failed to execute ffprobe for file " + path)
+		e.log.Error().Err(err).Msg("Failed to execute ffprobe for file " + redact(path))
 		return nil, err
 	}
 
 	var po ffprobeOutput
 	if err := json.Unmarshal(out, &po); err != nil {
-		e.log.Error().Err(err).Msg("Failed to unmarshal ffprobe output for file " + path)
+		e.log.Error().Err(err).Msg("Failed to unmarshal ffprobe output for file " + redact(path))
 		return nil, err
 	}
 
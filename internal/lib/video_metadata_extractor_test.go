@@ -1,6 +1,7 @@
 package lib_test
 
 import (
+	"context"
 	"errors"
 	"simple-file-processor/internal/lib"
 	"simple-file-processor/internal/mocks/mocklib"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var (
@@ -25,7 +27,7 @@ func TestVideoMetadataExtractor_ExtractVideoMetadata(t *testing.T) {
 			name: "Valid video file",
 			path: "tmp/test.mp4",
 			mockCommand: func(m *mocklib.CommandExecutor) {
-				m.On("Command", "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/test.mp4").Return(
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/test.mp4").Return(
 					[]byte(`
 						{
 							"format": {
@@ -49,7 +51,7 @@ func TestVideoMetadataExtractor_ExtractVideoMetadata(t *testing.T) {
 			name: "Invalid video file",
 			path: "tmp/invalid.mp4",
 			mockCommand: func(m *mocklib.CommandExecutor) {
-				m.On("Command", "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/invalid.mp4").Return(
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/invalid.mp4").Return(
 					[]byte(``), errors.New("ffprobe error"))
 			},
 			wantErr: true,
@@ -58,7 +60,7 @@ func TestVideoMetadataExtractor_ExtractVideoMetadata(t *testing.T) {
 			name: "json parsing error",
 			path: "tmp/test.mp4",
 			mockCommand: func(m *mocklib.CommandExecutor) {
-				m.On("Command", "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/test.mp4").Return(
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "tmp/test.mp4").Return(
 					[]byte(``), nil)
 			},
 			wantErr: true,
@@ -77,7 +79,7 @@ func TestVideoMetadataExtractor_ExtractVideoMetadata(t *testing.T) {
 			ext := lib.NewMetadataExtractor(ce, &log)
 
 			// call the function
-			got, err := ext.ExtractVideoMetadata(tt.path)
+			got, err := ext.ExtractVideoMetadata(context.Background(), tt.path)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExtractVideoMetadata() error = %v, wantErr %v", err, tt.wantErr)
@@ -0,0 +1,356 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Defaults of the poster and sprite sheet of a video
+const (
+	DefaultPosterPercent = 10  // The percentage of the duration that the poster is taken at
+	DefaultSpriteFrames  = 20  // The number of frames of a sprite sheet
+	DefaultSpriteWidth   = 160 // The width of each frame of a sprite sheet in pixels
+	MaxSpriteFrames      = 400 // The most frames a sprite sheet may hold
+	MaxSpriteWidth       = 640 // The widest frame of a sprite sheet in pixels
+)
+
+// ErrBeyondEnd is returned when a frame is asked for past the end of the video
+var ErrBeyondEnd = errors.New("timestamp is beyond the end of the video")
+
+func init() {
+	// WebVTT is missing from the built in mime types of some systems
+	mime.AddExtensionType(".vtt", "text/vtt")
+}
+
+// PosterOptions describes the frame of a video that is taken as its poster
+type PosterOptions struct {
+	Timestamp float64 // The second of the video the poster is taken at, unless a percentage is given
+	Percent   float64 // The percentage of the duration the poster is taken at, from 0 up to 100
+	Width     int     // The width of the poster in pixels, the width of the video when zero
+	Duration  float64 // The duration of the video in seconds, probed with ffprobe when zero
+}
+
+// Validate verifies that the options describe a poster that can be taken
+func (o PosterOptions) Validate() error {
+	if o.Timestamp < 0 {
+		return fmt.Errorf("timestamp must not be negative")
+	}
+
+	if o.Percent < 0 || o.Percent >= 100 {
+		return fmt.Errorf("percent must be from 0 up to 100")
+	}
+
+	if o.Timestamp > 0 && o.Percent > 0 {
+		return fmt.Errorf("a poster is taken at either a timestamp or a percentage")
+	}
+
+	if o.Width < 0 || o.Duration < 0 {
+		return fmt.Errorf("width and duration must not be negative")
+	}
+
+	if o.Width > MaxDimension {
+		return fmt.Errorf("width must be at most %d", MaxDimension)
+	}
+
+	return nil
+}
+
+// SpriteOptions describes the sprite sheet of evenly spaced frames of a video
+type SpriteOptions struct {
+	Frames   int     // The number of frames, DefaultSpriteFrames when zero
+	Columns  int     // The number of frames in a row of the sheet, the square root of the frames when zero
+	Width    int     // The width of each frame in pixels, DefaultSpriteWidth when zero
+	Duration float64 // The duration of the video in seconds, probed with ffprobe when zero
+	// The URL that the cues of the WebVTT index refer to the sheet by, with {id} replaced by the
+	// id of the sheet e.g. /file/123/outputs/{id}/content, the name of the sheet when empty
+	ImageURL string
+}
+
+// Validate verifies that the options describe a sprite sheet that can be made
+func (o SpriteOptions) Validate() error {
+	if o.Frames < 0 || o.Frames > MaxSpriteFrames {
+		return fmt.Errorf("frames must be from 1 to %d", MaxSpriteFrames)
+	}
+
+	if o.Columns < 0 || o.Width < 0 || o.Duration < 0 {
+		return fmt.Errorf("columns, width and duration must not be negative")
+	}
+
+	if o.Width > MaxSpriteWidth {
+		return fmt.Errorf("width must be at most %d", MaxSpriteWidth)
+	}
+
+	// The height of the frames is only known once they are scaled, so the rows are bounded as if they were square
+	d := o.withDefaults()
+	if w, h := d.Columns*d.Width, d.rows()*d.Width; w > MaxDimension || h > MaxDimension {
+		return fmt.Errorf("a sheet of %d columns and %d rows of %d pixel frames exceeds %d pixels", d.Columns, d.rows(), d.Width, MaxDimension)
+	}
+
+	return nil
+}
+
+// rows returns the number of rows of the sheet
+func (o SpriteOptions) rows() int {
+	return (o.Frames + o.Columns - 1) / o.Columns
+}
+
+// withDefaults returns the options with the defaults of the options that are not given
+func (o SpriteOptions) withDefaults() SpriteOptions {
+	if o.Frames == 0 {
+		o.Frames = DefaultSpriteFrames
+	}
+
+	if o.Columns == 0 {
+		o.Columns = int(math.Ceil(math.Sqrt(float64(o.Frames))))
+	}
+
+	o.Columns = min(o.Columns, o.Frames)
+	if o.Width == 0 {
+		o.Width = DefaultSpriteWidth
+	}
+
+	return o
+}
+
+type videoThumbnailer struct {
+	exec CommandExecutor
+	ext  MetadataExtractor
+	st   storage.Storage
+	log  *zerolog.Logger
+}
+
+type Thumbnailer interface {
+	Poster(ctx context.Context, sp string, fn string, o PosterOptions) (models.ProcessedOutput, error)
+	Sprite(ctx context.Context, sp string, fn string, o SpriteOptions) ([]models.ProcessedOutput, error)
+}
+
+// NewThumbnailer constructs a thumbnailer that takes frames of videos with ffmpeg
+// The videos are read from and the images are written to the given storage
+func NewThumbnailer(exec CommandExecutor, st storage.Storage, l *zerolog.Logger) Thumbnailer {
+	return &videoThumbnailer{
+		exec: exec,
+		ext:  NewMetadataExtractor(exec, l),
+		st:   st,
+		log:  l,
+	}
+}
+
+// Takes a single frame of the video as its poster
+func (t *videoThumbnailer) Poster(ctx context.Context, sp string, fn string, o PosterOptions) (models.ProcessedOutput, error) {
+	if err := o.Validate(); err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Invalid poster options for video %s at storage path %s", fn, sp))
+		return models.ProcessedOutput{}, fmt.Errorf("invalid poster options: %v", err)
+	}
+
+	in, err := t.st.PresignGet(ctx, path.Join(sp, fn), presignExpiry)
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to locate video %s at storage path %s", fn, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	// The duration is only needed to place a poster by percentage or to check the timestamp
+	at := o.Timestamp
+	if o.Percent > 0 || o.Duration > 0 || at > 0 {
+		d, err := t.duration(ctx, in, o.Duration)
+		if err != nil {
+			return models.ProcessedOutput{}, err
+		}
+
+		if o.Percent > 0 {
+			at = d * o.Percent / 100
+		}
+
+		if at >= d {
+			return models.ProcessedOutput{}, fmt.Errorf("%w: %s of %s seconds", ErrBeyondEnd, seconds(at), seconds(d))
+		}
+	}
+
+	filters := []string{}
+	if o.Width > 0 {
+		filters = append(filters, fmt.Sprintf("scale=%d:-2", o.Width))
+	}
+
+	// Seeking before the input jumps to the frame without decoding the video up to it
+	args := []string{"-v", "error", "-y", "-ss", seconds(at), "-i", in, "-frames:v", "1"}
+	return t.image(ctx, sp, "poster_", models.VideoPosterType, args, filters)
+}
+
+// Tiles evenly spaced frames of the video into a sheet, along with a WebVTT index of where each frame is
+// Both the sheet and the index are stored, or neither of them when one fails
+func (t *videoThumbnailer) Sprite(ctx context.Context, sp string, fn string, o SpriteOptions) ([]models.ProcessedOutput, error) {
+	if err := o.Validate(); err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Invalid sprite options for video %s at storage path %s", fn, sp))
+		return nil, fmt.Errorf("invalid sprite options: %v", err)
+	}
+
+	o = o.withDefaults()
+	in, err := t.st.PresignGet(ctx, path.Join(sp, fn), presignExpiry)
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to locate video %s at storage path %s", fn, sp))
+		return nil, err
+	}
+
+	d, err := t.duration(ctx, in, o.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := o.rows()
+	filters := []string{
+		fmt.Sprintf("fps=%d/%s", o.Frames, seconds(d)),
+		fmt.Sprintf("scale=%d:-2", o.Width),
+		fmt.Sprintf("tile=%dx%d", o.Columns, rows),
+	}
+
+	args := []string{"-v", "error", "-y", "-i", in, "-frames:v", "1"}
+	sheet, err := t.image(ctx, sp, "sprite_", models.VideoSpriteType, args, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.index(ctx, sp, sheet, o, rows, d)
+	if err != nil {
+		t.st.Delete(ctx, path.Join(sp, sheet.Name))
+		return nil, err
+	}
+
+	return []models.ProcessedOutput{sheet, index}, nil
+}
+
+// image runs ffmpeg to write a single JPEG image and stores it
+func (t *videoThumbnailer) image(ctx context.Context, sp string, prefix string, outputType string, args []string, filters []string) (models.ProcessedOutput, error) {
+	dir, err := os.MkdirTemp("", "thumbnail-")
+	if err != nil {
+		return models.ProcessedOutput{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	poid := uuid.New()
+	name := prefix + poid.String() + ".jpg"
+	out := filepath.Join(dir, name)
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+
+	args = append(args, "-q:v", "3", "-f", "image2", out)
+	if _, err := t.exec.CommandContext(ctx, "ffmpeg", args...); err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to take %s image of video at storage path: %s", outputType, sp))
+		return models.ProcessedOutput{}, fmt.Errorf("failed to take %s image: %w", outputType, err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		return models.ProcessedOutput{}, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to read %s image of video at storage path: %s", outputType, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	ofp := path.Join(sp, name)
+	oi, err := t.st.Put(ctx, ofp, bytes.NewReader(b), int64(len(b)), "image/jpeg")
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to store %s image %s at storage path: %s", outputType, ofp, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	t.log.Info().Msg(fmt.Sprintf("Stored %s image %s", outputType, ofp))
+	return models.ProcessedOutput{
+		ID:          poid,
+		StoragePath: sp,
+		Name:        name,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Resolution:  fmt.Sprintf("%dx%d", cfg.Width, cfg.Height),
+		Type:        outputType,
+		Extension:   path.Ext(name),
+		Format:      FormatJPEG,
+		Size:        oi.Size,
+	}, nil
+}
+
+// index writes the WebVTT index of the frames of the sheet and stores it next to the sheet
+// Each cue spans the part of the video that its frame was taken from
+func (t *videoThumbnailer) index(ctx context.Context, sp string, sheet models.ProcessedOutput, o SpriteOptions, rows int, d float64) (models.ProcessedOutput, error) {
+	url := sheet.Name
+	if o.ImageURL != "" {
+		url = strings.ReplaceAll(o.ImageURL, "{id}", sheet.ID.String())
+	}
+
+	w, h := sheet.Width/o.Columns, sheet.Height/rows
+	interval := d / float64(o.Frames)
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n")
+	for i := range o.Frames {
+		start, end := float64(i)*interval, min(float64(i+1)*interval, d)
+		fmt.Fprintf(&buf, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", timestamp(start), timestamp(end), url, (i%o.Columns)*w, (i/o.Columns)*h, w, h)
+	}
+
+	poid := uuid.New()
+	name := "sprite_" + poid.String() + ".vtt"
+	ofp := path.Join(sp, name)
+	oi, err := t.st.Put(ctx, ofp, &buf, int64(buf.Len()), "text/vtt")
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to store sprite index %s at storage path: %s", ofp, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	return models.ProcessedOutput{
+		ID:          poid,
+		StoragePath: sp,
+		Name:        name,
+		Duration:    seconds(d),
+		Type:        models.VideoSpriteIndexType,
+		Extension:   path.Ext(name),
+		Format:      "vtt",
+		Size:        oi.Size,
+	}, nil
+}
+
+// duration returns the given duration of the video, or probes the video for it when none is given
+func (t *videoThumbnailer) duration(ctx context.Context, in string, d float64) (float64, error) {
+	if d > 0 {
+		return d, nil
+	}
+
+	m, err := t.ext.ExtractVideoMetadata(ctx, in)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err = strconv.ParseFloat(m.Duration, 64)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("video has no duration")
+	}
+
+	return d, nil
+}
+
+// seconds formats the seconds as ffmpeg takes them e.g. 12.5
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
+
+// timestamp formats the seconds as a WebVTT timestamp e.g. 00:01:02.500
+func timestamp(s float64) string {
+	ms := time.Duration(math.Round(s*1000)) * time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", int(ms.Hours()), int(ms.Minutes())%60, int(ms.Seconds())%60, ms.Milliseconds()%1000)
+}
@@ -0,0 +1,274 @@
+package lib_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/mocks/mocklib"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// The ffprobe output of the source video
+const videoProbe = `{
+	"format": {"bit_rate": "2630000", "duration": "12.5", "size": "4100000"},
+	"streams": [{"codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080}]
+}`
+
+// writeFrame writes a JPEG image of the given size to the path that ffmpeg was given
+func writeFrame(w, h int) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil)
+		os.WriteFile(args.String(len(args)-1), buf.Bytes(), 0o644)
+	}
+}
+
+// poster matches the arguments that ffmpeg takes a poster with, up to the path of the poster
+func poster(m *mocklib.CommandExecutor, in string, at string, args ...string) *mock.Call {
+	ca := []interface{}{mock.Anything, "ffmpeg", "-v", "error", "-y", "-ss", at, "-i", in, "-frames:v", "1"}
+	for _, a := range append(args, "-q:v", "3", "-f", "image2") {
+		ca = append(ca, a)
+	}
+
+	return m.On("CommandContext", append(ca, mock.Anything)...)
+}
+
+func TestPoster(t *testing.T) {
+	probe := func(m *mocklib.CommandExecutor, in string) {
+		m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", in).Return([]byte(videoProbe), nil)
+	}
+
+	tests := []struct {
+		name        string
+		options     lib.PosterOptions
+		mockCommand func(m *mocklib.CommandExecutor, in string)
+		expectErr   error
+		expected    models.ProcessedOutput
+	}{
+		{
+			name:    "percentage of the probed duration",
+			options: lib.PosterOptions{Percent: 50, Width: 640},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				probe(m, in)
+				poster(m, in, "6.25", "-vf", "scale=640:-2").Run(writeFrame(640, 360)).Return(nil, nil)
+			},
+			expected: models.ProcessedOutput{Width: 640, Height: 360, Resolution: "640x360", Type: models.VideoPosterType, Extension: ".jpg", Format: "jpeg"},
+		},
+		{
+			name:    "percentage of the given duration",
+			options: lib.PosterOptions{Percent: 10, Duration: 60},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				poster(m, in, "6").Run(writeFrame(1920, 1080)).Return(nil, nil)
+			},
+			expected: models.ProcessedOutput{Width: 1920, Height: 1080, Resolution: "1920x1080", Type: models.VideoPosterType, Extension: ".jpg", Format: "jpeg"},
+		},
+		{
+			name:    "first frame without probing",
+			options: lib.PosterOptions{},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				poster(m, in, "0").Run(writeFrame(1920, 1080)).Return(nil, nil)
+			},
+			expected: models.ProcessedOutput{Width: 1920, Height: 1080, Resolution: "1920x1080", Type: models.VideoPosterType, Extension: ".jpg", Format: "jpeg"},
+		},
+		{
+			name:        "timestamp beyond the end",
+			options:     lib.PosterOptions{Timestamp: 20},
+			mockCommand: probe,
+			expectErr:   lib.ErrBeyondEnd,
+		},
+		{
+			name:    "ffmpeg error",
+			options: lib.PosterOptions{Timestamp: 2, Duration: 12.5},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				poster(m, in, "2").Return(nil, errors.New("ffmpeg error"))
+			},
+			expectErr: errors.New("failed to take video_poster image: ffmpeg error"),
+		},
+		{
+			name:        "timestamp and percentage",
+			options:     lib.PosterOptions{Timestamp: 2, Percent: 10},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   errors.New("invalid poster options: a poster is taken at either a timestamp or a percentage"),
+		},
+		{
+			name:        "too wide",
+			options:     lib.PosterOptions{Width: lib.MaxDimension + 1},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   errors.New("invalid poster options: width must be at most 8192"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			st := storage.NewLocal(root, &log)
+			_, err := st.Put(context.Background(), "videos/test.mp4", bytes.NewReader([]byte("video")), 5, "video/mp4")
+			assert.NoError(t, err)
+
+			m := new(mocklib.CommandExecutor)
+			tt.mockCommand(m, filepath.Join(root, "videos", "test.mp4"))
+
+			po, err := lib.NewThumbnailer(m, st, &log).Poster(context.Background(), "videos", "test.mp4", tt.options)
+			m.AssertExpectations(t)
+
+			objs, _ := st.List(context.Background(), "videos")
+			if tt.expectErr != nil {
+				if errors.Is(tt.expectErr, lib.ErrBeyondEnd) {
+					assert.ErrorIs(t, err, lib.ErrBeyondEnd)
+				} else {
+					assert.EqualError(t, err, tt.expectErr.Error())
+				}
+				assert.Len(t, objs, 1, "only the source video is left in the storage")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, objs, 2)
+			assert.Equal(t, "poster_"+po.ID.String()+".jpg", po.Name)
+			assert.Equal(t, "videos", po.StoragePath)
+			assert.Positive(t, po.Size)
+
+			// The ids, names and sizes are generated
+			po.ID, po.Name, po.StoragePath, po.Size = tt.expected.ID, tt.expected.Name, tt.expected.StoragePath, tt.expected.Size
+			assert.Equal(t, tt.expected, po)
+		})
+	}
+}
+
+func TestSprite(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     lib.SpriteOptions
+		mockCommand func(m *mocklib.CommandExecutor, in string)
+		expectErr   bool
+		expectedVTT []string
+	}{
+		{
+			name:    "sheet with an index",
+			options: lib.SpriteOptions{Frames: 4, Duration: 10, ImageURL: "/file/file-id/outputs/{id}/content"},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				ffmpeg(m, in, "-frames:v", "1", "-vf", "fps=4/10,scale=160:-2,tile=2x2", "-q:v", "3", "-f", "image2").Run(writeFrame(320, 180)).Return(nil, nil)
+			},
+			expectedVTT: []string{
+				"00:00:00.000 --> 00:00:02.500\n{url}#xywh=0,0,160,90",
+				"00:00:02.500 --> 00:00:05.000\n{url}#xywh=160,0,160,90",
+				"00:00:05.000 --> 00:00:07.500\n{url}#xywh=0,90,160,90",
+				"00:00:07.500 --> 00:00:10.000\n{url}#xywh=160,90,160,90",
+			},
+		},
+		{
+			name:    "probed duration and the name of the sheet",
+			options: lib.SpriteOptions{Frames: 3, Columns: 3, Width: 100},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", in).Return([]byte(videoProbe), nil)
+				ffmpeg(m, in, "-frames:v", "1", "-vf", "fps=3/12.5,scale=100:-2,tile=3x1", "-q:v", "3", "-f", "image2").Run(writeFrame(300, 56)).Return(nil, nil)
+			},
+			expectedVTT: []string{
+				"00:00:00.000 --> 00:00:04.167\n{url}#xywh=0,0,100,56",
+				"00:00:04.167 --> 00:00:08.333\n{url}#xywh=100,0,100,56",
+				"00:00:08.333 --> 00:00:12.500\n{url}#xywh=200,0,100,56",
+			},
+		},
+		{
+			name:    "ffmpeg error",
+			options: lib.SpriteOptions{Duration: 10},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				ffmpeg(m, in, "-frames:v", "1", "-vf", "fps=20/10,scale=160:-2,tile=5x4", "-q:v", "3", "-f", "image2").Return(nil, errors.New("ffmpeg error"))
+			},
+			expectErr: true,
+		},
+		{
+			name:        "too many frames",
+			options:     lib.SpriteOptions{Frames: lib.MaxSpriteFrames + 1},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+		{
+			name:        "frames too wide",
+			options:     lib.SpriteOptions{Width: lib.MaxSpriteWidth + 1},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+		{
+			name:        "sheet too wide",
+			options:     lib.SpriteOptions{Frames: 400, Columns: 400, Width: 100},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+		{
+			name:        "sheet too tall",
+			options:     lib.SpriteOptions{Frames: 400, Columns: 10, Width: 640},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			st := storage.NewLocal(root, &log)
+			_, err := st.Put(context.Background(), "videos/test.mp4", bytes.NewReader([]byte("video")), 5, "video/mp4")
+			assert.NoError(t, err)
+
+			m := new(mocklib.CommandExecutor)
+			tt.mockCommand(m, filepath.Join(root, "videos", "test.mp4"))
+
+			pos, err := lib.NewThumbnailer(m, st, &log).Sprite(context.Background(), "videos", "test.mp4", tt.options)
+			m.AssertExpectations(t)
+
+			objs, _ := st.List(context.Background(), "videos")
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Len(t, objs, 1, "only the source video is left in the storage")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, objs, 3)
+			if !assert.Len(t, pos, 2) {
+				return
+			}
+
+			sheet, index := pos[0], pos[1]
+			assert.Equal(t, models.VideoSpriteType, sheet.Type)
+			assert.Equal(t, "sprite_"+sheet.ID.String()+".jpg", sheet.Name)
+			assert.Equal(t, models.VideoSpriteIndexType, index.Type)
+			assert.Equal(t, "sprite_"+index.ID.String()+".vtt", index.Name)
+			assert.Equal(t, "vtt", index.Format)
+
+			obj, err := st.Get(context.Background(), path.Join("videos", index.Name))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer obj.Close()
+
+			b, _ := io.ReadAll(obj)
+			assert.True(t, strings.HasPrefix(obj.Info().ContentType, "text/vtt"), obj.Info().ContentType)
+
+			// The cues refer to the sheet by its id when a URL is given, or by its name otherwise
+			url := strings.ReplaceAll(tt.options.ImageURL, "{id}", sheet.ID.String())
+			if tt.options.ImageURL == "" {
+				url = sheet.Name
+			}
+
+			vtt := "WEBVTT\n"
+			for _, cue := range tt.expectedVTT {
+				vtt += "\n" + strings.ReplaceAll(cue, "{url}", url) + "\n"
+			}
+			assert.Equal(t, vtt, string(b))
+		})
+	}
+}
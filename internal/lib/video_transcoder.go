@@ -0,0 +1,232 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Containers that videos are transcoded to
+const (
+	FormatMP4  = "mp4"  // H.264 video and AAC audio
+	FormatWebM = "webm" // VP9 video and Opus audio
+)
+
+// The audio bit rate in kbit/s when none is given
+const DefaultAudioBitrate = 128
+
+// How long ffmpeg may read the source video from the storage
+const presignExpiry = 6 * time.Hour
+
+var videoFormats = []string{FormatMP4, FormatWebM}
+
+// The ffmpeg encoders and mime type of each container
+var videoCodecs = map[string]struct{ video, audio, mimeType string }{
+	FormatMP4:  {video: "libx264", audio: "aac", mimeType: "video/mp4"},
+	FormatWebM: {video: "libvpx-vp9", audio: "libopus", mimeType: "video/webm"},
+}
+
+// TranscodeProfile describes a rendition that a video is transcoded to
+type TranscodeProfile struct {
+	Name         string // The name of the profile e.g. 720p
+	Format       string // The container of the rendition e.g. mp4, webm
+	Width        int    // The width of the rendition in pixels, zero to scale by the height
+	Height       int    // The height of the rendition in pixels, zero to scale by the width
+	VideoBitrate int    // The bit rate of the video in kbit/s
+	AudioBitrate int    // The bit rate of the audio in kbit/s, DefaultAudioBitrate when zero
+}
+
+// Validate verifies that the profile describes a rendition that can be transcoded
+func (p TranscodeProfile) Validate() error {
+	if !slices.Contains(videoFormats, p.Format) {
+		return fmt.Errorf("unsupported format %q, must be one of %v", p.Format, videoFormats)
+	}
+
+	if p.Width < 0 || p.Height < 0 || (p.Width == 0 && p.Height == 0) {
+		return fmt.Errorf("a width or a height greater than zero is required")
+	}
+
+	if p.VideoBitrate <= 0 {
+		return fmt.Errorf("video bitrate must be greater than zero")
+	}
+
+	if p.AudioBitrate < 0 {
+		return fmt.Errorf("audio bitrate must not be negative")
+	}
+
+	return nil
+}
+
+// scale returns the ffmpeg filter that fits the video into the size of the profile
+// The aspect ratio is kept, and the sides are rounded to even numbers as the encoders require
+func (p TranscodeProfile) scale() string {
+	switch {
+	case p.Width == 0:
+		return fmt.Sprintf("scale=-2:%d", p.Height)
+	case p.Height == 0:
+		return fmt.Sprintf("scale=%d:-2", p.Width)
+	default:
+		return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2", p.Width, p.Height)
+	}
+}
+
+// args returns the arguments that ffmpeg transcodes the input to the output with
+func (p TranscodeProfile) args(in string, out string) []string {
+	codecs := videoCodecs[p.Format]
+	ab := p.AudioBitrate
+	if ab == 0 {
+		ab = DefaultAudioBitrate
+	}
+
+	args := []string{
+		"-v", "error",
+		"-y",
+		"-i", in,
+		"-vf", p.scale(),
+		"-c:v", codecs.video,
+		"-b:v", strconv.Itoa(p.VideoBitrate) + "k",
+		"-pix_fmt", "yuv420p",
+		"-c:a", codecs.audio,
+		"-b:a", strconv.Itoa(ab) + "k",
+	}
+
+	// MP4s are played while they download once the index is at the start of the file
+	if p.Format == FormatMP4 {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	return append(args, "-f", p.Format, out)
+}
+
+type videoTranscoder struct {
+	exec CommandExecutor
+	ext  MetadataExtractor
+	st   storage.Storage
+	log  *zerolog.Logger
+}
+
+type Transcoder interface {
+	Transcode(ctx context.Context, sp string, fn string, profiles []TranscodeProfile) ([]models.ProcessedOutput, error)
+}
+
+// NewTranscoder constructs a video transcoder that shells out to ffmpeg
+// The sources are read from and the renditions are written to the given storage
+func NewTranscoder(exec CommandExecutor, st storage.Storage, l *zerolog.Logger) Transcoder {
+	return &videoTranscoder{
+		exec: exec,
+		ext:  NewMetadataExtractor(exec, l),
+		st:   st,
+		log:  l,
+	}
+}
+
+// Transcodes the video once for each of the profiles
+// Either every rendition is stored or, when one of them fails, none of them are
+func (t *videoTranscoder) Transcode(ctx context.Context, sp string, fn string, profiles []TranscodeProfile) ([]models.ProcessedOutput, error) {
+	if len(profiles) == 0 || fn == "" {
+		t.log.Error().Msg(fmt.Sprintf("No transcode profiles for video %s at storage path %s", fn, sp))
+		return nil, fmt.Errorf("invalid transcode profiles: no profiles")
+	}
+
+	for _, p := range profiles {
+		if err := p.Validate(); err != nil {
+			t.log.Error().Err(err).Msg(fmt.Sprintf("Invalid transcode profile %s for video %s at storage path %s", p.Name, fn, sp))
+			return nil, fmt.Errorf("invalid transcode profile %q: %v", p.Name, err)
+		}
+	}
+
+	// ffmpeg reads the video straight from the storage rather than through the worker
+	in, err := t.st.PresignGet(ctx, path.Join(sp, fn), presignExpiry)
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to locate video %s at storage path %s", fn, sp))
+		return nil, err
+	}
+
+	// The renditions are written to a local directory before being stored
+	dir, err := os.MkdirTemp("", "transcode-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	pos := make([]models.ProcessedOutput, 0, len(profiles))
+	for _, p := range profiles {
+		po, err := t.rendition(ctx, sp, in, dir, p)
+		if err != nil {
+			// Remove the renditions that were already stored
+			for _, o := range pos {
+				t.st.Delete(ctx, path.Join(sp, o.Name))
+			}
+
+			return nil, err
+		}
+
+		pos = append(pos, po)
+	}
+
+	return pos, nil
+}
+
+// rendition transcodes the video with the given profile and stores the result
+// The codec, bit rate, resolution and duration are probed from the rendition itself
+func (t *videoTranscoder) rendition(ctx context.Context, sp string, in string, dir string, p TranscodeProfile) (models.ProcessedOutput, error) {
+	poid := uuid.New()
+	name := "transcoded_" + poid.String() + "." + p.Format
+	out := filepath.Join(dir, name)
+	if _, err := t.exec.CommandContext(ctx, "ffmpeg", p.args(in, out)...); err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to transcode video to profile %s at storage path: %s", p.Name, sp))
+		return models.ProcessedOutput{}, fmt.Errorf("failed to transcode to profile %q: %w", p.Name, err)
+	}
+
+	m, err := t.ext.ExtractVideoMetadata(ctx, out)
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to probe rendition of profile %s at storage path: %s", p.Name, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		return models.ProcessedOutput{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return models.ProcessedOutput{}, err
+	}
+
+	ofp := path.Join(sp, name)
+	oi, err := t.st.Put(ctx, ofp, f, fi.Size(), videoCodecs[p.Format].mimeType)
+	if err != nil {
+		t.log.Error().Err(err).Msg(fmt.Sprintf("Failed to store rendition %s at storage path: %s", ofp, sp))
+		return models.ProcessedOutput{}, err
+	}
+
+	t.log.Info().Msg(fmt.Sprintf("Transcoded video to profile %s at %s", p.Name, ofp))
+	return models.ProcessedOutput{
+		ID:          poid,
+		StoragePath: sp,
+		Name:        name,
+		Preset:      p.Name,
+		BitRate:     m.BitRate,
+		Codec:       m.Codec,
+		Duration:    m.Duration,
+		Width:       m.Width,
+		Height:      m.Height,
+		Resolution:  m.Resolution,
+		Type:        models.TranscodedVideoType,
+		Extension:   path.Ext(name),
+		Format:      p.Format,
+		Size:        oi.Size,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package lib_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/mocks/mocklib"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// The ffprobe output of a transcoded rendition
+const renditionProbe = `{
+	"format": {"bit_rate": "2630000", "duration": "12.5", "size": "4100000"},
+	"streams": [{"codec_name": "h264", "codec_type": "video", "width": 1280, "height": 720}, {"codec_name": "aac", "codec_type": "audio"}]
+}`
+
+// ffmpeg matches the arguments that ffmpeg is run with, up to the path of the rendition
+func ffmpeg(m *mocklib.CommandExecutor, in string, args ...string) *mock.Call {
+	ca := []interface{}{mock.Anything, "ffmpeg", "-v", "error", "-y", "-i", in}
+	for _, a := range args {
+		ca = append(ca, a)
+	}
+
+	return m.On("CommandContext", append(ca, mock.Anything)...)
+}
+
+// writeRendition writes the rendition to the path that ffmpeg was given
+func writeRendition(args mock.Arguments) {
+	os.WriteFile(args.String(len(args)-1), []byte("rendition"), 0o644)
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name        string
+		profiles    []lib.TranscodeProfile
+		mockCommand func(m *mocklib.CommandExecutor, in string)
+		expectErr   bool
+		expected    []models.ProcessedOutput
+	}{
+		{
+			name:     "h264 and vp9 renditions",
+			profiles: []lib.TranscodeProfile{{Name: "720p", Format: lib.FormatMP4, Width: 1280, Height: 720, VideoBitrate: 2500}, {Name: "480p-webm", Format: lib.FormatWebM, Height: 480, VideoBitrate: 800, AudioBitrate: 96}},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				ffmpeg(m, in, "-vf", "scale=1280:720:force_original_aspect_ratio=decrease:force_divisible_by=2", "-c:v", "libx264", "-b:v", "2500k", "-pix_fmt", "yuv420p", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", "-f", "mp4").Run(writeRendition).Return(nil, nil)
+				ffmpeg(m, in, "-vf", "scale=-2:480", "-c:v", "libvpx-vp9", "-b:v", "800k", "-pix_fmt", "yuv420p", "-c:a", "libopus", "-b:a", "96k", "-f", "webm").Run(writeRendition).Return(nil, nil)
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", mock.Anything).Return([]byte(renditionProbe), nil)
+			},
+			expected: []models.ProcessedOutput{
+				{Preset: "720p", Format: "mp4", Extension: ".mp4", Codec: "h264", BitRate: "2630000", Duration: "12.5", Width: 1280, Height: 720, Resolution: "1280x720", Size: 9, Type: models.TranscodedVideoType},
+				{Preset: "480p-webm", Format: "webm", Extension: ".webm", Codec: "h264", BitRate: "2630000", Duration: "12.5", Width: 1280, Height: 720, Resolution: "1280x720", Size: 9, Type: models.TranscodedVideoType},
+			},
+		},
+		{
+			name:     "failed rendition removes the others",
+			profiles: []lib.TranscodeProfile{{Name: "720p", Format: lib.FormatMP4, Width: 1280, VideoBitrate: 2500}, {Name: "480p", Format: lib.FormatMP4, Width: 854, VideoBitrate: 1000}},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {
+				ffmpeg(m, in, "-vf", "scale=1280:-2", "-c:v", "libx264", "-b:v", "2500k", "-pix_fmt", "yuv420p", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", "-f", "mp4").Run(writeRendition).Return(nil, nil)
+				ffmpeg(m, in, "-vf", "scale=854:-2", "-c:v", "libx264", "-b:v", "1000k", "-pix_fmt", "yuv420p", "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", "-f", "mp4").Return(nil, errors.New("ffmpeg error"))
+				m.On("CommandContext", mock.Anything, "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", mock.Anything).Return([]byte(renditionProbe), nil)
+			},
+			expectErr: true,
+		},
+		{
+			name:        "unsupported format",
+			profiles:    []lib.TranscodeProfile{{Name: "hevc", Format: "mkv", Width: 1280, VideoBitrate: 2500}},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+		{
+			name:        "no size",
+			profiles:    []lib.TranscodeProfile{{Name: "720p", Format: lib.FormatMP4, VideoBitrate: 2500}},
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+		{
+			name:        "no profiles",
+			mockCommand: func(m *mocklib.CommandExecutor, in string) {},
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			st := storage.NewLocal(root, &log)
+			_, err := st.Put(context.Background(), "videos/test.mp4", bytes.NewReader([]byte("video")), 5, "video/mp4")
+			assert.NoError(t, err)
+
+			m := new(mocklib.CommandExecutor)
+			tt.mockCommand(m, filepath.Join(root, "videos", "test.mp4"))
+
+			pos, err := lib.NewTranscoder(m, st, &log).Transcode(context.Background(), "videos", "test.mp4", tt.profiles)
+			m.AssertExpectations(t)
+
+			objs, _ := st.List(context.Background(), "videos")
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Len(t, objs, 1, "only the source video is left in the storage")
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1+len(tt.expected))
+			if assert.Len(t, pos, len(tt.expected)) {
+				for i, po := range pos {
+					assert.True(t, strings.HasPrefix(po.Name, "transcoded_"+po.ID.String()))
+					assert.Equal(t, "videos", po.StoragePath)
+
+					// The ids and names are generated
+					po.ID, po.Name, po.StoragePath = tt.expected[i].ID, tt.expected[i].Name, tt.expected[i].StoragePath
+					assert.Equal(t, tt.expected[i], po)
+				}
+			}
+		})
+	}
+}
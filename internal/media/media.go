@@ -0,0 +1,190 @@
+package media
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Categories of the supported media types
+const (
+	CategoryImage    = "image"
+	CategoryVideo    = "video"
+	CategoryDocument = "document"
+	CategoryOther    = "other" // The category of every media type that is not registered
+)
+
+// Processors that can be allowed for a media type
+const (
+	ProcessorResize        = "resize"         // Resizing of images
+	ProcessorVideoMetadata = "video_metadata" // Extraction of video metadata using ffprobe
+	ProcessorTranscode     = "transcode"      // Transcoding of videos to other formats and sizes using ffmpeg
+	ProcessorPoster        = "poster"         // Taking a frame of a video as its poster using ffmpeg
+	ProcessorSprite        = "sprite"         // Tiling frames of a video into a sprite sheet for scrubbing previews using ffmpeg
+)
+
+var processors = []string{ProcessorResize, ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}
+
+// MediaType describes a supported media type
+type MediaType struct {
+	MimeType   string   `json:"mime_type"`  // e.g. video/x-matroska
+	Aliases    []string `json:"aliases"`    // e.g. video/mkv, other mime types that name the same media type
+	Extensions []string `json:"extensions"` // e.g. mkv, the extensions of files of the media type without the dot
+	Category   string   `json:"category"`   // e.g. image, video, document
+	Processors []string `json:"processors"` // e.g. resize, the processors that files of the media type may be run through
+}
+
+// Registry classifies files by their media type
+type Registry interface {
+	// Lookup returns the media type with the given mime type or alias
+	Lookup(mimeType string) (MediaType, bool)
+	// Category returns the category of the given mime type, or other when it is not registered
+	Category(mimeType string) string
+	// Supports reports whether files of the given mime type may be run through the processor
+	Supports(mimeType, processor string) bool
+	// MimeTypeByExtension returns the mime type of the given extension, or an empty string when it is not registered
+	MimeTypeByExtension(ext string) string
+	// ExtensionMatches reports whether the extension is expected for a file of the given mime type
+	ExtensionMatches(mimeType, ext string) bool
+}
+
+type registry struct {
+	types  []MediaType
+	byMime map[string]int // indexes types by mime type and alias
+	byExt  map[string]int // indexes types by extension
+}
+
+// NewRegistry creates a registry of the given media types
+// An error is returned when a media type is incomplete, or when two media
+// types share a mime type or extension
+func NewRegistry(types []MediaType) (Registry, error) {
+	r := &registry{
+		types:  types,
+		byMime: map[string]int{},
+		byExt:  map[string]int{},
+	}
+
+	for i, t := range types {
+		if t.MimeType == "" || t.Category == "" {
+			return nil, fmt.Errorf("media type %d must have a mime type and a category", i)
+		}
+
+		for _, p := range t.Processors {
+			if !IsProcessor(p) {
+				return nil, fmt.Errorf("media type %s has unknown processor %q", t.MimeType, p)
+			}
+		}
+
+		for _, m := range append([]string{t.MimeType}, t.Aliases...) {
+			m = strings.ToLower(m)
+			if _, ok := r.byMime[m]; ok {
+				return nil, fmt.Errorf("mime type %s is registered more than once", m)
+			}
+
+			r.byMime[m] = i
+		}
+
+		for _, e := range t.Extensions {
+			e = normalizeExtension(e)
+			if _, ok := r.byExt[e]; ok {
+				return nil, fmt.Errorf("extension %s is registered more than once", e)
+			}
+
+			r.byExt[e] = i
+		}
+	}
+
+	return r, nil
+}
+
+func (r *registry) Lookup(mimeType string) (MediaType, bool) {
+	// Parameters such as the charset do not change the media type
+	mt, _, _ := strings.Cut(mimeType, ";")
+	i, ok := r.byMime[strings.ToLower(strings.TrimSpace(mt))]
+	if !ok {
+		return MediaType{}, false
+	}
+
+	return r.types[i], true
+}
+
+func (r *registry) Category(mimeType string) string {
+	if t, ok := r.Lookup(mimeType); ok {
+		return t.Category
+	}
+
+	return CategoryOther
+}
+
+func (r *registry) Supports(mimeType, processor string) bool {
+	t, ok := r.Lookup(mimeType)
+	return ok && slices.Contains(t.Processors, processor)
+}
+
+func (r *registry) MimeTypeByExtension(ext string) string {
+	if i, ok := r.byExt[normalizeExtension(ext)]; ok {
+		return r.types[i].MimeType
+	}
+
+	return ""
+}
+
+// A registered mime type matches its own extensions only, while a mime type
+// that is not registered mismatches the extensions of every registered one
+func (r *registry) ExtensionMatches(mimeType, ext string) bool {
+	ext = normalizeExtension(ext)
+	if t, ok := r.Lookup(mimeType); ok {
+		return slices.Contains(t.Extensions, ext)
+	}
+
+	_, claimed := r.byExt[ext]
+	return !claimed
+}
+
+func normalizeExtension(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// IsProcessor reports whether the given name is a known processor e.g. resize
+func IsProcessor(name string) bool {
+	return slices.Contains(processors, name)
+}
+
+// DefaultTypes returns the media types supported when none are configured
+func DefaultTypes() []MediaType {
+	return []MediaType{
+		{MimeType: "image/jpeg", Aliases: []string{"image/jpg", "image/pjpeg"}, Extensions: []string{"jpg", "jpeg", "jpe", "jfif"}, Category: CategoryImage, Processors: []string{ProcessorResize}},
+		{MimeType: "image/png", Extensions: []string{"png"}, Category: CategoryImage, Processors: []string{ProcessorResize}},
+		{MimeType: "image/gif", Extensions: []string{"gif"}, Category: CategoryImage, Processors: []string{ProcessorResize}},
+		{MimeType: "image/webp", Extensions: []string{"webp"}, Category: CategoryImage, Processors: []string{ProcessorResize}},
+		{MimeType: "video/mp4", Extensions: []string{"mp4", "m4v"}, Category: CategoryVideo, Processors: []string{ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}},
+		{MimeType: "video/quicktime", Aliases: []string{"video/mov"}, Extensions: []string{"mov", "qt"}, Category: CategoryVideo, Processors: []string{ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}},
+		{MimeType: "video/x-msvideo", Aliases: []string{"video/avi", "video/msvideo"}, Extensions: []string{"avi"}, Category: CategoryVideo, Processors: []string{ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}},
+		{MimeType: "video/x-matroska", Aliases: []string{"video/mkv"}, Extensions: []string{"mkv"}, Category: CategoryVideo, Processors: []string{ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}},
+		{MimeType: "video/webm", Extensions: []string{"webm"}, Category: CategoryVideo, Processors: []string{ProcessorVideoMetadata, ProcessorTranscode, ProcessorPoster, ProcessorSprite}},
+		{MimeType: "application/pdf", Extensions: []string{"pdf"}, Category: CategoryDocument},
+	}
+}
+
+var defaultRegistry atomic.Pointer[Registry]
+
+func init() {
+	r, err := NewRegistry(DefaultTypes())
+	if err != nil {
+		panic(err)
+	}
+
+	SetDefault(r)
+}
+
+// Default returns the registry used throughout the service
+func Default() Registry {
+	return *defaultRegistry.Load()
+}
+
+// SetDefault replaces the registry used throughout the service
+// It is called at startup with the media types from the configuration
+func SetDefault(r Registry) {
+	defaultRegistry.Store(&r)
+}
@@ -0,0 +1,110 @@
+package media_test
+
+import (
+	"simple-file-processor/internal/media"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   []media.MediaType
+		wantErr bool
+	}{
+		{
+			name:  "default types",
+			types: media.DefaultTypes(),
+		},
+		{
+			name:    "missing category",
+			types:   []media.MediaType{{MimeType: "image/png"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown processor",
+			types:   []media.MediaType{{MimeType: "image/png", Category: media.CategoryImage, Processors: []string{"sharpen"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate mime type",
+			types: []media.MediaType{
+				{MimeType: "video/x-matroska", Category: media.CategoryVideo},
+				{MimeType: "video/mkv", Aliases: []string{"video/x-matroska"}, Category: media.CategoryVideo},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate extension",
+			types: []media.MediaType{
+				{MimeType: "image/jpeg", Extensions: []string{"jpg"}, Category: media.CategoryImage},
+				{MimeType: "image/pjpeg", Extensions: []string{".JPG"}, Category: media.CategoryImage},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := media.NewRegistry(tt.types)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, r)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := media.NewRegistry(media.DefaultTypes())
+	assert.NoError(t, err)
+
+	t.Run("Category", func(t *testing.T) {
+		assert.Equal(t, media.CategoryImage, r.Category("image/jpeg"))
+		assert.Equal(t, media.CategoryImage, r.Category("image/jpg"))
+		assert.Equal(t, media.CategoryVideo, r.Category("video/x-matroska"))
+		assert.Equal(t, media.CategoryVideo, r.Category("video/mkv"))
+		assert.Equal(t, media.CategoryDocument, r.Category("application/pdf"))
+		assert.Equal(t, media.CategoryOther, r.Category("text/plain; charset=utf-8"))
+	})
+
+	t.Run("Supports", func(t *testing.T) {
+		assert.True(t, r.Supports("image/png", media.ProcessorResize))
+		assert.False(t, r.Supports("image/png", media.ProcessorVideoMetadata))
+		assert.True(t, r.Supports("video/quicktime", media.ProcessorVideoMetadata))
+		assert.False(t, r.Supports("application/pdf", media.ProcessorResize))
+		assert.False(t, r.Supports("application/octet-stream", media.ProcessorResize))
+	})
+
+	t.Run("MimeTypeByExtension", func(t *testing.T) {
+		assert.Equal(t, "video/x-matroska", r.MimeTypeByExtension("mkv"))
+		assert.Equal(t, "image/jpeg", r.MimeTypeByExtension(".JPG"))
+		assert.Equal(t, "", r.MimeTypeByExtension("exe"))
+	})
+
+	t.Run("ExtensionMatches", func(t *testing.T) {
+		assert.True(t, r.ExtensionMatches("image/jpeg", "jpg"))
+		assert.True(t, r.ExtensionMatches("image/jpeg", ".JPEG"))
+		assert.False(t, r.ExtensionMatches("image/png", "jpg"))
+		assert.False(t, r.ExtensionMatches("video/x-matroska", "mp4"))
+		assert.False(t, r.ExtensionMatches("application/octet-stream", "mp4"))
+		assert.True(t, r.ExtensionMatches("application/octet-stream", "exe"))
+		assert.True(t, r.ExtensionMatches("text/plain; charset=utf-8", "txt"))
+	})
+}
+
+func TestSetDefault(t *testing.T) {
+	def := media.Default()
+	t.Cleanup(func() { media.SetDefault(def) })
+
+	r, err := media.NewRegistry([]media.MediaType{{MimeType: "image/tiff", Extensions: []string{"tif"}, Category: media.CategoryImage}})
+	assert.NoError(t, err)
+	media.SetDefault(r)
+	assert.Equal(t, media.CategoryImage, media.Default().Category("image/tiff"))
+	assert.Equal(t, media.CategoryOther, media.Default().Category("image/png"))
+}
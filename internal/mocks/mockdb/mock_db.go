@@ -21,6 +21,66 @@ func (_m *Database) EXPECT() *Database_Expecter {
 	return &Database_Expecter{mock: &_m.Mock}
 }
 
+// ActiveJob provides a mock function with given fields: fid, taskType, payload
+func (_m *Database) ActiveJob(fid string, taskType string, payload []byte) (*models.Job, error) {
+	ret := _m.Called(fid, taskType, payload)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ActiveJob")
+	}
+
+	var r0 *models.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, []byte) (*models.Job, error)); ok {
+		return rf(fid, taskType, payload)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, []byte) *models.Job); ok {
+		r0 = rf(fid, taskType, payload)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, []byte) error); ok {
+		r1 = rf(fid, taskType, payload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_ActiveJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ActiveJob'
+type Database_ActiveJob_Call struct {
+	*mock.Call
+}
+
+// ActiveJob is a helper method to define mock.On call
+//   - fid string
+//   - taskType string
+//   - payload []byte
+func (_e *Database_Expecter) ActiveJob(fid interface{}, taskType interface{}, payload interface{}) *Database_ActiveJob_Call {
+	return &Database_ActiveJob_Call{Call: _e.mock.On("ActiveJob", fid, taskType, payload)}
+}
+
+func (_c *Database_ActiveJob_Call) Run(run func(fid string, taskType string, payload []byte)) *Database_ActiveJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].([]byte))
+	})
+	return _c
+}
+
+func (_c *Database_ActiveJob_Call) Return(_a0 *models.Job, _a1 error) *Database_ActiveJob_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_ActiveJob_Call) RunAndReturn(run func(string, string, []byte) (*models.Job, error)) *Database_ActiveJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // AddProcessedOutput provides a mock function with given fields: _a0, _a1
 func (_m *Database) AddProcessedOutput(_a0 string, _a1 models.ProcessedOutput) error {
 	ret := _m.Called(_a0, _a1)
@@ -68,6 +128,193 @@ func (_c *Database_AddProcessedOutput_Call) RunAndReturn(run func(string, models
 	return _c
 }
 
+// AddProcessedOutputs provides a mock function with given fields: _a0, _a1
+func (_m *Database) AddProcessedOutputs(_a0 string, _a1 []models.ProcessedOutput) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddProcessedOutputs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []models.ProcessedOutput) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_AddProcessedOutputs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddProcessedOutputs'
+type Database_AddProcessedOutputs_Call struct {
+	*mock.Call
+}
+
+// AddProcessedOutputs is a helper method to define mock.On call
+//   - _a0 string
+//   - _a1 []models.ProcessedOutput
+func (_e *Database_Expecter) AddProcessedOutputs(_a0 interface{}, _a1 interface{}) *Database_AddProcessedOutputs_Call {
+	return &Database_AddProcessedOutputs_Call{Call: _e.mock.On("AddProcessedOutputs", _a0, _a1)}
+}
+
+func (_c *Database_AddProcessedOutputs_Call) Run(run func(_a0 string, _a1 []models.ProcessedOutput)) *Database_AddProcessedOutputs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].([]models.ProcessedOutput))
+	})
+	return _c
+}
+
+func (_c *Database_AddProcessedOutputs_Call) Return(_a0 error) *Database_AddProcessedOutputs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_AddProcessedOutputs_Call) RunAndReturn(run func(string, []models.ProcessedOutput) error) *Database_AddProcessedOutputs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AdvanceUploadOffset provides a mock function with given fields: id, from, to
+func (_m *Database) AdvanceUploadOffset(id string, from int64, to int64) error {
+	ret := _m.Called(id, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AdvanceUploadOffset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64, int64) error); ok {
+		r0 = rf(id, from, to)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_AdvanceUploadOffset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AdvanceUploadOffset'
+type Database_AdvanceUploadOffset_Call struct {
+	*mock.Call
+}
+
+// AdvanceUploadOffset is a helper method to define mock.On call
+//   - id string
+//   - from int64
+//   - to int64
+func (_e *Database_Expecter) AdvanceUploadOffset(id interface{}, from interface{}, to interface{}) *Database_AdvanceUploadOffset_Call {
+	return &Database_AdvanceUploadOffset_Call{Call: _e.mock.On("AdvanceUploadOffset", id, from, to)}
+}
+
+func (_c *Database_AdvanceUploadOffset_Call) Run(run func(id string, from int64, to int64)) *Database_AdvanceUploadOffset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int64), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *Database_AdvanceUploadOffset_Call) Return(_a0 error) *Database_AdvanceUploadOffset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_AdvanceUploadOffset_Call) RunAndReturn(run func(string, int64, int64) error) *Database_AdvanceUploadOffset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteFile provides a mock function with given fields: _a0
+func (_m *Database) DeleteFile(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_DeleteFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteFile'
+type Database_DeleteFile_Call struct {
+	*mock.Call
+}
+
+// DeleteFile is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) DeleteFile(_a0 interface{}) *Database_DeleteFile_Call {
+	return &Database_DeleteFile_Call{Call: _e.mock.On("DeleteFile", _a0)}
+}
+
+func (_c *Database_DeleteFile_Call) Run(run func(_a0 string)) *Database_DeleteFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_DeleteFile_Call) Return(_a0 error) *Database_DeleteFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_DeleteFile_Call) RunAndReturn(run func(string) error) *Database_DeleteFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteJob provides a mock function with given fields: _a0
+func (_m *Database) DeleteJob(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_DeleteJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteJob'
+type Database_DeleteJob_Call struct {
+	*mock.Call
+}
+
+// DeleteJob is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) DeleteJob(_a0 interface{}) *Database_DeleteJob_Call {
+	return &Database_DeleteJob_Call{Call: _e.mock.On("DeleteJob", _a0)}
+}
+
+func (_c *Database_DeleteJob_Call) Run(run func(_a0 string)) *Database_DeleteJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_DeleteJob_Call) Return(_a0 error) *Database_DeleteJob_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_DeleteJob_Call) RunAndReturn(run func(string) error) *Database_DeleteJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // FileByID provides a mock function with given fields: _a0
 func (_m *Database) FileByID(_a0 string) (*models.File, error) {
 	ret := _m.Called(_a0)
@@ -126,6 +373,64 @@ func (_c *Database_FileByID_Call) RunAndReturn(run func(string) (*models.File, e
 	return _c
 }
 
+// Files provides a mock function with given fields: _a0
+func (_m *Database) Files(_a0 models.FileFilter) (*models.FilePage, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Files")
+	}
+
+	var r0 *models.FilePage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.FileFilter) (*models.FilePage, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(models.FileFilter) *models.FilePage); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.FilePage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(models.FileFilter) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_Files_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Files'
+type Database_Files_Call struct {
+	*mock.Call
+}
+
+// Files is a helper method to define mock.On call
+//   - _a0 models.FileFilter
+func (_e *Database_Expecter) Files(_a0 interface{}) *Database_Files_Call {
+	return &Database_Files_Call{Call: _e.mock.On("Files", _a0)}
+}
+
+func (_c *Database_Files_Call) Run(run func(_a0 models.FileFilter)) *Database_Files_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(models.FileFilter))
+	})
+	return _c
+}
+
+func (_c *Database_Files_Call) Return(_a0 *models.FilePage, _a1 error) *Database_Files_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_Files_Call) RunAndReturn(run func(models.FileFilter) (*models.FilePage, error)) *Database_Files_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // InsertFileMetadata provides a mock function with given fields: _a0
 func (_m *Database) InsertFileMetadata(_a0 *models.File) error {
 	ret := _m.Called(_a0)
@@ -172,17 +477,17 @@ func (_c *Database_InsertFileMetadata_Call) RunAndReturn(run func(*models.File)
 	return _c
 }
 
-// Migrate provides a mock function with no fields
-func (_m *Database) Migrate() error {
-	ret := _m.Called()
+// InsertJob provides a mock function with given fields: _a0
+func (_m *Database) InsertJob(_a0 *models.Job) error {
+	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Migrate")
+		panic("no return value specified for InsertJob")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func() error); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(*models.Job) error); ok {
+		r0 = rf(_a0)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -190,29 +495,495 @@ func (_m *Database) Migrate() error {
 	return r0
 }
 
-// Database_Migrate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Migrate'
-type Database_Migrate_Call struct {
+// Database_InsertJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertJob'
+type Database_InsertJob_Call struct {
 	*mock.Call
 }
 
-// Migrate is a helper method to define mock.On call
-func (_e *Database_Expecter) Migrate() *Database_Migrate_Call {
-	return &Database_Migrate_Call{Call: _e.mock.On("Migrate")}
+// InsertJob is a helper method to define mock.On call
+//   - _a0 *models.Job
+func (_e *Database_Expecter) InsertJob(_a0 interface{}) *Database_InsertJob_Call {
+	return &Database_InsertJob_Call{Call: _e.mock.On("InsertJob", _a0)}
 }
 
-func (_c *Database_Migrate_Call) Run(run func()) *Database_Migrate_Call {
+func (_c *Database_InsertJob_Call) Run(run func(_a0 *models.Job)) *Database_InsertJob_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run()
+		run(args[0].(*models.Job))
 	})
 	return _c
 }
 
-func (_c *Database_Migrate_Call) Return(_a0 error) *Database_Migrate_Call {
+func (_c *Database_InsertJob_Call) Return(_a0 error) *Database_InsertJob_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Database_Migrate_Call) RunAndReturn(run func() error) *Database_Migrate_Call {
+func (_c *Database_InsertJob_Call) RunAndReturn(run func(*models.Job) error) *Database_InsertJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// InsertWebhookDelivery provides a mock function with given fields: _a0
+func (_m *Database) InsertWebhookDelivery(_a0 *models.WebhookDelivery) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.WebhookDelivery) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_InsertWebhookDelivery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertWebhookDelivery'
+type Database_InsertWebhookDelivery_Call struct {
+	*mock.Call
+}
+
+// InsertWebhookDelivery is a helper method to define mock.On call
+//   - _a0 *models.WebhookDelivery
+func (_e *Database_Expecter) InsertWebhookDelivery(_a0 interface{}) *Database_InsertWebhookDelivery_Call {
+	return &Database_InsertWebhookDelivery_Call{Call: _e.mock.On("InsertWebhookDelivery", _a0)}
+}
+
+func (_c *Database_InsertWebhookDelivery_Call) Run(run func(_a0 *models.WebhookDelivery)) *Database_InsertWebhookDelivery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*models.WebhookDelivery))
+	})
+	return _c
+}
+
+func (_c *Database_InsertWebhookDelivery_Call) Return(_a0 error) *Database_InsertWebhookDelivery_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_InsertWebhookDelivery_Call) RunAndReturn(run func(*models.WebhookDelivery) error) *Database_InsertWebhookDelivery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// JobByID provides a mock function with given fields: _a0
+func (_m *Database) JobByID(_a0 string) (*models.Job, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for JobByID")
+	}
+
+	var r0 *models.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.Job, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Job); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_JobByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'JobByID'
+type Database_JobByID_Call struct {
+	*mock.Call
+}
+
+// JobByID is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) JobByID(_a0 interface{}) *Database_JobByID_Call {
+	return &Database_JobByID_Call{Call: _e.mock.On("JobByID", _a0)}
+}
+
+func (_c *Database_JobByID_Call) Run(run func(_a0 string)) *Database_JobByID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_JobByID_Call) Return(_a0 *models.Job, _a1 error) *Database_JobByID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_JobByID_Call) RunAndReturn(run func(string) (*models.Job, error)) *Database_JobByID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// JobsByFileID provides a mock function with given fields: _a0
+func (_m *Database) JobsByFileID(_a0 string) ([]models.Job, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for JobsByFileID")
+	}
+
+	var r0 []models.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.Job, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.Job); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Job)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_JobsByFileID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'JobsByFileID'
+type Database_JobsByFileID_Call struct {
+	*mock.Call
+}
+
+// JobsByFileID is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) JobsByFileID(_a0 interface{}) *Database_JobsByFileID_Call {
+	return &Database_JobsByFileID_Call{Call: _e.mock.On("JobsByFileID", _a0)}
+}
+
+func (_c *Database_JobsByFileID_Call) Run(run func(_a0 string)) *Database_JobsByFileID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_JobsByFileID_Call) Return(_a0 []models.Job, _a1 error) *Database_JobsByFileID_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_JobsByFileID_Call) RunAndReturn(run func(string) ([]models.Job, error)) *Database_JobsByFileID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Migrate provides a mock function with no fields
+func (_m *Database) Migrate() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Migrate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_Migrate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Migrate'
+type Database_Migrate_Call struct {
+	*mock.Call
+}
+
+// Migrate is a helper method to define mock.On call
+func (_e *Database_Expecter) Migrate() *Database_Migrate_Call {
+	return &Database_Migrate_Call{Call: _e.mock.On("Migrate")}
+}
+
+func (_c *Database_Migrate_Call) Run(run func()) *Database_Migrate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Database_Migrate_Call) Return(_a0 error) *Database_Migrate_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_Migrate_Call) RunAndReturn(run func() error) *Database_Migrate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// StatusTransitions provides a mock function with given fields: _a0
+func (_m *Database) StatusTransitions(_a0 string) ([]models.StatusTransition, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StatusTransitions")
+	}
+
+	var r0 []models.StatusTransition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.StatusTransition, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.StatusTransition); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusTransition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_StatusTransitions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StatusTransitions'
+type Database_StatusTransitions_Call struct {
+	*mock.Call
+}
+
+// StatusTransitions is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) StatusTransitions(_a0 interface{}) *Database_StatusTransitions_Call {
+	return &Database_StatusTransitions_Call{Call: _e.mock.On("StatusTransitions", _a0)}
+}
+
+func (_c *Database_StatusTransitions_Call) Run(run func(_a0 string)) *Database_StatusTransitions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_StatusTransitions_Call) Return(_a0 []models.StatusTransition, _a1 error) *Database_StatusTransitions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_StatusTransitions_Call) RunAndReturn(run func(string) ([]models.StatusTransition, error)) *Database_StatusTransitions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TransitionFile provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Database) TransitionFile(_a0 string, _a1 string, _a2 models.StatusTransition) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TransitionFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, models.StatusTransition) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_TransitionFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TransitionFile'
+type Database_TransitionFile_Call struct {
+	*mock.Call
+}
+
+// TransitionFile is a helper method to define mock.On call
+//   - _a0 string
+//   - _a1 string
+//   - _a2 models.StatusTransition
+func (_e *Database_Expecter) TransitionFile(_a0 interface{}, _a1 interface{}, _a2 interface{}) *Database_TransitionFile_Call {
+	return &Database_TransitionFile_Call{Call: _e.mock.On("TransitionFile", _a0, _a1, _a2)}
+}
+
+func (_c *Database_TransitionFile_Call) Run(run func(_a0 string, _a1 string, _a2 models.StatusTransition)) *Database_TransitionFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(models.StatusTransition))
+	})
+	return _c
+}
+
+func (_c *Database_TransitionFile_Call) Return(_a0 error) *Database_TransitionFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_TransitionFile_Call) RunAndReturn(run func(string, string, models.StatusTransition) error) *Database_TransitionFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateFile provides a mock function with given fields: _a0, _a1
+func (_m *Database) UpdateFile(_a0 string, _a1 map[string]interface{}) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_UpdateFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateFile'
+type Database_UpdateFile_Call struct {
+	*mock.Call
+}
+
+// UpdateFile is a helper method to define mock.On call
+//   - _a0 string
+//   - _a1 map[string]interface{}
+func (_e *Database_Expecter) UpdateFile(_a0 interface{}, _a1 interface{}) *Database_UpdateFile_Call {
+	return &Database_UpdateFile_Call{Call: _e.mock.On("UpdateFile", _a0, _a1)}
+}
+
+func (_c *Database_UpdateFile_Call) Run(run func(_a0 string, _a1 map[string]interface{})) *Database_UpdateFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(map[string]interface{}))
+	})
+	return _c
+}
+
+func (_c *Database_UpdateFile_Call) Return(_a0 error) *Database_UpdateFile_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_UpdateFile_Call) RunAndReturn(run func(string, map[string]interface{}) error) *Database_UpdateFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateJob provides a mock function with given fields: _a0, _a1
+func (_m *Database) UpdateJob(_a0 string, _a1 map[string]interface{}) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Database_UpdateJob_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateJob'
+type Database_UpdateJob_Call struct {
+	*mock.Call
+}
+
+// UpdateJob is a helper method to define mock.On call
+//   - _a0 string
+//   - _a1 map[string]interface{}
+func (_e *Database_Expecter) UpdateJob(_a0 interface{}, _a1 interface{}) *Database_UpdateJob_Call {
+	return &Database_UpdateJob_Call{Call: _e.mock.On("UpdateJob", _a0, _a1)}
+}
+
+func (_c *Database_UpdateJob_Call) Run(run func(_a0 string, _a1 map[string]interface{})) *Database_UpdateJob_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(map[string]interface{}))
+	})
+	return _c
+}
+
+func (_c *Database_UpdateJob_Call) Return(_a0 error) *Database_UpdateJob_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Database_UpdateJob_Call) RunAndReturn(run func(string, map[string]interface{}) error) *Database_UpdateJob_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WebhookDeliveries provides a mock function with given fields: _a0
+func (_m *Database) WebhookDeliveries(_a0 string) ([]models.WebhookDelivery, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WebhookDeliveries")
+	}
+
+	var r0 []models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.WebhookDelivery, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.WebhookDelivery); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Database_WebhookDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WebhookDeliveries'
+type Database_WebhookDeliveries_Call struct {
+	*mock.Call
+}
+
+// WebhookDeliveries is a helper method to define mock.On call
+//   - _a0 string
+func (_e *Database_Expecter) WebhookDeliveries(_a0 interface{}) *Database_WebhookDeliveries_Call {
+	return &Database_WebhookDeliveries_Call{Call: _e.mock.On("WebhookDeliveries", _a0)}
+}
+
+func (_c *Database_WebhookDeliveries_Call) Run(run func(_a0 string)) *Database_WebhookDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Database_WebhookDeliveries_Call) Return(_a0 []models.WebhookDelivery, _a1 error) *Database_WebhookDeliveries_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Database_WebhookDeliveries_Call) RunAndReturn(run func(string) ([]models.WebhookDelivery, error)) *Database_WebhookDeliveries_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -3,6 +3,8 @@
 package mockdb
 
 import (
+	sql "database/sql"
+
 	mock "github.com/stretchr/testify/mock"
 	gorm "gorm.io/gorm"
 )
@@ -171,6 +173,67 @@ func (_c *GormDB_Model_Call) RunAndReturn(run func(interface{}) *gorm.DB) *GormD
 	return _c
 }
 
+// Transaction provides a mock function with given fields: fc, opts
+func (_m *GormDB) Transaction(fc func(*gorm.DB) error, opts ...*sql.TxOptions) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, fc)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(*gorm.DB) error, ...*sql.TxOptions) error); ok {
+		r0 = rf(fc, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GormDB_Transaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transaction'
+type GormDB_Transaction_Call struct {
+	*mock.Call
+}
+
+// Transaction is a helper method to define mock.On call
+//   - fc func(*gorm.DB) error
+//   - opts ...*sql.TxOptions
+func (_e *GormDB_Expecter) Transaction(fc interface{}, opts ...interface{}) *GormDB_Transaction_Call {
+	return &GormDB_Transaction_Call{Call: _e.mock.On("Transaction",
+		append([]interface{}{fc}, opts...)...)}
+}
+
+func (_c *GormDB_Transaction_Call) Run(run func(fc func(*gorm.DB) error, opts ...*sql.TxOptions)) *GormDB_Transaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]*sql.TxOptions, len(args)-1)
+		for i, a := range args[1:] {
+			if a != nil {
+				variadicArgs[i] = a.(*sql.TxOptions)
+			}
+		}
+		run(args[0].(func(*gorm.DB) error), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *GormDB_Transaction_Call) Return(_a0 error) *GormDB_Transaction_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *GormDB_Transaction_Call) RunAndReturn(run func(func(*gorm.DB) error, ...*sql.TxOptions) error) *GormDB_Transaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewGormDB creates a new instance of GormDB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewGormDB(t interface {
@@ -0,0 +1,143 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mockevents
+
+import (
+	context "context"
+	events "simple-file-processor/internal/events"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Stream is an autogenerated mock type for the Stream type
+type Stream struct {
+	mock.Mock
+}
+
+type Stream_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Stream) EXPECT() *Stream_Expecter {
+	return &Stream_Expecter{mock: &_m.Mock}
+}
+
+// Publish provides a mock function with given fields: ctx, e
+func (_m *Stream) Publish(ctx context.Context, e events.Event) error {
+	ret := _m.Called(ctx, e)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Publish")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, events.Event) error); ok {
+		r0 = rf(ctx, e)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Stream_Publish_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Publish'
+type Stream_Publish_Call struct {
+	*mock.Call
+}
+
+// Publish is a helper method to define mock.On call
+//   - ctx context.Context
+//   - e events.Event
+func (_e *Stream_Expecter) Publish(ctx interface{}, e interface{}) *Stream_Publish_Call {
+	return &Stream_Publish_Call{Call: _e.mock.On("Publish", ctx, e)}
+}
+
+func (_c *Stream_Publish_Call) Run(run func(ctx context.Context, e events.Event)) *Stream_Publish_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(events.Event))
+	})
+	return _c
+}
+
+func (_c *Stream_Publish_Call) Return(_a0 error) *Stream_Publish_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Stream_Publish_Call) RunAndReturn(run func(context.Context, events.Event) error) *Stream_Publish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Subscribe provides a mock function with given fields: ctx, fid
+func (_m *Stream) Subscribe(ctx context.Context, fid string) (<-chan events.Event, error) {
+	ret := _m.Called(ctx, fid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 <-chan events.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (<-chan events.Event, error)); ok {
+		return rf(ctx, fid)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) <-chan events.Event); ok {
+		r0 = rf(ctx, fid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan events.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stream_Subscribe_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Subscribe'
+type Stream_Subscribe_Call struct {
+	*mock.Call
+}
+
+// Subscribe is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fid string
+func (_e *Stream_Expecter) Subscribe(ctx interface{}, fid interface{}) *Stream_Subscribe_Call {
+	return &Stream_Subscribe_Call{Call: _e.mock.On("Subscribe", ctx, fid)}
+}
+
+func (_c *Stream_Subscribe_Call) Run(run func(ctx context.Context, fid string)) *Stream_Subscribe_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Stream_Subscribe_Call) Return(_a0 <-chan events.Event, _a1 error) *Stream_Subscribe_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Stream_Subscribe_Call) RunAndReturn(run func(context.Context, string) (<-chan events.Event, error)) *Stream_Subscribe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewStream creates a new instance of Stream. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStream(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Stream {
+	mock := &Stream{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -2,7 +2,11 @@
 
 package mocklib
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // CommandExecutor is an autogenerated mock type for the CommandExecutor type
 type CommandExecutor struct {
@@ -90,6 +94,80 @@ func (_c *CommandExecutor_Command_Call) RunAndReturn(run func(string, ...string)
 	return _c
 }
 
+// CommandContext provides a mock function with given fields: ctx, name, args
+func (_m *CommandExecutor) CommandContext(ctx context.Context, name string, args ...string) ([]byte, error) {
+	_va := make([]interface{}, len(args))
+	for _i := range args {
+		_va[_i] = args[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, name)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommandContext")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) ([]byte, error)); ok {
+		return rf(ctx, name, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) []byte); ok {
+		r0 = rf(ctx, name, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...string) error); ok {
+		r1 = rf(ctx, name, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommandExecutor_CommandContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CommandContext'
+type CommandExecutor_CommandContext_Call struct {
+	*mock.Call
+}
+
+// CommandContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - args ...string
+func (_e *CommandExecutor_Expecter) CommandContext(ctx interface{}, name interface{}, args ...interface{}) *CommandExecutor_CommandContext_Call {
+	return &CommandExecutor_CommandContext_Call{Call: _e.mock.On("CommandContext",
+		append([]interface{}{ctx, name}, args...)...)}
+}
+
+func (_c *CommandExecutor_CommandContext_Call) Run(run func(ctx context.Context, name string, args ...string)) *CommandExecutor_CommandContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *CommandExecutor_CommandContext_Call) Return(_a0 []byte, _a1 error) *CommandExecutor_CommandContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *CommandExecutor_CommandContext_Call) RunAndReturn(run func(context.Context, string, ...string) ([]byte, error)) *CommandExecutor_CommandContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewCommandExecutor creates a new instance of CommandExecutor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCommandExecutor(t interface {
@@ -3,6 +3,7 @@
 package mocklib
 
 import (
+	context "context"
 	lib "simple-file-processor/internal/lib"
 
 	mock "github.com/stretchr/testify/mock"
@@ -21,9 +22,9 @@ func (_m *MetadataExtractor) EXPECT() *MetadataExtractor_Expecter {
 	return &MetadataExtractor_Expecter{mock: &_m.Mock}
 }
 
-// ExtractVideoMetadata provides a mock function with given fields: path
-func (_m *MetadataExtractor) ExtractVideoMetadata(path string) (*lib.VideoMetadata, error) {
-	ret := _m.Called(path)
+// ExtractVideoMetadata provides a mock function with given fields: ctx, path
+func (_m *MetadataExtractor) ExtractVideoMetadata(ctx context.Context, path string) (*lib.VideoMetadata, error) {
+	ret := _m.Called(ctx, path)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ExtractVideoMetadata")
@@ -31,19 +32,19 @@ func (_m *MetadataExtractor) ExtractVideoMetadata(path string) (*lib.VideoMetada
 
 	var r0 *lib.VideoMetadata
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (*lib.VideoMetadata, error)); ok {
-		return rf(path)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*lib.VideoMetadata, error)); ok {
+		return rf(ctx, path)
 	}
-	if rf, ok := ret.Get(0).(func(string) *lib.VideoMetadata); ok {
-		r0 = rf(path)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *lib.VideoMetadata); ok {
+		r0 = rf(ctx, path)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*lib.VideoMetadata)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(path)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, path)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -57,14 +58,15 @@ type MetadataExtractor_ExtractVideoMetadata_Call struct {
 }
 
 // ExtractVideoMetadata is a helper method to define mock.On call
+//   - ctx context.Context
 //   - path string
-func (_e *MetadataExtractor_Expecter) ExtractVideoMetadata(path interface{}) *MetadataExtractor_ExtractVideoMetadata_Call {
-	return &MetadataExtractor_ExtractVideoMetadata_Call{Call: _e.mock.On("ExtractVideoMetadata", path)}
+func (_e *MetadataExtractor_Expecter) ExtractVideoMetadata(ctx interface{}, path interface{}) *MetadataExtractor_ExtractVideoMetadata_Call {
+	return &MetadataExtractor_ExtractVideoMetadata_Call{Call: _e.mock.On("ExtractVideoMetadata", ctx, path)}
 }
 
-func (_c *MetadataExtractor_ExtractVideoMetadata_Call) Run(run func(path string)) *MetadataExtractor_ExtractVideoMetadata_Call {
+func (_c *MetadataExtractor_ExtractVideoMetadata_Call) Run(run func(ctx context.Context, path string)) *MetadataExtractor_ExtractVideoMetadata_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -74,7 +76,7 @@ func (_c *MetadataExtractor_ExtractVideoMetadata_Call) Return(_a0 *lib.VideoMeta
 	return _c
 }
 
-func (_c *MetadataExtractor_ExtractVideoMetadata_Call) RunAndReturn(run func(string) (*lib.VideoMetadata, error)) *MetadataExtractor_ExtractVideoMetadata_Call {
+func (_c *MetadataExtractor_ExtractVideoMetadata_Call) RunAndReturn(run func(context.Context, string) (*lib.VideoMetadata, error)) *MetadataExtractor_ExtractVideoMetadata_Call {
 	_c.Call.Return(run)
 	return _c
 }
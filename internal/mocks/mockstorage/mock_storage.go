@@ -0,0 +1,381 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mockstorage
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+
+	storage "simple-file-processor/internal/storage"
+
+	time "time"
+)
+
+// Storage is an autogenerated mock type for the Storage type
+type Storage struct {
+	mock.Mock
+}
+
+type Storage_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Storage) EXPECT() *Storage_Expecter {
+	return &Storage_Expecter{mock: &_m.Mock}
+}
+
+// Delete provides a mock function with given fields: ctx, key
+func (_m *Storage) Delete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Storage_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type Storage_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Storage_Expecter) Delete(ctx interface{}, key interface{}) *Storage_Delete_Call {
+	return &Storage_Delete_Call{Call: _e.mock.On("Delete", ctx, key)}
+}
+
+func (_c *Storage_Delete_Call) Run(run func(ctx context.Context, key string)) *Storage_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_Delete_Call) Return(_a0 error) *Storage_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Storage_Delete_Call) RunAndReturn(run func(context.Context, string) error) *Storage_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *Storage) Get(ctx context.Context, key string) (storage.Object, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 storage.Object
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (storage.Object, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) storage.Object); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.Object)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type Storage_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Storage_Expecter) Get(ctx interface{}, key interface{}) *Storage_Get_Call {
+	return &Storage_Get_Call{Call: _e.mock.On("Get", ctx, key)}
+}
+
+func (_c *Storage_Get_Call) Run(run func(ctx context.Context, key string)) *Storage_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_Get_Call) Return(_a0 storage.Object, _a1 error) *Storage_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_Get_Call) RunAndReturn(run func(context.Context, string) (storage.Object, error)) *Storage_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx, prefix
+func (_m *Storage) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	ret := _m.Called(ctx, prefix)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []storage.ObjectInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]storage.ObjectInfo, error)); ok {
+		return rf(ctx, prefix)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []storage.ObjectInfo); ok {
+		r0 = rf(ctx, prefix)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]storage.ObjectInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prefix)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type Storage_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - prefix string
+func (_e *Storage_Expecter) List(ctx interface{}, prefix interface{}) *Storage_List_Call {
+	return &Storage_List_Call{Call: _e.mock.On("List", ctx, prefix)}
+}
+
+func (_c *Storage_List_Call) Run(run func(ctx context.Context, prefix string)) *Storage_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_List_Call) Return(_a0 []storage.ObjectInfo, _a1 error) *Storage_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_List_Call) RunAndReturn(run func(context.Context, string) ([]storage.ObjectInfo, error)) *Storage_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PresignGet provides a mock function with given fields: ctx, key, expiry
+func (_m *Storage) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	ret := _m.Called(ctx, key, expiry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PresignGet")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) (string, error)); ok {
+		return rf(ctx, key, expiry)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) string); ok {
+		r0 = rf(ctx, key, expiry)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
+		r1 = rf(ctx, key, expiry)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_PresignGet_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PresignGet'
+type Storage_PresignGet_Call struct {
+	*mock.Call
+}
+
+// PresignGet is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - expiry time.Duration
+func (_e *Storage_Expecter) PresignGet(ctx interface{}, key interface{}, expiry interface{}) *Storage_PresignGet_Call {
+	return &Storage_PresignGet_Call{Call: _e.mock.On("PresignGet", ctx, key, expiry)}
+}
+
+func (_c *Storage_PresignGet_Call) Run(run func(ctx context.Context, key string, expiry time.Duration)) *Storage_PresignGet_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Storage_PresignGet_Call) Return(_a0 string, _a1 error) *Storage_PresignGet_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_PresignGet_Call) RunAndReturn(run func(context.Context, string, time.Duration) (string, error)) *Storage_PresignGet_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Put provides a mock function with given fields: ctx, key, r, size, contentType
+func (_m *Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (storage.ObjectInfo, error) {
+	ret := _m.Called(ctx, key, r, size, contentType)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Put")
+	}
+
+	var r0 storage.ObjectInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader, int64, string) (storage.ObjectInfo, error)); ok {
+		return rf(ctx, key, r, size, contentType)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader, int64, string) storage.ObjectInfo); ok {
+		r0 = rf(ctx, key, r, size, contentType)
+	} else {
+		r0 = ret.Get(0).(storage.ObjectInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, io.Reader, int64, string) error); ok {
+		r1 = rf(ctx, key, r, size, contentType)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_Put_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Put'
+type Storage_Put_Call struct {
+	*mock.Call
+}
+
+// Put is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - r io.Reader
+//   - size int64
+//   - contentType string
+func (_e *Storage_Expecter) Put(ctx interface{}, key interface{}, r interface{}, size interface{}, contentType interface{}) *Storage_Put_Call {
+	return &Storage_Put_Call{Call: _e.mock.On("Put", ctx, key, r, size, contentType)}
+}
+
+func (_c *Storage_Put_Call) Run(run func(ctx context.Context, key string, r io.Reader, size int64, contentType string)) *Storage_Put_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(io.Reader), args[3].(int64), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_Put_Call) Return(_a0 storage.ObjectInfo, _a1 error) *Storage_Put_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_Put_Call) RunAndReturn(run func(context.Context, string, io.Reader, int64, string) (storage.ObjectInfo, error)) *Storage_Put_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Stat provides a mock function with given fields: ctx, key
+func (_m *Storage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Stat")
+	}
+
+	var r0 storage.ObjectInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (storage.ObjectInfo, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) storage.ObjectInfo); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(storage.ObjectInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Storage_Stat_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stat'
+type Storage_Stat_Call struct {
+	*mock.Call
+}
+
+// Stat is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Storage_Expecter) Stat(ctx interface{}, key interface{}) *Storage_Stat_Call {
+	return &Storage_Stat_Call{Call: _e.mock.On("Stat", ctx, key)}
+}
+
+func (_c *Storage_Stat_Call) Run(run func(ctx context.Context, key string)) *Storage_Stat_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Storage_Stat_Call) Return(_a0 storage.ObjectInfo, _a1 error) *Storage_Stat_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Storage_Stat_Call) RunAndReturn(run func(context.Context, string) (storage.ObjectInfo, error)) *Storage_Stat_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewStorage creates a new instance of Storage. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStorage(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Storage {
+	mock := &Storage{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
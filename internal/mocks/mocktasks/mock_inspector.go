@@ -0,0 +1,292 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mocktasks
+
+import (
+	tasks "simple-file-processor/internal/tasks"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Inspector is an autogenerated mock type for the Inspector type
+type Inspector struct {
+	mock.Mock
+}
+
+type Inspector_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Inspector) EXPECT() *Inspector_Expecter {
+	return &Inspector_Expecter{mock: &_m.Mock}
+}
+
+// Close provides a mock function with no fields
+func (_m *Inspector) Close() error {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Inspector_Close_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Close'
+type Inspector_Close_Call struct {
+	*mock.Call
+}
+
+// Close is a helper method to define mock.On call
+func (_e *Inspector_Expecter) Close() *Inspector_Close_Call {
+	return &Inspector_Close_Call{Call: _e.mock.On("Close")}
+}
+
+func (_c *Inspector_Close_Call) Run(run func()) *Inspector_Close_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Inspector_Close_Call) Return(_a0 error) *Inspector_Close_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Inspector_Close_Call) RunAndReturn(run func() error) *Inspector_Close_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: queue, id
+func (_m *Inspector) Delete(queue string, id string) error {
+	ret := _m.Called(queue, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(queue, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Inspector_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type Inspector_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - queue string
+//   - id string
+func (_e *Inspector_Expecter) Delete(queue interface{}, id interface{}) *Inspector_Delete_Call {
+	return &Inspector_Delete_Call{Call: _e.mock.On("Delete", queue, id)}
+}
+
+func (_c *Inspector_Delete_Call) Run(run func(queue string, id string)) *Inspector_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Inspector_Delete_Call) Return(_a0 error) *Inspector_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Inspector_Delete_Call) RunAndReturn(run func(string, string) error) *Inspector_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailedTask provides a mock function with given fields: queue, id
+func (_m *Inspector) FailedTask(queue string, id string) (*tasks.FailedTask, error) {
+	ret := _m.Called(queue, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailedTask")
+	}
+
+	var r0 *tasks.FailedTask
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (*tasks.FailedTask, error)); ok {
+		return rf(queue, id)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) *tasks.FailedTask); ok {
+		r0 = rf(queue, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tasks.FailedTask)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(queue, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Inspector_FailedTask_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailedTask'
+type Inspector_FailedTask_Call struct {
+	*mock.Call
+}
+
+// FailedTask is a helper method to define mock.On call
+//   - queue string
+//   - id string
+func (_e *Inspector_Expecter) FailedTask(queue interface{}, id interface{}) *Inspector_FailedTask_Call {
+	return &Inspector_FailedTask_Call{Call: _e.mock.On("FailedTask", queue, id)}
+}
+
+func (_c *Inspector_FailedTask_Call) Run(run func(queue string, id string)) *Inspector_FailedTask_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Inspector_FailedTask_Call) Return(_a0 *tasks.FailedTask, _a1 error) *Inspector_FailedTask_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Inspector_FailedTask_Call) RunAndReturn(run func(string, string) (*tasks.FailedTask, error)) *Inspector_FailedTask_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FailedTasks provides a mock function with given fields: f
+func (_m *Inspector) FailedTasks(f tasks.FailedTaskFilter) ([]tasks.FailedTask, error) {
+	ret := _m.Called(f)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailedTasks")
+	}
+
+	var r0 []tasks.FailedTask
+	var r1 error
+	if rf, ok := ret.Get(0).(func(tasks.FailedTaskFilter) ([]tasks.FailedTask, error)); ok {
+		return rf(f)
+	}
+	if rf, ok := ret.Get(0).(func(tasks.FailedTaskFilter) []tasks.FailedTask); ok {
+		r0 = rf(f)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]tasks.FailedTask)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(tasks.FailedTaskFilter) error); ok {
+		r1 = rf(f)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Inspector_FailedTasks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FailedTasks'
+type Inspector_FailedTasks_Call struct {
+	*mock.Call
+}
+
+// FailedTasks is a helper method to define mock.On call
+//   - f tasks.FailedTaskFilter
+func (_e *Inspector_Expecter) FailedTasks(f interface{}) *Inspector_FailedTasks_Call {
+	return &Inspector_FailedTasks_Call{Call: _e.mock.On("FailedTasks", f)}
+}
+
+func (_c *Inspector_FailedTasks_Call) Run(run func(f tasks.FailedTaskFilter)) *Inspector_FailedTasks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(tasks.FailedTaskFilter))
+	})
+	return _c
+}
+
+func (_c *Inspector_FailedTasks_Call) Return(_a0 []tasks.FailedTask, _a1 error) *Inspector_FailedTasks_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Inspector_FailedTasks_Call) RunAndReturn(run func(tasks.FailedTaskFilter) ([]tasks.FailedTask, error)) *Inspector_FailedTasks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Replay provides a mock function with given fields: queue, id
+func (_m *Inspector) Replay(queue string, id string) error {
+	ret := _m.Called(queue, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Replay")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(queue, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Inspector_Replay_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Replay'
+type Inspector_Replay_Call struct {
+	*mock.Call
+}
+
+// Replay is a helper method to define mock.On call
+//   - queue string
+//   - id string
+func (_e *Inspector_Expecter) Replay(queue interface{}, id interface{}) *Inspector_Replay_Call {
+	return &Inspector_Replay_Call{Call: _e.mock.On("Replay", queue, id)}
+}
+
+func (_c *Inspector_Replay_Call) Run(run func(queue string, id string)) *Inspector_Replay_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Inspector_Replay_Call) Return(_a0 error) *Inspector_Replay_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Inspector_Replay_Call) RunAndReturn(run func(string, string) error) *Inspector_Replay_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewInspector creates a new instance of Inspector. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewInspector(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Inspector {
+	mock := &Inspector{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
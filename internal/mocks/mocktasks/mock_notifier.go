@@ -0,0 +1,72 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mocktasks
+
+import (
+	events "simple-file-processor/internal/events"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "simple-file-processor/internal/models"
+)
+
+// Notifier is an autogenerated mock type for the Notifier type
+type Notifier struct {
+	mock.Mock
+}
+
+type Notifier_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Notifier) EXPECT() *Notifier_Expecter {
+	return &Notifier_Expecter{mock: &_m.Mock}
+}
+
+// Notify provides a mock function with given fields: e, f
+func (_m *Notifier) Notify(e events.Event, f *models.File) {
+	_m.Called(e, f)
+}
+
+// Notifier_Notify_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Notify'
+type Notifier_Notify_Call struct {
+	*mock.Call
+}
+
+// Notify is a helper method to define mock.On call
+//   - e events.Event
+//   - f *models.File
+func (_e *Notifier_Expecter) Notify(e interface{}, f interface{}) *Notifier_Notify_Call {
+	return &Notifier_Notify_Call{Call: _e.mock.On("Notify", e, f)}
+}
+
+func (_c *Notifier_Notify_Call) Run(run func(e events.Event, f *models.File)) *Notifier_Notify_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(events.Event), args[1].(*models.File))
+	})
+	return _c
+}
+
+func (_c *Notifier_Notify_Call) Return() *Notifier_Notify_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Notifier_Notify_Call) RunAndReturn(run func(events.Event, *models.File)) *Notifier_Notify_Call {
+	_c.Run(run)
+	return _c
+}
+
+// NewNotifier creates a new instance of Notifier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewNotifier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Notifier {
+	mock := &Notifier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
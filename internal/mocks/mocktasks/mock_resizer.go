@@ -3,9 +3,12 @@
 package mocktasks
 
 import (
-	models "simple-file-processor/internal/models"
+	context "context"
+	lib "simple-file-processor/internal/lib"
 
 	mock "github.com/stretchr/testify/mock"
+
+	models "simple-file-processor/internal/models"
 )
 
 // Resizer is an autogenerated mock type for the Resizer type
@@ -21,9 +24,9 @@ func (_m *Resizer) EXPECT() *Resizer_Expecter {
 	return &Resizer_Expecter{mock: &_m.Mock}
 }
 
-// ResizeImage provides a mock function with given fields: sp, fn, w, h
-func (_m *Resizer) ResizeImage(sp string, fn string, w int, h int) (models.ProcessedOutput, error) {
-	ret := _m.Called(sp, fn, w, h)
+// ResizeImage provides a mock function with given fields: ctx, sp, fn, o
+func (_m *Resizer) ResizeImage(ctx context.Context, sp string, fn string, o lib.ResizeOptions) (models.ProcessedOutput, error) {
+	ret := _m.Called(ctx, sp, fn, o)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ResizeImage")
@@ -31,17 +34,17 @@ func (_m *Resizer) ResizeImage(sp string, fn string, w int, h int) (models.Proce
 
 	var r0 models.ProcessedOutput
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, int, int) (models.ProcessedOutput, error)); ok {
-		return rf(sp, fn, w, h)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.ResizeOptions) (models.ProcessedOutput, error)); ok {
+		return rf(ctx, sp, fn, o)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, int, int) models.ProcessedOutput); ok {
-		r0 = rf(sp, fn, w, h)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.ResizeOptions) models.ProcessedOutput); ok {
+		r0 = rf(ctx, sp, fn, o)
 	} else {
 		r0 = ret.Get(0).(models.ProcessedOutput)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, int, int) error); ok {
-		r1 = rf(sp, fn, w, h)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, lib.ResizeOptions) error); ok {
+		r1 = rf(ctx, sp, fn, o)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,17 +58,17 @@ type Resizer_ResizeImage_Call struct {
 }
 
 // ResizeImage is a helper method to define mock.On call
+//   - ctx context.Context
 //   - sp string
 //   - fn string
-//   - w int
-//   - h int
-func (_e *Resizer_Expecter) ResizeImage(sp interface{}, fn interface{}, w interface{}, h interface{}) *Resizer_ResizeImage_Call {
-	return &Resizer_ResizeImage_Call{Call: _e.mock.On("ResizeImage", sp, fn, w, h)}
+//   - o lib.ResizeOptions
+func (_e *Resizer_Expecter) ResizeImage(ctx interface{}, sp interface{}, fn interface{}, o interface{}) *Resizer_ResizeImage_Call {
+	return &Resizer_ResizeImage_Call{Call: _e.mock.On("ResizeImage", ctx, sp, fn, o)}
 }
 
-func (_c *Resizer_ResizeImage_Call) Run(run func(sp string, fn string, w int, h int)) *Resizer_ResizeImage_Call {
+func (_c *Resizer_ResizeImage_Call) Run(run func(ctx context.Context, sp string, fn string, o lib.ResizeOptions)) *Resizer_ResizeImage_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(int), args[3].(int))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(lib.ResizeOptions))
 	})
 	return _c
 }
@@ -75,7 +78,68 @@ func (_c *Resizer_ResizeImage_Call) Return(_a0 models.ProcessedOutput, _a1 error
 	return _c
 }
 
-func (_c *Resizer_ResizeImage_Call) RunAndReturn(run func(string, string, int, int) (models.ProcessedOutput, error)) *Resizer_ResizeImage_Call {
+func (_c *Resizer_ResizeImage_Call) RunAndReturn(run func(context.Context, string, string, lib.ResizeOptions) (models.ProcessedOutput, error)) *Resizer_ResizeImage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResizeVariants provides a mock function with given fields: ctx, sp, fn, opts
+func (_m *Resizer) ResizeVariants(ctx context.Context, sp string, fn string, opts []lib.ResizeOptions) ([]models.ProcessedOutput, error) {
+	ret := _m.Called(ctx, sp, fn, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResizeVariants")
+	}
+
+	var r0 []models.ProcessedOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []lib.ResizeOptions) ([]models.ProcessedOutput, error)); ok {
+		return rf(ctx, sp, fn, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []lib.ResizeOptions) []models.ProcessedOutput); ok {
+		r0 = rf(ctx, sp, fn, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProcessedOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []lib.ResizeOptions) error); ok {
+		r1 = rf(ctx, sp, fn, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Resizer_ResizeVariants_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResizeVariants'
+type Resizer_ResizeVariants_Call struct {
+	*mock.Call
+}
+
+// ResizeVariants is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sp string
+//   - fn string
+//   - opts []lib.ResizeOptions
+func (_e *Resizer_Expecter) ResizeVariants(ctx interface{}, sp interface{}, fn interface{}, opts interface{}) *Resizer_ResizeVariants_Call {
+	return &Resizer_ResizeVariants_Call{Call: _e.mock.On("ResizeVariants", ctx, sp, fn, opts)}
+}
+
+func (_c *Resizer_ResizeVariants_Call) Run(run func(ctx context.Context, sp string, fn string, opts []lib.ResizeOptions)) *Resizer_ResizeVariants_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].([]lib.ResizeOptions))
+	})
+	return _c
+}
+
+func (_c *Resizer_ResizeVariants_Call) Return(_a0 []models.ProcessedOutput, _a1 error) *Resizer_ResizeVariants_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Resizer_ResizeVariants_Call) RunAndReturn(run func(context.Context, string, string, []lib.ResizeOptions) ([]models.ProcessedOutput, error)) *Resizer_ResizeVariants_Call {
 	_c.Call.Return(run)
 	return _c
 }
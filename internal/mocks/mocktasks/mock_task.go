@@ -2,7 +2,12 @@
 
 package mocktasks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	asynq "github.com/hibiken/asynq"
+	mock "github.com/stretchr/testify/mock"
+
+	tasks "simple-file-processor/internal/tasks"
+)
 
 // Task is an autogenerated mock type for the Task type
 type Task struct {
@@ -18,21 +23,33 @@ func (_m *Task) EXPECT() *Task_Expecter {
 }
 
 // Enqueue provides a mock function with no fields
-func (_m *Task) Enqueue() error {
+func (_m *Task) Enqueue() (*asynq.TaskInfo, error) {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
 		panic("no return value specified for Enqueue")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func() error); ok {
+	var r0 *asynq.TaskInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (*asynq.TaskInfo, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() *asynq.TaskInfo); ok {
 		r0 = rf()
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*asynq.TaskInfo)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // Task_Enqueue_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Enqueue'
@@ -52,12 +69,196 @@ func (_c *Task_Enqueue_Call) Run(run func()) *Task_Enqueue_Call {
 	return _c
 }
 
-func (_c *Task_Enqueue_Call) Return(_a0 error) *Task_Enqueue_Call {
+func (_c *Task_Enqueue_Call) Return(_a0 *asynq.TaskInfo, _a1 error) *Task_Enqueue_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Task_Enqueue_Call) RunAndReturn(run func() (*asynq.TaskInfo, error)) *Task_Enqueue_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ID provides a mock function with no fields
+func (_m *Task) ID() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ID")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// Task_ID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ID'
+type Task_ID_Call struct {
+	*mock.Call
+}
+
+// ID is a helper method to define mock.On call
+func (_e *Task_Expecter) ID() *Task_ID_Call {
+	return &Task_ID_Call{Call: _e.mock.On("ID")}
+}
+
+func (_c *Task_ID_Call) Run(run func()) *Task_ID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Task_ID_Call) Return(_a0 string) *Task_ID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Task_ID_Call) RunAndReturn(run func() string) *Task_ID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Payload provides a mock function with no fields
+func (_m *Task) Payload() []byte {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Payload")
+	}
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func() []byte); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	return r0
+}
+
+// Task_Payload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Payload'
+type Task_Payload_Call struct {
+	*mock.Call
+}
+
+// Payload is a helper method to define mock.On call
+func (_e *Task_Expecter) Payload() *Task_Payload_Call {
+	return &Task_Payload_Call{Call: _e.mock.On("Payload")}
+}
+
+func (_c *Task_Payload_Call) Run(run func()) *Task_Payload_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Task_Payload_Call) Return(_a0 []byte) *Task_Payload_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Task_Payload_Call) RunAndReturn(run func() []byte) *Task_Payload_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Type provides a mock function with no fields
+func (_m *Task) Type() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Type")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// Task_Type_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Type'
+type Task_Type_Call struct {
+	*mock.Call
+}
+
+// Type is a helper method to define mock.On call
+func (_e *Task_Expecter) Type() *Task_Type_Call {
+	return &Task_Type_Call{Call: _e.mock.On("Type")}
+}
+
+func (_c *Task_Type_Call) Run(run func()) *Task_Type_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Task_Type_Call) Return(_a0 string) *Task_Type_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Task_Type_Call) RunAndReturn(run func() string) *Task_Type_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Unlocked provides a mock function with no fields
+func (_m *Task) Unlocked() tasks.Task {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unlocked")
+	}
+
+	var r0 tasks.Task
+	if rf, ok := ret.Get(0).(func() tasks.Task); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(tasks.Task)
+		}
+	}
+
+	return r0
+}
+
+// Task_Unlocked_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Unlocked'
+type Task_Unlocked_Call struct {
+	*mock.Call
+}
+
+// Unlocked is a helper method to define mock.On call
+func (_e *Task_Expecter) Unlocked() *Task_Unlocked_Call {
+	return &Task_Unlocked_Call{Call: _e.mock.On("Unlocked")}
+}
+
+func (_c *Task_Unlocked_Call) Run(run func()) *Task_Unlocked_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Task_Unlocked_Call) Return(_a0 tasks.Task) *Task_Unlocked_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Task_Enqueue_Call) RunAndReturn(run func() error) *Task_Enqueue_Call {
+func (_c *Task_Unlocked_Call) RunAndReturn(run func() tasks.Task) *Task_Unlocked_Call {
 	_c.Call.Return(run)
 	return _c
 }
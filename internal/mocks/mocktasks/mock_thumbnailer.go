@@ -0,0 +1,159 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mocktasks
+
+import (
+	context "context"
+	lib "simple-file-processor/internal/lib"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "simple-file-processor/internal/models"
+)
+
+// Thumbnailer is an autogenerated mock type for the Thumbnailer type
+type Thumbnailer struct {
+	mock.Mock
+}
+
+type Thumbnailer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Thumbnailer) EXPECT() *Thumbnailer_Expecter {
+	return &Thumbnailer_Expecter{mock: &_m.Mock}
+}
+
+// Poster provides a mock function with given fields: ctx, sp, fn, o
+func (_m *Thumbnailer) Poster(ctx context.Context, sp string, fn string, o lib.PosterOptions) (models.ProcessedOutput, error) {
+	ret := _m.Called(ctx, sp, fn, o)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Poster")
+	}
+
+	var r0 models.ProcessedOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.PosterOptions) (models.ProcessedOutput, error)); ok {
+		return rf(ctx, sp, fn, o)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.PosterOptions) models.ProcessedOutput); ok {
+		r0 = rf(ctx, sp, fn, o)
+	} else {
+		r0 = ret.Get(0).(models.ProcessedOutput)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, lib.PosterOptions) error); ok {
+		r1 = rf(ctx, sp, fn, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Thumbnailer_Poster_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Poster'
+type Thumbnailer_Poster_Call struct {
+	*mock.Call
+}
+
+// Poster is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sp string
+//   - fn string
+//   - o lib.PosterOptions
+func (_e *Thumbnailer_Expecter) Poster(ctx interface{}, sp interface{}, fn interface{}, o interface{}) *Thumbnailer_Poster_Call {
+	return &Thumbnailer_Poster_Call{Call: _e.mock.On("Poster", ctx, sp, fn, o)}
+}
+
+func (_c *Thumbnailer_Poster_Call) Run(run func(ctx context.Context, sp string, fn string, o lib.PosterOptions)) *Thumbnailer_Poster_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(lib.PosterOptions))
+	})
+	return _c
+}
+
+func (_c *Thumbnailer_Poster_Call) Return(_a0 models.ProcessedOutput, _a1 error) *Thumbnailer_Poster_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Thumbnailer_Poster_Call) RunAndReturn(run func(context.Context, string, string, lib.PosterOptions) (models.ProcessedOutput, error)) *Thumbnailer_Poster_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Sprite provides a mock function with given fields: ctx, sp, fn, o
+func (_m *Thumbnailer) Sprite(ctx context.Context, sp string, fn string, o lib.SpriteOptions) ([]models.ProcessedOutput, error) {
+	ret := _m.Called(ctx, sp, fn, o)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Sprite")
+	}
+
+	var r0 []models.ProcessedOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.SpriteOptions) ([]models.ProcessedOutput, error)); ok {
+		return rf(ctx, sp, fn, o)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, lib.SpriteOptions) []models.ProcessedOutput); ok {
+		r0 = rf(ctx, sp, fn, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProcessedOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, lib.SpriteOptions) error); ok {
+		r1 = rf(ctx, sp, fn, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Thumbnailer_Sprite_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Sprite'
+type Thumbnailer_Sprite_Call struct {
+	*mock.Call
+}
+
+// Sprite is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sp string
+//   - fn string
+//   - o lib.SpriteOptions
+func (_e *Thumbnailer_Expecter) Sprite(ctx interface{}, sp interface{}, fn interface{}, o interface{}) *Thumbnailer_Sprite_Call {
+	return &Thumbnailer_Sprite_Call{Call: _e.mock.On("Sprite", ctx, sp, fn, o)}
+}
+
+func (_c *Thumbnailer_Sprite_Call) Run(run func(ctx context.Context, sp string, fn string, o lib.SpriteOptions)) *Thumbnailer_Sprite_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(lib.SpriteOptions))
+	})
+	return _c
+}
+
+func (_c *Thumbnailer_Sprite_Call) Return(_a0 []models.ProcessedOutput, _a1 error) *Thumbnailer_Sprite_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Thumbnailer_Sprite_Call) RunAndReturn(run func(context.Context, string, string, lib.SpriteOptions) ([]models.ProcessedOutput, error)) *Thumbnailer_Sprite_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewThumbnailer creates a new instance of Thumbnailer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewThumbnailer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Thumbnailer {
+	mock := &Thumbnailer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,100 @@
+// Code generated by mockery v2.52.2. DO NOT EDIT.
+
+package mocktasks
+
+import (
+	context "context"
+	lib "simple-file-processor/internal/lib"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "simple-file-processor/internal/models"
+)
+
+// Transcoder is an autogenerated mock type for the Transcoder type
+type Transcoder struct {
+	mock.Mock
+}
+
+type Transcoder_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Transcoder) EXPECT() *Transcoder_Expecter {
+	return &Transcoder_Expecter{mock: &_m.Mock}
+}
+
+// Transcode provides a mock function with given fields: ctx, sp, fn, profiles
+func (_m *Transcoder) Transcode(ctx context.Context, sp string, fn string, profiles []lib.TranscodeProfile) ([]models.ProcessedOutput, error) {
+	ret := _m.Called(ctx, sp, fn, profiles)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transcode")
+	}
+
+	var r0 []models.ProcessedOutput
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []lib.TranscodeProfile) ([]models.ProcessedOutput, error)); ok {
+		return rf(ctx, sp, fn, profiles)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []lib.TranscodeProfile) []models.ProcessedOutput); ok {
+		r0 = rf(ctx, sp, fn, profiles)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ProcessedOutput)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []lib.TranscodeProfile) error); ok {
+		r1 = rf(ctx, sp, fn, profiles)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transcoder_Transcode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transcode'
+type Transcoder_Transcode_Call struct {
+	*mock.Call
+}
+
+// Transcode is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sp string
+//   - fn string
+//   - profiles []lib.TranscodeProfile
+func (_e *Transcoder_Expecter) Transcode(ctx interface{}, sp interface{}, fn interface{}, profiles interface{}) *Transcoder_Transcode_Call {
+	return &Transcoder_Transcode_Call{Call: _e.mock.On("Transcode", ctx, sp, fn, profiles)}
+}
+
+func (_c *Transcoder_Transcode_Call) Run(run func(ctx context.Context, sp string, fn string, profiles []lib.TranscodeProfile)) *Transcoder_Transcode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].([]lib.TranscodeProfile))
+	})
+	return _c
+}
+
+func (_c *Transcoder_Transcode_Call) Return(_a0 []models.ProcessedOutput, _a1 error) *Transcoder_Transcode_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Transcoder_Transcode_Call) RunAndReturn(run func(context.Context, string, string, []lib.TranscodeProfile) ([]models.ProcessedOutput, error)) *Transcoder_Transcode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewTranscoder creates a new instance of Transcoder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTranscoder(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Transcoder {
+	mock := &Transcoder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,29 +1,29 @@
 package models
 
 import (
-	"slices"
-	"strings"
+	"simple-file-processor/internal/media"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-var (
-	imageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/jpg"} // Supported image types
-	videoTypes = []string{"video/mp4", "video/avi", "video/mkv", "video/mov"}  // Supported video types
-)
-
 type File struct {
 	ID                string            `gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	Checksum          string            `json:"checksum"`                            // e.g. hex encoded sha256 of the file content
+	ExtensionMismatch bool              `json:"extension_mismatch"`                  // e.g. true when the content does not match the uploaded extension
 	GeneratedName     string            `json:"generated_name"`                      // e.g. file name without extension
 	MimeType          string            `json:"mime_type"`                           // e.g. file mime type
 	ProcessedOutputs  []ProcessedOutput `json:"processed_outputs" gorm:"type:jsonb"` // e.g. processed outputs of the file, storing as jsonb
 	OriginalName      string            `json:"original_name"`                       // e.g. file name with extension
 	Size              int64             `json:"size"`                                // e.g. file size in bytes
 	Status            string            `json:"status" gorm:"default:'pending'"`     // e.g. pending, processing, completed, failed
+	StatusChangedAt   *time.Time        `json:"status_changed_at,omitempty"`         // e.g. when the status last changed, empty while it is the initial status
 	StoragePath       string            `json:"storage_path"`                        // e.g. path where the file is stored
 	Type              string            `json:"type"`                                // e.g. image, video, document, other, etc.
 	UploadedExtension string            `json:"uploaded_extension"`                  // e.g. file extension
+	UploadLength      int64             `json:"upload_length,omitempty"`             // e.g. the announced size of a resumable upload in bytes
+	UploadOffset      int64             `json:"upload_offset,omitempty"`             // e.g. the number of bytes of a resumable upload received so far
+	WebhookURL        string            `json:"webhook_url,omitempty"`               // e.g. the webhook that receives the events of the file
 	CreatedAt         time.Time         `json:"created_at" gorm:"autoCreateTime"`    // e.g. file created at
 	UpdatedAt         time.Time         `json:"updated_at" gorm:"autoUpdateTime"`    // e.g. file updated at
 }
@@ -32,26 +32,50 @@ type File struct {
 func (f *File) BeforeCreate(tx *gorm.DB) (err error) {
 	// Set the type based on the mime type
 	if f.Type == "" {
-		if slices.Contains(imageTypes, f.MimeType) {
-			f.Type = "image"
-		} else if slices.Contains(videoTypes, f.MimeType) {
-			f.Type = "video"
-		} else {
-			f.Type = "other"
-		}
+		f.Type = FileType(f.MimeType)
 	}
 
 	return nil
 }
 
+// FileType returns the type of a file with the given mime type e.g. image, video or other
+// The types are looked up in the media registry
+func FileType(mimeType string) string {
+	return media.Default().Category(mimeType)
+}
+
+// IsImage reports whether the content of the file is an image
+// The mime type is detected from the content, so the extension is not trusted
 func (f *File) IsImage() bool {
-	// Check if the file extension is valid
-	ext := strings.ToLower(f.UploadedExtension)
-	return ext == "jpg" || ext == "jpeg" || ext == "png" || ext == "gif"
+	return media.Default().Category(f.MimeType) == media.CategoryImage
 }
 
+// IsVideo reports whether the content of the file is a video
 func (f *File) IsVideo() bool {
-	// Check if the file extension is valid
-	ext := strings.ToLower(f.UploadedExtension)
-	return ext == "mp4" || ext == "avi" || ext == "mkv" || ext == "mov"
+	return media.Default().Category(f.MimeType) == media.CategoryVideo
+}
+
+// Supports reports whether the file may be run through the given processor e.g. resize
+func (f *File) Supports(processor string) bool {
+	return media.Default().Supports(f.MimeType, processor)
+}
+
+// FileFilter holds the optional filters used when listing files
+// Zero values are ignored so that an empty filter lists every file
+type FileFilter struct {
+	Type          string     // e.g. image, video, other
+	Status        string     // e.g. pending, processing, completed, failed
+	MimeType      string     // e.g. image/jpeg
+	CreatedAfter  *time.Time // only files created at or after this time
+	CreatedBefore *time.Time // only files created before this time
+	// only files whose status last changed before this time, e.g. uploads stuck in processing
+	StatusChangedBefore *time.Time
+	Cursor              string // the next cursor returned by a previous page
+	Limit               int    // the page size, the default page size of the database when zero
+}
+
+// FilePage is a single page of files ordered from newest to oldest
+type FilePage struct {
+	Files      []File `json:"files"`
+	NextCursor string `json:"next_cursor,omitempty"` // empty when there are no more files
 }
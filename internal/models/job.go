@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"    // The task is waiting in the queue
+	JobRunning   = "running"   // The task is being processed by a worker
+	JobRetrying  = "retrying"  // The last attempt failed and the task will be retried
+	JobCompleted = "completed" // The task was processed successfully
+	JobFailed    = "failed"    // The task failed and will not be retried
+)
+
+type Job struct {
+	ID          string          `json:"id" gorm:"type:uuid;primary_key"`        // e.g. the id of the asynq task
+	FileID      string          `json:"file_id" gorm:"type:uuid;index"`         // e.g. the file the task processes
+	TaskType    string          `json:"task_type"`                              // e.g. image:resize
+	Queue       string          `json:"queue"`                                  // e.g. the asynq queue the task was enqueued to
+	Payload     json.RawMessage `json:"payload" gorm:"type:jsonb"`              // e.g. the payload of the task
+	State       string          `json:"state" gorm:"default:'queued'"`          // e.g. queued, running, retrying, completed, failed
+	Attempts    int             `json:"attempts"`                               // e.g. the number of times the task has been run
+	MaxRetry    int             `json:"max_retry"`                              // e.g. the number of times a failed task is retried
+	Error       string          `json:"error,omitempty"`                        // e.g. the error of the last failed attempt
+	StartedAt   *time.Time      `json:"started_at,omitempty"`                   // e.g. when the last attempt started
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`                 // e.g. when the task completed or finally failed
+	CreatedAt   time.Time       `json:"created_at" gorm:"autoCreateTime;index"` // e.g. when the task was enqueued
+	UpdatedAt   time.Time       `json:"updated_at" gorm:"autoUpdateTime"`       // e.g. when the job was last updated
+}
@@ -3,18 +3,25 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const (
-	VideoMetadataType = "video_metadata" // The type of the processed output
-	ResizedImageType  = "resized_image"  // The type of the resized image
+	VideoMetadataType    = "video_metadata"     // The type of the processed output
+	ResizedImageType     = "resized_image"      // The type of the resized image
+	TranscodedVideoType  = "transcoded_video"   // The type of a rendition of a transcoded video
+	VideoPosterType      = "video_poster"       // The type of the poster frame of a video
+	VideoSpriteType      = "video_sprite"       // The type of a sprite sheet of frames of a video
+	VideoSpriteIndexType = "video_sprite_index" // The type of the WebVTT index of the frames of a sprite sheet
 )
 
 type ProcessedOutput struct {
 	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid()"` // The unique identifier of the processed output
+	BatchID     string    `json:"batch_id,omitempty"`                  // The batch of outputs that the processed output was created in
 	BitRate     string    `json:"bit_rate"`                            // The bit rate of the processed output
 	Codec       string    `json:"codec"`                               // The codec of the processed output
 	Duration    string    `json:"duration"`                            // The duration of the processed output
@@ -22,6 +29,7 @@ type ProcessedOutput struct {
 	Format      string    `json:"format"`                              // The format of the processed output
 	Height      int       `json:"height"`                              // The height of the processed output
 	Name        string    `json:"name"`                                // The name of the processed output
+	Preset      string    `json:"preset,omitempty"`                    // The name of the preset the processed output was created with
 	Resolution  string    `json:"resolution"`                          // The resolution of the processed output
 	Size        int64     `json:"size"`                                // The size of the processed output in bytes
 	StoragePath string    `json:"storage_path"`                        // The storage path of the processed output
@@ -44,3 +52,15 @@ func (po *ProcessedOutput) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(b, po)
 }
+
+// Filename returns the name of the processed output as it is stored on disk
+// Some outputs store their name without the extension, so the extension
+// is appended when the name does not already carry one
+func (po ProcessedOutput) Filename() string {
+	ext := strings.TrimPrefix(po.Extension, ".")
+	if filepath.Ext(po.Name) != "" || ext == "" {
+		return po.Name
+	}
+
+	return po.Name + "." + ext
+}
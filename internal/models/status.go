@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusUploading  = "uploading"  // The file is being uploaded in chunks
+	StatusPending    = "pending"    // The file is uploaded and waiting to be processed
+	StatusProcessing = "processing" // A task of the file is being processed
+	StatusCompleted  = "completed"  // Every task of the file was processed successfully
+	StatusFailed     = "failed"     // A task of the file failed and will not be retried
+)
+
+// ErrInvalidTransition is returned when a file cannot move from its status to another
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// The statuses that a file may move to from each status
+// Processed files move back to processing when another task of theirs starts
+var transitions = map[string][]string{
+	StatusUploading:  {StatusPending, StatusFailed},
+	StatusPending:    {StatusProcessing, StatusFailed},
+	StatusProcessing: {StatusCompleted, StatusFailed},
+	StatusCompleted:  {StatusProcessing},
+	StatusFailed:     {StatusProcessing},
+}
+
+// ValidateTransition verifies that a file may move from one status to another
+// Staying in the same status is always allowed
+func ValidateTransition(from string, to string) error {
+	if from == to {
+		return nil
+	}
+
+	if !slices.Contains(transitions[from], to) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	return nil
+}
+
+// StatusTransition records a change of the status of a file
+type StatusTransition struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	FileID    string    `json:"file_id" gorm:"type:uuid;index"`         // e.g. the file whose status changed
+	From      string    `json:"from" gorm:"column:from_status"`         // e.g. pending
+	To        string    `json:"to" gorm:"column:to_status"`             // e.g. processing
+	JobID     string    `json:"job_id,omitempty"`                       // e.g. the job that caused the transition
+	Reason    string    `json:"reason,omitempty"`                       // e.g. why the status changed
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"` // e.g. when the status changed
+}
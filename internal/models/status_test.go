@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"simple-file-processor/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		valid    bool
+	}{
+		{models.StatusUploading, models.StatusPending, true},
+		{models.StatusPending, models.StatusProcessing, true},
+		{models.StatusProcessing, models.StatusProcessing, true},
+		{models.StatusProcessing, models.StatusCompleted, true},
+		{models.StatusProcessing, models.StatusFailed, true},
+		{models.StatusCompleted, models.StatusProcessing, true},
+		{models.StatusFailed, models.StatusProcessing, true},
+		{models.StatusUploading, models.StatusProcessing, false},
+		{models.StatusPending, models.StatusCompleted, false},
+		{models.StatusFailed, models.StatusCompleted, false},
+		{models.StatusCompleted, models.StatusPending, false},
+		{"", models.StatusProcessing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			err := models.ValidateTransition(tt.from, tt.to)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, models.ErrInvalidTransition)
+			}
+		})
+	}
+}
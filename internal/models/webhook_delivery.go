@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// WebhookDelivery is a single attempt to post an event to a webhook
+type WebhookDelivery struct {
+	ID         string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	EventID    string    `json:"event_id" gorm:"index"`                  // e.g. the id of the delivered event, shared by its retries
+	EventType  string    `json:"event_type"`                             // e.g. output.created
+	FileID     string    `json:"file_id" gorm:"type:uuid;index"`         // e.g. the file the event happened to
+	URL        string    `json:"url"`                                    // e.g. the webhook the event was posted to
+	Attempt    int       `json:"attempt"`                                // e.g. 1 for the first attempt, 2 for the first retry
+	StatusCode int       `json:"status_code,omitempty"`                  // e.g. the status the webhook answered with, empty when it could not be reached
+	Error      string    `json:"error,omitempty"`                        // e.g. why the delivery failed
+	DurationMs int64     `json:"duration_ms"`                            // e.g. how long the webhook took to answer in milliseconds
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;index"` // e.g. when the attempt was made
+}
+
+// Succeeded reports whether the webhook accepted the delivery
+func (d WebhookDelivery) Succeeded() bool {
+	return d.Error == ""
+}
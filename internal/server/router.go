@@ -1,13 +1,20 @@
 package server
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"net/http"
 	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
 	"simple-file-processor/internal/handlers"
+	"simple-file-processor/internal/media"
+	"simple-file-processor/internal/storage"
 	"simple-file-processor/internal/tasks"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
@@ -21,36 +28,135 @@ type router struct {
 type Router interface {
 	InitRoutes()
 	Router() *mux.Router
+	Handlers() handlers.Handlers
 }
 
 // NewRouter initializes the router with the given configuration
-func NewRouter(c config.Config, log *zerolog.Logger, db db.Database) Router {
-	// Set up an async client to be used for async tasks
+// The async client and the inspector are shared with the rest of the server, which closes them on shutdown
+func NewRouter(c config.Config, log *zerolog.Logger, db db.Database, ac tasks.Client, in tasks.Inspector, st storage.Storage) Router {
 	// Initialize the router with the given configuration
 	// and return the router instance
 	return &router{
 		conf:     c,
 		log:      log,
 		router:   mux.NewRouter(),
-		handlers: handlers.NewHandlers(log, db, AsyncClient(c)),
+		handlers: handlers.NewHandlers(c, log, db, ac, st, EventStream(c, log), in),
 	}
 }
 
 // AsyncClient initializes the async client
 // This is used to send tasks to the async worker
+// A single client is created for the server and closed when it shuts down
 func AsyncClient(c config.Config) tasks.Client {
 	return tasks.NewAsyncClient(c.RedisAddress(), c.RedisDB())
 }
 
+// Inspector initializes the inspector of the queues
+// This is used to list, replay and delete the tasks that failed
+func Inspector(c config.Config) tasks.Inspector {
+	return tasks.NewInspector(c.RedisAddress(), c.RedisDB())
+}
+
+// EventStream initializes the stream of the events of files
+// The worker publishes to it and the API streams it to the clients subscribed to a file
+func EventStream(c config.Config, log *zerolog.Logger) events.Stream {
+	return events.NewRedisStream(redis.NewClient(&redis.Options{Addr: c.RedisAddress(), DB: c.RedisDB()}), log)
+}
+
+// Storage initializes the storage backend selected in the configuration
+// Uploads and processed outputs are read from and written to this storage
+func Storage(c config.Config, log *zerolog.Logger) (storage.Storage, error) {
+	switch c.StorageDriver() {
+	case storage.LocalDriver:
+		return storage.NewLocal(c.LocalStorageRoot(), log), nil
+	case storage.S3Driver:
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  c.S3Endpoint(),
+			Bucket:    c.S3Bucket(),
+			Region:    c.S3Region(),
+			AccessKey: c.S3AccessKey(),
+			SecretKey: c.S3SecretKey(),
+			UseSSL:    c.S3UseSSL(),
+		}, log)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", c.StorageDriver())
+	}
+}
+
+// MediaRegistry creates the registry of the media types supported in the configuration
+// Files are classified and matched to their processors through this registry
+func MediaRegistry(c config.Config) (media.Registry, error) {
+	return media.NewRegistry(c.MediaTypes())
+}
+
 // Initializes the routes for the server using the configuration
 func (r *router) InitRoutes() {
 	// Initialize routes here
 	r.log.Debug().Msg("Initializing routes")
 	rts := r.conf.GetRoutes()
+	token := r.conf.AdminToken()
 	for _, rt := range rts {
+		// Admin routes act on the tasks of every file, so they are left out rather than served unauthenticated
+		if rt.Admin && token == "" {
+			r.log.Warn().Msg("Admin route " + rt.Method + " " + rt.Path + " is not served, no admin token is configured")
+			continue
+		}
+
 		fmt.Println("Route: ", rt.Path, " Method: ", rt.Method)
-		r.router.HandleFunc(rt.Path, r.handlers.GetHandler(rt.Handler)).Methods(rt.Method)
+		h := withTimeout(r.handlers.GetHandler(rt.Handler), time.Duration(rt.Timeout)*time.Second)
+		if rt.Admin {
+			h = requireToken(h, token)
+		}
+		r.router.HandleFunc(rt.Path, limitBody(h, rt.MaxBodySize)).Methods(rt.Method)
+	}
+}
+
+// requireToken only passes requests that carry the token in a bearer Authorization header to the handler
+func requireToken(h http.HandlerFunc, token string) http.HandlerFunc {
+	expected := []byte("Bearer " + token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, `{"error": "A valid admin token is required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// limitBody caps the size of the request body that the handler may read
+// Reading past the limit fails with an *http.MaxBytesError
+func limitBody(h http.HandlerFunc, n int64) http.HandlerFunc {
+	if n <= 0 {
+		return h
 	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		h(w, r)
+	}
+}
+
+// withTimeout gives the handler the given time to read the request and write the response,
+// in place of the read and write timeouts of the server
+func withTimeout(h http.HandlerFunc, d time.Duration) http.HandlerFunc {
+	if d <= 0 {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		deadline := time.Now().Add(d)
+		rc.SetReadDeadline(deadline)
+		rc.SetWriteDeadline(deadline)
+		h(w, r)
+	}
+}
+
+// returns the handlers of the routes, which are drained when the server shuts down
+func (r *router) Handlers() handlers.Handlers {
+	return r.handlers
 }
 
 // returns the router to be used in the main function
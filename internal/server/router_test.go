@@ -1,10 +1,16 @@
 package server
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
 	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"strings"
 	"testing"
 
 	"github.com/rs/zerolog"
@@ -24,6 +30,78 @@ func TestNewRouter(t *testing.T) {
 	c := config.NewConfig()
 	l := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
 	db := db.NewDB(gdb, &l)
-	r := NewRouter(c, &l, db)
+	r := NewRouter(c, &l, db, new(mocktasks.Client), new(mocktasks.Inspector), new(mockstorage.Storage))
 	assert.NotNil(t, r)
 }
+
+// Verifies that the route's body limit is applied to the request body
+func TestLimitBody(t *testing.T) {
+	var readErr error
+	h := limitBody(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}, 4)
+
+	h(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("too long")))
+	var mbe *http.MaxBytesError
+	assert.ErrorAs(t, readErr, &mbe)
+
+	h(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("ok")))
+	assert.NoError(t, readErr)
+}
+
+// Verifies that the admin routes are only served with the admin token, and not registered without one
+func TestAdminRoutes(t *testing.T) {
+	routes := `"routes": [
+		{"path": "/health", "handler": "HealthCheckHandler", "method": "GET"},
+		{"path": "/admin/tasks", "handler": "FailedTasksHandler", "method": "GET", "admin": true}
+	]`
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expected      int
+	}{
+		{name: "no admin token configured", expected: http.StatusNotFound},
+		{name: "missing token", token: "s3cret", expected: http.StatusUnauthorized},
+		{name: "wrong token", token: "s3cret", authorization: "Bearer other", expected: http.StatusUnauthorized},
+		{name: "valid token", token: "s3cret", authorization: "Bearer s3cret", expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := config.FromJSON([]byte(`{"service": {"admin_token": "` + tt.token + `"}, ` + routes + `}`))
+			assert.NoError(t, err)
+			log := zerolog.Nop()
+			r := NewRouter(conf, &log, new(mockdb.Database), new(mocktasks.Client), new(mocktasks.Inspector), new(mockstorage.Storage))
+			r.InitRoutes()
+
+			// An invalid state is rejected by the handler before the queues are inspected
+			req := httptest.NewRequest("GET", "/admin/tasks?state=pending", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			r.Router().ServeHTTP(rec, req)
+			assert.Equal(t, tt.expected, rec.Code, rec.Body.String())
+
+			rec = httptest.NewRecorder()
+			r.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+			assert.Equal(t, http.StatusOK, rec.Code, "other routes are served without the token")
+		})
+	}
+}
+
+// Verifies that the media registry is created from the configured media types
+func TestMediaRegistry(t *testing.T) {
+	conf, _ := config.FromJSON([]byte(`{"media_types": [{"mime_type": "image/tiff", "extensions": ["tif"], "category": "image"}]}`))
+	r, err := MediaRegistry(conf)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/tiff", r.MimeTypeByExtension("tif"))
+	assert.Equal(t, "", r.MimeTypeByExtension("png"))
+
+	conf, _ = config.FromJSON([]byte(`{"media_types": [{"mime_type": "image/tiff"}]}`))
+	_, err = MediaRegistry(conf)
+	assert.Error(t, err)
+}
@@ -1,58 +1,202 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/media"
+	"simple-file-processor/internal/tasks"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// How long uploads are given to fail and remove what they wrote once their connections are closed
+const abandonTimeout = 5 * time.Second
+
+// Mode selects the parts of the service that a process runs
+// The API and the worker share the database, storage and Redis, so either can be scaled on its own
+type Mode string
+
+const (
+	ModeAPI    Mode = "api"    // Serves the API and enqueues tasks for the workers
+	ModeWorker Mode = "worker" // Processes the tasks of the queues
+	ModeAll    Mode = "all"    // Serves the API and processes the tasks in a single process
+)
+
+// ParseMode returns the mode with the given name
+func ParseMode(name string) (Mode, error) {
+	switch m := Mode(name); m {
+	case ModeAPI, ModeWorker, ModeAll:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q, must be one of %s, %s or %s", name, ModeAPI, ModeWorker, ModeAll)
+	}
+}
+
+// API returns whether the mode serves the API
+func (m Mode) API() bool {
+	return m == ModeAPI || m == ModeAll
+}
+
+// Worker returns whether the mode processes tasks
+func (m Mode) Worker() bool {
+	return m == ModeWorker || m == ModeAll
+}
+
 type server struct {
+	mode   Mode
 	conf   config.Config
-	router Router
+	router Router // Unset when the API is not served
 	log    zerolog.Logger
-	ws     WorkerServer
+	ws     WorkerServer // Unset when tasks are not processed
+	ac     tasks.Client
+	in     tasks.Inspector // Unset when the API is not served
 }
 
 type Server interface {
 	Start() error
 }
 
-func NewServer() Server {
+// NewServer creates the server that runs the parts of the service selected by the mode
+// The database schema is migrated by the processes that serve the API
+func NewServer(mode Mode) Server {
 	c := config.NewConfig()
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	l := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
+	l := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Str("mode", string(mode)).Logger()
 	gdb, err := gorm.Open(postgres.Open(c.ConnectionString()), &gorm.Config{})
 	if err != nil {
 		l.Fatal().Err(err).Msg("Failed to connect to database")
 		panic(err)
 	}
 
-	db := db.NewDB(gdb, &l)                                      // Initialize the database with the given configuration
-	r := NewRouter(c, &l, db)                                    // Initialize the router with the given configuration
-	db.Migrate()                                                 // Migrate the database schema
-	ws := NewWorkerServer(c.RedisAddress(), c.RedisDB(), db, &l) // Initialize the worker server with the given configuration
+	mr, err := MediaRegistry(c)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to load the media types")
+		panic(err)
+	}
+
+	media.SetDefault(mr) // Classify files by the configured media types
+
+	st, err := Storage(c, &l)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Failed to initialize storage")
+		panic(err)
+	}
+
+	db := db.NewDB(gdb, &l) // Initialize the database with the given configuration
+	ac := AsyncClient(c)    // A single async client is shared by the API and the worker
+	s := &server{
+		mode: mode,
+		conf: c,
+		log:  l,
+		ac:   ac,
+	}
+
+	if mode.API() {
+		s.in = Inspector(c)                           // Inspects the tasks that failed for the admin endpoints
+		s.router = NewRouter(c, &l, db, ac, s.in, st) // Initialize the router with the given configuration
+		db.Migrate()                                  // Migrate the database schema
+	}
 
-	// Initialize the server with the given configuration
-	return &server{
-		conf:   c,
-		router: r,
-		log:    l,
-		ws:     ws,
+	if mode.Worker() {
+		s.ws = NewWorkerServer(c, db, st, ac, &l) // Initialize the worker server with the given configuration
 	}
+
+	return s
 }
 
+// Start runs the parts of the service selected by its mode until an interrupt or termination signal is received
+// On shutdown the API stops first so that no task is enqueued after the async client is closed
 func (s *server) Start() error {
-	s.log.Info().Msg("Starting server on port " + strconv.Itoa(s.conf.Port()))
-	s.router.InitRoutes()
-	go func() {
-		s.ws.Start()
-	}()
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.conf.Port()), s.router.Router())
+	if s.ws != nil {
+		if err := s.ws.Start(); err != nil {
+			s.log.Error().Err(err).Msg("Failed to start worker")
+			return err
+		}
+	}
+
+	var srv *http.Server
+	errs := make(chan error, 1)
+	if s.router != nil {
+		s.log.Info().Msg("Starting server on port " + strconv.Itoa(s.conf.Port()))
+		s.router.InitRoutes()
+		srv = s.httpServer()
+		go func() {
+			errs <- srv.ListenAndServe()
+		}()
+	}
+
+	// Wait for a signal or for the server to fail
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	var err error
+	select {
+	case <-c:
+		s.log.Info().Msg("Received shutdown signal, shutting down...")
+	case err = <-errs:
+		s.log.Error().Err(err).Msg("Server stopped")
+	}
+
+	if srv != nil {
+		s.shutdownAPI(srv)
+	}
+
+	if s.ws != nil {
+		s.ws.Shutdown()
+	}
+
+	if cerr := s.ac.Close(); cerr != nil {
+		s.log.Error().Err(cerr).Msg("Failed to close async client")
+	}
+
+	if s.in != nil {
+		if cerr := s.in.Close(); cerr != nil {
+			s.log.Error().Err(cerr).Msg("Failed to close inspector")
+		}
+	}
+
+	s.log.Info().Msg("Server gracefully stopped")
+	return err
+}
+
+// httpServer creates the server of the API with the timeouts of the configuration
+// Event streams are ended as soon as it shuts down, since they never finish on their own
+func (s *server) httpServer() *http.Server {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.conf.Port()),
+		Handler:           s.router.Router(),
+		ReadHeaderTimeout: s.conf.ReadHeaderTimeout(),
+		ReadTimeout:       s.conf.ReadTimeout(),
+		WriteTimeout:      s.conf.WriteTimeout(),
+		IdleTimeout:       s.conf.IdleTimeout(),
+	}
+
+	srv.RegisterOnShutdown(s.router.Handlers().CloseStreams)
+	return srv
+}
+
+// shutdownAPI stops accepting requests and gives the requests in flight, such as uploads, time to finish
+// Once the time is up the remaining connections are closed and the uploads they abandon are removed
+func (s *server) shutdownAPI(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.ShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(ctx); err == nil {
+		return
+	}
+
+	s.log.Error().Msg("Failed to shut down server in time, closing remaining connections")
+	srv.Close()
+
+	actx, acancel := context.WithTimeout(context.Background(), abandonTimeout)
+	defer acancel()
+	s.router.Handlers().AbandonUploads(actx)
 }
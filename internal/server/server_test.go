@@ -0,0 +1,57 @@
+package server
+
+import (
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockstorage"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// Verifies that the modes select the parts of the service that a process runs
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   Mode
+		api    bool
+		worker bool
+	}{
+		{name: "api", mode: ModeAPI, api: true},
+		{name: "worker", mode: ModeWorker, worker: true},
+		{name: "all", mode: ModeAll, api: true, worker: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMode(tt.name)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.mode, m)
+			assert.Equal(t, tt.api, m.API())
+			assert.Equal(t, tt.worker, m.Worker())
+		})
+	}
+
+	_, err := ParseMode("scheduler")
+	assert.Error(t, err)
+	_, err = ParseMode("")
+	assert.Error(t, err)
+}
+
+// Verifies that the API is served with the timeouts of the configuration
+func TestHTTPServer(t *testing.T) {
+	c, err := config.FromJSON([]byte(`{"service": {"port": 9090, "read_timeout": 5, "write_timeout": 7}}`))
+	assert.NoError(t, err)
+	l := zerolog.Nop()
+	s := &server{conf: c, log: l, router: NewRouter(c, &l, new(mockdb.Database), new(mocktasks.Client), new(mocktasks.Inspector), new(mockstorage.Storage))}
+
+	srv := s.httpServer()
+	assert.Equal(t, ":9090", srv.Addr)
+	assert.Equal(t, 5*time.Second, srv.ReadTimeout)
+	assert.Equal(t, 7*time.Second, srv.WriteTimeout)
+	assert.Equal(t, config.DefaultReadHeaderTimeout, srv.ReadHeaderTimeout)
+	assert.Equal(t, config.DefaultIdleTimeout, srv.IdleTimeout)
+}
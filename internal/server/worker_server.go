@@ -1,80 +1,96 @@
 package server
 
 import (
-	"fmt"
-	"os"
-	"os/signal"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
 	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/storage"
 	"simple-file-processor/internal/tasks"
-	"syscall"
 
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
 
 type workerServer struct {
+	conf  config.Config
 	log   *zerolog.Logger
 	rDB   int
 	rAddr string
 	db    db.Database
+	st    storage.Storage
+	ac    tasks.Client
+	srv   *asynq.Server
 }
 
 type WorkerServer interface {
-	Start()
+	Start() error
+	Shutdown()
 }
 
-func NewWorkerServer(rAddr string, rDB int, db db.Database, log *zerolog.Logger) WorkerServer {
+// NewWorkerServer creates the worker server
+// The async client is used to enqueue the webhook deliveries of the tasks and is closed by the caller
+func NewWorkerServer(c config.Config, db db.Database, st storage.Storage, ac tasks.Client, log *zerolog.Logger) WorkerServer {
 	return &workerServer{
+		conf:  c,
 		log:   log,
-		rDB:   rDB,
-		rAddr: rAddr,
+		rDB:   c.RedisDB(),
+		rAddr: c.RedisAddress(),
 		db:    db,
+		st:    st,
+		ac:    ac,
 	}
 }
 
 // A background worker server that processes tasks from the task queue
 // The worker server is responsible for consuming from the task queue
 // and delegating the tasks to the appropriate handlers
-func (ws *workerServer) Start() {
+// Start returns once the worker is consuming, and the tasks are processed until Shutdown
+func (ws *workerServer) Start() error {
 	// Create a command executor for executing commands
 	cmdexec := lib.NewCommandExecutor(ws.log)
 
 	// Initialize the worker server with the given redis address and database
-	srv := asynq.NewServer(asynq.RedisClientOpt{Addr: ws.rAddr, DB: ws.rDB}, asynq.Config{
-		Concurrency: 10, // Set the concurrency level
+	ws.srv = asynq.NewServer(asynq.RedisClientOpt{Addr: ws.rAddr, DB: ws.rDB}, asynq.Config{
+		Concurrency:    ws.conf.WorkerConcurrency(), // Set the concurrency level
+		Queues:         ws.conf.Queues(),            // Queues the task types are routed to, along with their weights
+		StrictPriority: ws.conf.StrictPriority(),    // Empty the queues of higher weight first
 	})
 
 	mux := asynq.NewServeMux()
 
+	// Events of the tasks are streamed to the API and delivered to the webhooks by tasks of their own
+	n := tasks.NewNotifier(ws.ac, ws.db, EventStream(ws.conf, ws.log), ws.conf.WebhookEndpoints(), ws.conf.TaskOptions(tasks.WebhookTaskType), ws.log)
+
 	// Register the image resize handler with the task queue
-	mux.Handle(tasks.ImageResizeTaskType, tasks.NewImageResizeHandler(ws.db, lib.NewResizer(ws.log), ws.log))
+	mux.Handle(tasks.ImageResizeTaskType, tasks.NewImageResizeHandler(ws.db, lib.NewResizer(ws.st, ws.log), n, ws.log))
 
 	// Register the video metadata handler with the task queue
-	mux.Handle(tasks.VideoMetadataTaskType, tasks.NewVideoMetadataHandler(lib.NewMetadataExtractor(cmdexec, ws.log), ws.db, lib.NewFileSystem(), ws.log))
+	mux.Handle(tasks.VideoMetadataTaskType, tasks.NewVideoMetadataHandler(lib.NewMetadataExtractor(cmdexec, ws.log), ws.db, ws.st, n, ws.log))
 
-	ws.log.Info().Msg("Starting worker server...")
+	// Register the video transcode handler with the task queue
+	mux.Handle(tasks.VideoTranscodeTaskType, tasks.NewVideoTranscodeHandler(ws.db, lib.NewTranscoder(cmdexec, ws.st, ws.log), n, ws.log))
 
-	// Create a channel to listen for interrupt signals
-	c := make(chan os.Signal, 1)
+	// Register the video poster and sprite sheet handlers with the task queue
+	thumbnailer := lib.NewThumbnailer(cmdexec, ws.st, ws.log)
+	mux.Handle(tasks.VideoPosterTaskType, tasks.NewVideoPosterHandler(ws.db, thumbnailer, n, ws.log))
+	mux.Handle(tasks.VideoSpriteTaskType, tasks.NewVideoSpriteHandler(ws.db, thumbnailer, n, ws.log))
 
-	// Listen for interrupt signals
-	// This will allow the server to gracefully shutdown
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM) // Notify the channel when an interrupt signal is
+	// Register the webhook delivery handler with the task queue
+	mux.Handle(tasks.WebhookTaskType, tasks.NewWebhookHandler(ws.db, events.NewSender(ws.conf.WebhookSecret(), ws.conf.WebhookTimeout(), ws.conf.WebhookEndpoints()), ws.log))
 
-	go func() {
-		// Start the worker server with the given mux
-		if err := srv.Run(mux); err != nil {
-			ws.log.Fatal().Err(err).Msg("Failed to start worker")
-		}
-	}()
+	ws.log.Info().Msg("Starting worker server...")
+	return ws.srv.Start(mux)
+}
 
-	// Wait for the signal
-	<-c
-	ws.log.Info().Msg("Received shutdown signal, shutting down worker server...")
-	srv.Shutdown() // Shutdown the server
-	fmt.Println("Server gracefully stopped")
+// Shutdown stops consuming tasks and waits for the running tasks to finish
+// Tasks that do not finish in time are returned to the queue to be retried
+func (ws *workerServer) Shutdown() {
+	if ws.srv == nil {
+		return
+	}
 
-	// Exit the process
-	os.Exit(0)
+	ws.log.Info().Msg("Shutting down worker server...")
+	ws.srv.Shutdown()
+	ws.log.Info().Msg("Worker server stopped")
 }
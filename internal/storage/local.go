@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type local struct {
+	root string
+	log  *zerolog.Logger
+}
+
+type localObject struct {
+	*os.File
+	info ObjectInfo
+}
+
+// NewLocal constructs a storage backend that keeps objects on the local
+// file system underneath the given root directory
+func NewLocal(root string, l *zerolog.Logger) Storage {
+	return &local{
+		root: root,
+		log:  l,
+	}
+}
+
+// Put writes the object to a temporary file next to its destination and renames
+// it into place once fully written, so readers never observe a partial object
+func (s *local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		s.log.Error().Err(err).Msg("Failed to create directory for object " + key)
+		return ObjectInfo{}, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-"+filepath.Base(p)+"-*")
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to create object " + key)
+		return ObjectInfo{}, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once the file has been renamed
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		s.log.Error().Err(err).Msg("Failed to write object " + key)
+		return ObjectInfo{}, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		s.log.Error().Err(err).Msg("Failed to move object into place " + key)
+		return ObjectInfo{}, err
+	}
+
+	return s.Stat(ctx, key)
+}
+
+// Get opens the object for reading
+func (s *local) Get(ctx context.Context, key string) (Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &localObject{File: f, info: s.info(key, fi)}, nil
+}
+
+// Stat returns the information of the object
+func (s *local) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return ObjectInfo{}, notFound(err)
+	}
+
+	if fi.IsDir() {
+		return ObjectInfo{}, ErrNotFound
+	}
+
+	return s.info(key, fi), nil
+}
+
+// Delete removes the object, empty parent directories are left in place
+func (s *local) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.log.Error().Err(err).Msg("Failed to delete object " + key)
+		return err
+	}
+
+	return nil
+}
+
+// List walks the directory that contains the prefix and returns the matching objects
+func (s *local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	// Only walk the deepest directory that is fully named by the prefix
+	dir := path.Dir(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		dir = strings.TrimSuffix(prefix, "/")
+	}
+
+	root, err := s.path(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []ObjectInfo{}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objs = append(objs, s.info(key, fi))
+		return nil
+	})
+
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to list objects with prefix " + prefix)
+		return nil, err
+	}
+
+	return objs, nil
+}
+
+// PresignGet returns the absolute path of the object on disk
+// Local objects can be read directly by tools running on the same host
+func (s *local) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(p); err != nil {
+		return "", notFound(err)
+	}
+
+	return filepath.Abs(p)
+}
+
+// path resolves the key to a path underneath the storage root
+// Keys that would escape the root are rejected
+func (s *local) path(key string) (string, error) {
+	k := filepath.FromSlash(key)
+	if !filepath.IsLocal(k) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.root, k), nil
+}
+
+// info builds the object information from the file info
+func (s *local) info(key string, fi fs.FileInfo) ObjectInfo {
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+
+	return ObjectInfo{
+		Key:          key,
+		Size:         fi.Size(),
+		ContentType:  ct,
+		ETag:         fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+		LastModified: fi.ModTime(),
+	}
+}
+
+func (o *localObject) Info() ObjectInfo {
+	return o.info
+}
+
+// notFound maps a missing file to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// contextReader stops reading once the context is done
+// so that an abandoned upload does not keep writing to disk
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
@@ -0,0 +1,42 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"simple-file-processor/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	verifyStorage(t, storage.NewLocal(t.TempDir(), &log))
+}
+
+// Verifies that keys cannot be used to read or write outside of the storage root
+func TestLocalStorage_WhenKeyEscapesRoot_ExpectErrInvalidKey(t *testing.T) {
+	st := storage.NewLocal(t.TempDir(), &log)
+	for _, key := range []string{"../outside.txt", "uploads/../../outside.txt", "/etc/passwd", ""} {
+		_, err := st.Put(context.Background(), key, bytes.NewBufferString("x"), 1, "text/plain")
+		assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+		_, err = st.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+	}
+}
+
+// Verifies that the presigned location of a local object is its path on disk
+func TestLocalStorage_PresignGet_ExpectAbsolutePath(t *testing.T) {
+	root := t.TempDir()
+	st := storage.NewLocal(root, &log)
+	_, err := st.Put(context.Background(), "uploads/1/video.mp4", bytes.NewBufferString("x"), 1, "video/mp4")
+	assert.NoError(t, err)
+
+	p, err := st.PresignGet(context.Background(), "uploads/1/video.mp4", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "uploads", "1", "video.mp4"), p)
+
+	_, err = os.Stat(p)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/rs/zerolog"
+)
+
+// S3Options holds the connection settings of an S3 compatible object store
+type S3Options struct {
+	Endpoint  string // e.g. localhost:9000 or s3.amazonaws.com
+	Bucket    string // The bucket that holds every object
+	Region    string // e.g. us-east-1
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// PartSize is the size of the parts that objects of unknown size are uploaded in
+// Each upload buffers a part in memory, so it is kept small rather than left to minio,
+// which sizes the parts of an object of unknown size for the largest object S3 allows
+const PartSize = 16 << 20
+
+type s3 struct {
+	client *minio.Client
+	bucket string
+	log    *zerolog.Logger
+}
+
+type s3Object struct {
+	*minio.Object
+	info ObjectInfo
+}
+
+// NewS3 constructs a storage backend that keeps objects in an S3 compatible
+// object store such as AWS S3 or MinIO, so that workers on different hosts
+// share the same blobs
+func NewS3(o S3Options, l *zerolog.Logger) (Storage, error) {
+	c, err := minio.New(o.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(o.AccessKey, o.SecretKey, ""),
+		Secure:       o.UseSSL,
+		Region:       o.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to create S3 client for endpoint " + o.Endpoint)
+		return nil, err
+	}
+
+	if !o.UseSSL {
+		l.Warn().Msg("S3 storage at " + o.Endpoint + " is used without TLS, uploaded payloads are sent unsigned and unencrypted")
+	}
+
+	return &s3{
+		client: c,
+		bucket: o.Bucket,
+		log:    l,
+	}, nil
+}
+
+// Put uploads the object, objects of unknown size are uploaded in parts of PartSize
+// The payload is sent unsigned since streaming signatures are not supported
+// by every S3 compatible store, so its integrity relies on the connection
+// being secured with TLS through use_ssl, which NewS3 warns about when it is not
+func (s *s3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (ObjectInfo, error) {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:          contentType,
+		DisableContentSha256: true,
+		PartSize:             PartSize,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to put object " + key)
+		return ObjectInfo{}, err
+	}
+
+	return s.Stat(ctx, key)
+}
+
+// Get opens the object, reads are streamed from the object store on demand
+func (s *s3) Get(ctx context.Context, key string) (Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	oi, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, s.error(err)
+	}
+
+	return &s3Object{Object: obj, info: objectInfo(oi)}, nil
+}
+
+// Stat returns the information of the object
+func (s *s3) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	oi, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s.error(err)
+	}
+
+	return objectInfo(oi), nil
+}
+
+// Delete removes the object
+func (s *s3) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		s.log.Error().Err(err).Msg("Failed to delete object " + key)
+		return s.error(err)
+	}
+
+	return nil
+}
+
+// List returns every object whose key starts with the prefix
+func (s *s3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objs := []ObjectInfo{}
+	for oi := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if oi.Err != nil {
+			s.log.Error().Err(oi.Err).Msg("Failed to list objects with prefix " + prefix)
+			return nil, s.error(oi.Err)
+		}
+
+		objs = append(objs, objectInfo(oi))
+	}
+
+	return objs, nil
+}
+
+// PresignGet returns a presigned URL that can be used to download the object
+func (s *s3) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := s.Stat(ctx, key); err != nil {
+		return "", err
+	}
+
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to presign object " + key)
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// error maps the missing object responses of the object store to ErrNotFound
+func (s *s3) error(err error) error {
+	resp := minio.ToErrorResponse(err)
+	if resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func (o *s3Object) Info() ObjectInfo {
+	return o.info
+}
+
+// objectInfo converts the object store's object information
+func objectInfo(oi minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          oi.Key,
+		Size:         oi.Size,
+		ContentType:  oi.ContentType,
+		ETag:         `"` + oi.ETag + `"`,
+		LastModified: oi.LastModified,
+	}
+}
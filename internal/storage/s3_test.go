@@ -0,0 +1,74 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"simple-file-processor/internal/storage"
+	"sync/atomic"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs the storage behaviour against an in-memory S3 compatible server
+// that stands in for MinIO
+func TestS3Storage(t *testing.T) {
+	backend := s3mem.New()
+	require.NoError(t, backend.CreateBucket("files"))
+	srv := httptest.NewServer(gofakes3.New(backend).Server())
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	st, err := storage.NewS3(storage.S3Options{
+		Endpoint:  u.Host,
+		Bucket:    "files",
+		Region:    "us-east-1",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+	}, &log)
+	require.NoError(t, err)
+
+	verifyStorage(t, st)
+}
+
+// Verifies that objects of unknown size are uploaded in parts of PartSize,
+// rather than the parts minio sizes for the largest object S3 allows
+func TestS3PutUnknownSizeParts(t *testing.T) {
+	backend := s3mem.New()
+	require.NoError(t, backend.CreateBucket("files"))
+
+	var parts atomic.Int32
+	fake := gofakes3.New(backend).Server()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Query().Has("partNumber") {
+			parts.Add(1)
+		}
+
+		fake.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	st, err := storage.NewS3(storage.S3Options{
+		Endpoint:  u.Host,
+		Bucket:    "files",
+		Region:    "us-east-1",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+	}, &log)
+	require.NoError(t, err)
+
+	oi, err := st.Put(context.Background(), "uploads/large.bin", bytes.NewReader(make([]byte, storage.PartSize+1)), -1, "application/octet-stream")
+	require.NoError(t, err)
+	require.Equal(t, int64(storage.PartSize+1), oi.Size)
+	require.Equal(t, int32(2), parts.Load())
+}
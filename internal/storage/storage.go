@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	LocalDriver = "local" // Stores objects on the local file system
+	S3Driver    = "s3"    // Stores objects in an S3 compatible object store
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")   // Returned when an object does not exist
+	ErrInvalidKey = errors.New("invalid object key") // Returned when a key escapes the storage root
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string    // e.g. uploads/<id>/<generated name>
+	Size         int64     // The size of the object in bytes
+	ContentType  string    // e.g. image/jpeg
+	ETag         string    // An opaque, quoted identifier of the object's current content
+	LastModified time.Time // When the object was last written
+}
+
+// Object is an open stored object
+// Objects can be seeked so that they can serve range requests
+type Object interface {
+	io.ReadSeekCloser
+	Info() ObjectInfo
+}
+
+// Storage is the interface implemented by every storage backend
+// Keys are slash separated paths relative to the root of the backend
+// so the same key addresses the same blob regardless of the driver
+type Storage interface {
+	// Put writes the content of the reader to the given key, replacing any existing object
+	// The size may be -1 when it is not known up front
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (ObjectInfo, error)
+	// Get opens the object stored at the given key
+	Get(ctx context.Context, key string) (Object, error)
+	// Stat returns the information of the object stored at the given key
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete removes the object stored at the given key, deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with the given prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// PresignGet returns a location that external tools such as ffprobe can read the object from
+	// without going through the service, valid for the given duration
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
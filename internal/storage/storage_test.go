@@ -0,0 +1,96 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"simple-file-processor/internal/storage"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var log = zerolog.Nop()
+
+// verifyStorage runs the behaviour every storage driver must share
+// against the given backend
+func verifyStorage(t *testing.T, st storage.Storage) {
+	ctx := context.Background()
+
+	t.Run("Put and Get", func(t *testing.T) {
+		info, err := st.Put(ctx, "uploads/1/photo.jpg", bytes.NewBufferString("0123456789"), 10, "image/jpeg")
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), info.Size)
+		assert.Equal(t, "image/jpeg", info.ContentType)
+		assert.NotEmpty(t, info.ETag)
+
+		obj, err := st.Get(ctx, "uploads/1/photo.jpg")
+		require.NoError(t, err)
+		defer obj.Close()
+		assert.Equal(t, int64(10), obj.Info().Size)
+
+		// Objects must be seekable to serve range requests
+		_, err = obj.Seek(4, io.SeekStart)
+		require.NoError(t, err)
+		b, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, "456789", string(b))
+	})
+
+	t.Run("Put with unknown size", func(t *testing.T) {
+		info, err := st.Put(ctx, "uploads/1/stream.bin", bytes.NewBufferString("streamed"), -1, "application/octet-stream")
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), info.Size)
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		info, err := st.Stat(ctx, "uploads/1/photo.jpg")
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), info.Size)
+		assert.WithinDuration(t, time.Now(), info.LastModified, time.Minute)
+
+		_, err = st.Stat(ctx, "uploads/1/missing.jpg")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("Get missing object", func(t *testing.T) {
+		_, err := st.Get(ctx, "uploads/1/missing.jpg")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		_, err := st.Put(ctx, "uploads/2/other.jpg", bytes.NewBufferString("x"), 1, "image/jpeg")
+		require.NoError(t, err)
+
+		objs, err := st.List(ctx, "uploads/1/")
+		require.NoError(t, err)
+		keys := []string{}
+		for _, o := range objs {
+			keys = append(keys, o.Key)
+		}
+		sort.Strings(keys)
+		assert.Equal(t, []string{"uploads/1/photo.jpg", "uploads/1/stream.bin"}, keys)
+	})
+
+	t.Run("PresignGet", func(t *testing.T) {
+		u, err := st.PresignGet(ctx, "uploads/1/photo.jpg", time.Hour)
+		require.NoError(t, err)
+		assert.NotEmpty(t, u)
+
+		_, err = st.PresignGet(ctx, "uploads/1/missing.jpg", time.Hour)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, st.Delete(ctx, "uploads/1/photo.jpg"))
+		_, err := st.Stat(ctx, "uploads/1/photo.jpg")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		// Deleting a missing object is not an error
+		assert.NoError(t, st.Delete(ctx, "uploads/1/photo.jpg"))
+	})
+}
@@ -1,22 +1,29 @@
 package tasks
 
 import (
+	"sync"
+
 	"github.com/hibiken/asynq"
 )
 
 // A wrapper struct for the async client
 type async struct {
 	client *asynq.Client
+	once   sync.Once
+	err    error
 }
 
 // A wrapper interface for the async client
 // allowing for easier testing and mocking
 type Client interface {
 	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+	Close() error
 }
 
 // Initializes a new async client
 // with the given redis address
+// The client holds a pool of redis connections, so a single client is shared
+// by every enqueue and closed once when the server shuts down
 func NewAsyncClient(rAddr string, rDB int) Client {
 	return &async{
 		client: asynq.NewClient(asynq.RedisClientOpt{Addr: rAddr, DB: rDB}),
@@ -25,8 +32,6 @@ func NewAsyncClient(rAddr string, rDB int) Client {
 
 // Enqueues a task to the async worker
 func (a *async) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
-	defer a.client.Close() // Close the client when done
-
 	// Enqueue the task with the given options
 	ti, err := a.client.Enqueue(task, opts...)
 	if err != nil {
@@ -35,3 +40,13 @@ func (a *async) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo
 
 	return ti, nil
 }
+
+// Closes the connections of the client
+// Only the first call closes the client, later calls return the same result
+func (a *async) Close() error {
+	a.once.Do(func() {
+		a.err = a.client.Close()
+	})
+
+	return a.err
+}
@@ -0,0 +1,63 @@
+package tasks_test
+
+import (
+	"simple-file-processor/internal/tasks"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAsyncClient tests that a single client enqueues every task until it is closed
+func TestAsyncClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := tasks.NewAsyncClient(mr.Addr(), 0)
+
+	ids := []string{"job-1", "job-2", "job-3"}
+	for _, id := range ids {
+		task, err := tasks.NewImageResizeTask(c, &tasks.ImageResizePayload{Width: 100, Height: 100, JobID: id, FileID: "123"}, options, &log)
+		assert.NoError(t, err)
+
+		ti, err := task.Enqueue()
+		if assert.NoError(t, err, "enqueue of %s", id) {
+			assert.Equal(t, id, ti.ID)
+		}
+	}
+
+	pending, err := mr.List("asynq:{default}:pending")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, ids, pending)
+
+	// Closing is safe to repeat, and nothing is enqueued once closed
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+	task, _ := tasks.NewImageResizeTask(c, &tasks.ImageResizePayload{JobID: "job-4", FileID: "123"}, options, &log)
+	_, err = task.Enqueue()
+	assert.Error(t, err)
+}
+
+// TestAsyncClientUnique tests that identical resizes of a file are enqueued once while the first is pending
+func TestAsyncClientUnique(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := tasks.NewAsyncClient(mr.Addr(), 0)
+	defer c.Close()
+
+	unique := options
+	unique.Unique = time.Minute
+	resize := func(jobID string, width int) error {
+		task, err := tasks.NewImageResizeTask(c, &tasks.ImageResizePayload{Width: width, Height: 100, JobID: jobID, FileID: "123"}, unique, &log)
+		assert.NoError(t, err)
+		_, err = task.Enqueue()
+		return err
+	}
+
+	assert.NoError(t, resize("job-1", 100))
+	assert.ErrorIs(t, resize("job-2", 100), asynq.ErrDuplicateTask, "the job is not part of the payload")
+	assert.NoError(t, resize("job-3", 200), "other dimensions are another task")
+
+	pending, err := mr.List("asynq:{default}:pending")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"job-1", "job-3"}, pending)
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
 	"simple-file-processor/internal/lib"
 
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
@@ -16,23 +19,51 @@ const (
 )
 
 // Holds the payload for the image resize task
+// The variants of a batch replace the options, and the batch is identified by the id of the task
 type ImageResizePayload struct {
 	Width       int
 	Height      int
+	Mode        string // How the image is fitted into the size e.g. fit, fill, pad, scale
+	Gravity     string // The part of the image kept when cropping or padding
+	Background  string // The hex colour that padded images are filled with
+	Filter      string // The resampling filter e.g. nearest, bilinear, bicubic, lanczos
+	Format      string // The format of the resized image, the format of the source image when empty
+	Quality     int    // The quality of lossy formats from 1 to 100
+	Preset      string // The name of the preset the options were taken from, if any
+	Variants    []lib.ResizeOptions
+	JobID       string `json:",omitempty"` // The job that tracks the task, sent as the id of the task rather than in the payload
 	FileID      string
 	StoragePath string
 	Filename    string
 }
 
+// Options returns the options the image is resized with
+func (p *ImageResizePayload) Options() lib.ResizeOptions {
+	return lib.ResizeOptions{
+		Width:      p.Width,
+		Height:     p.Height,
+		Mode:       p.Mode,
+		Gravity:    p.Gravity,
+		Background: p.Background,
+		Filter:     p.Filter,
+		Format:     p.Format,
+		Quality:    p.Quality,
+	}
+}
+
 type imageResizeHandler struct {
-	db      db.Database
-	resizer lib.Resizer
-	log     *zerolog.Logger
+	db       db.Database
+	resizer  lib.Resizer
+	notifier Notifier
+	log      *zerolog.Logger
 }
 
 // Constructs a client for the image resize task
-func NewImageResizeTask(c Client, p *ImageResizePayload, l *zerolog.Logger) (Task, error) {
-	payload, err := json.Marshal(p)
+// The job is left out of the payload so that identical resizes have identical payloads
+func NewImageResizeTask(c Client, p *ImageResizePayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	q := *p
+	q.JobID = ""
+	payload, err := json.Marshal(q)
 	if err != nil {
 		l.Error().Err(err).Msg("Failed to marshal image resize task payload for file: " + p.FileID)
 		return nil, err
@@ -40,20 +71,23 @@ func NewImageResizeTask(c Client, p *ImageResizePayload, l *zerolog.Logger) (Tas
 
 	l.Info().Msg("Creating image resize task with payload: " + string(payload))
 	return &task{
-		client: c,
-		log:    l,
-		task:   asynq.NewTask(ImageResizeTaskType, payload),
+		id:      p.JobID,
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(ImageResizeTaskType, payload),
+		options: o,
 	}, nil
 }
 
 // Constructs a new image resize handler for the async worker
 // This will handle the image resize task and ensures that the
 // handler has access to the logger
-func NewImageResizeHandler(db db.Database, resizer lib.Resizer, l *zerolog.Logger) *imageResizeHandler {
+func NewImageResizeHandler(db db.Database, resizer lib.Resizer, n Notifier, l *zerolog.Logger) *imageResizeHandler {
 	return &imageResizeHandler{
-		db:      db,
-		log:     l,
-		resizer: resizer,
+		db:       db,
+		log:      l,
+		resizer:  resizer,
+		notifier: n,
 	}
 }
 
@@ -64,17 +98,31 @@ func (i *imageResizeHandler) ProcessTask(ctx context.Context, t *asynq.Task) err
 	var p *ImageResizePayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		i.log.Error().Err(err).Msg("Failed to unmarshal image resize task payload")
-		return err
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
 	}
 
 	i.log.Info().Msg("Resizing image for file with payload: " + string(t.Payload()))
+	id := jobID(ctx, p.JobID)
+	return trackJob(ctx, i.db, i.notifier, id, p.FileID, i.log, func() error {
+		if len(p.Variants) > 0 {
+			return i.processBatch(ctx, p, id)
+		}
 
-	po, err := i.resizer.ResizeImage(p.StoragePath, p.Filename, p.Width, p.Height)
+		return i.process(ctx, p)
+	})
+}
+
+// Resizes the image with the options of the payload
+func (i *imageResizeHandler) process(ctx context.Context, p *ImageResizePayload) error {
+	po, err := i.resizer.ResizeImage(ctx, p.StoragePath, p.Filename, p.Options())
 	if err != nil {
-		i.log.Error().Err(err).Msg("Failed to resize image for file with payload: " + string(t.Payload()))
+		i.log.Error().Err(err).Msg(fmt.Sprintf("Failed to resize image of file: %s", p.FileID))
 		return err
 	}
 
+	po.ID = uuid.New() // known up front so that the event refers to the stored output
+	po.Preset = p.Preset
+
 	// Insert the processed output into the database
 	if err := i.db.AddProcessedOutput(p.FileID, po); err != nil {
 		i.log.Error().Err(err).Msg(fmt.Sprintf("Failed to add processed output %s to file: %s", po.Name, p.FileID))
@@ -82,5 +130,34 @@ func (i *imageResizeHandler) ProcessTask(ctx context.Context, t *asynq.Task) err
 	}
 
 	i.log.Info().Msg(fmt.Sprintf("Added processed output %s to file: %s", po.Name, p.FileID))
+	i.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), nil)
+	return nil
+}
+
+// Resizes every variant of a batch from a single decode of the image
+// The outputs are added together so that a batch is either complete or absent
+// The id of the batch is the id of the job, so that identical batches have identical payloads
+func (i *imageResizeHandler) processBatch(ctx context.Context, p *ImageResizePayload, batchID string) error {
+	pos, err := i.resizer.ResizeVariants(ctx, p.StoragePath, p.Filename, p.Variants)
+	if err != nil {
+		i.log.Error().Err(err).Msg(fmt.Sprintf("Failed to resize batch %s of file: %s", batchID, p.FileID))
+		return err
+	}
+
+	for n := range pos {
+		pos[n].ID = uuid.New()
+		pos[n].BatchID = batchID
+	}
+
+	if err := i.db.AddProcessedOutputs(p.FileID, pos); err != nil {
+		i.log.Error().Err(err).Msg(fmt.Sprintf("Failed to add batch %s to file: %s", batchID, p.FileID))
+		return err
+	}
+
+	i.log.Info().Msg(fmt.Sprintf("Added batch %s of %d processed outputs to file: %s", batchID, len(pos), p.FileID))
+	for _, po := range pos {
+		i.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), nil)
+	}
+
 	return nil
 }
@@ -37,7 +37,7 @@ func TestNewImageResizeTask(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			task, err := tasks.NewImageResizeTask(tt.client, tt.payload, tt.logger)
+			task, err := tasks.NewImageResizeTask(tt.client, tt.payload, options, tt.logger)
 			if (tt.expectErr && err == nil) || (!tt.expectErr && err != nil) {
 				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
 			}
@@ -70,7 +70,7 @@ func TestNewImageResizeHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := tasks.NewImageResizeHandler(tt.db, tt.resizer, tt.log)
+			handler := tasks.NewImageResizeHandler(tt.db, tt.resizer, notifier(), tt.log)
 			assert.NotNil(t, handler)
 		})
 	}
@@ -79,7 +79,7 @@ func TestNewImageResizeHandler(t *testing.T) {
 // Tests for the ProcessTask function
 func TestProcessTask(t *testing.T) {
 	log := zerolog.Nop()
-	task := asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"Width":100,"Height":100,"FileID":"123","StoragePath":"/path/to/file","Filename":"test.jpg"}`))
+	task := asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"Width":100,"Height":100,"Mode":"pad","Gravity":"north","Background":"#000000","Filter":"bicubic","Format":"png","Quality":80,"FileID":"123","StoragePath":"/path/to/file","Filename":"test.jpg"}`))
 	// test cases
 	tests := []struct {
 		name        string
@@ -95,7 +95,20 @@ func TestProcessTask(t *testing.T) {
 				m.On("AddProcessedOutput", mock.Anything, mock.Anything).Return(nil)
 			},
 			mockResizer: func(m *mocktasks.Resizer) {
-				m.On("ResizeImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, nil)
+				m.On("ResizeImage", mock.Anything, "/path/to/file", "test.jpg", lib.ResizeOptions{Width: 100, Height: 100, Mode: "pad", Gravity: "north", Background: "#000000", Filter: "bicubic", Format: "png", Quality: 80}).Return(models.ProcessedOutput{}, nil)
+			},
+			expectErr: false,
+		},
+		{
+			name: "preset recorded on the output",
+			task: asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"Width":150,"Height":150,"Mode":"fill","Preset":"thumbnail","FileID":"123","StoragePath":"/path/to/file","Filename":"test.jpg"}`)),
+			mockDB: func(m *mockdb.Database) {
+				m.On("AddProcessedOutput", "123", mock.MatchedBy(func(po models.ProcessedOutput) bool {
+					return po.Preset == "thumbnail"
+				})).Return(nil)
+			},
+			mockResizer: func(m *mocktasks.Resizer) {
+				m.On("ResizeImage", mock.Anything, "/path/to/file", "test.jpg", lib.ResizeOptions{Width: 150, Height: 150, Mode: "fill"}).Return(models.ProcessedOutput{}, nil)
 			},
 			expectErr: false,
 		},
@@ -132,7 +145,7 @@ func TestProcessTask(t *testing.T) {
 			tt.mockDB(mockDB)
 			tt.mockResizer(mockResizer)
 			// Create the image resize handler with the mocked DB
-			handler := tasks.NewImageResizeHandler(mockDB, mockResizer, &log)
+			handler := tasks.NewImageResizeHandler(mockDB, mockResizer, notifier(), &log)
 
 			// Run the function
 			err := handler.ProcessTask(context.Background(), tt.task)
@@ -154,3 +167,64 @@ func TestProcessTask(t *testing.T) {
 		})
 	}
 }
+
+// TestProcessTaskBatch tests that the variants of a batch are resized and added together in the batch of the job
+func TestProcessTaskBatch(t *testing.T) {
+	log := zerolog.Nop()
+	task := asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"JobID":"job-id","Variants":[{"Width":320,"Mode":"scale"},{"Width":64,"Height":64,"Mode":"fill"}],"FileID":"123","StoragePath":"/path/to/file","Filename":"test.jpg"}`))
+	variants := []lib.ResizeOptions{{Width: 320, Mode: "scale"}, {Width: 64, Height: 64, Mode: "fill"}}
+
+	tests := []struct {
+		name        string
+		mockDB      func(m *mockdb.Database)
+		mockResizer func(m *mocktasks.Resizer)
+		expectErr   bool
+	}{
+		{
+			name: "valid batch",
+			mockDB: func(m *mockdb.Database) {
+				m.On("AddProcessedOutputs", "123", mock.MatchedBy(func(pos []models.ProcessedOutput) bool {
+					return len(pos) == 2 && pos[0].BatchID == "job-id" && pos[1].BatchID == "job-id"
+				})).Return(nil)
+				m.On("JobsByFileID", "123").Return([]models.Job{}, nil)
+			},
+			mockResizer: func(m *mocktasks.Resizer) {
+				m.On("ResizeVariants", mock.Anything, "/path/to/file", "test.jpg", variants).Return([]models.ProcessedOutput{{Width: 320}, {Width: 64}}, nil)
+			},
+		},
+		{
+			name:   "resize error",
+			mockDB: func(m *mockdb.Database) {},
+			mockResizer: func(m *mocktasks.Resizer) {
+				m.On("ResizeVariants", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectErr: true,
+		},
+		{
+			name: "error adding processed outputs",
+			mockDB: func(m *mockdb.Database) {
+				m.On("AddProcessedOutputs", "123", mock.Anything).Return(assert.AnError)
+			},
+			mockResizer: func(m *mocktasks.Resizer) {
+				m.On("ResizeVariants", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.ProcessedOutput{{}}, nil)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mockdb.Database)
+			mockResizer := new(mocktasks.Resizer)
+			tt.mockDB(mockDB)
+			tt.mockResizer(mockResizer)
+			mockDB.On("UpdateJob", "job-id", mock.Anything).Return(nil)
+			mockDB.On("TransitionFile", "123", mock.Anything, mock.Anything).Return(nil)
+
+			err := tasks.NewImageResizeHandler(mockDB, mockResizer, notifier(), &log).ProcessTask(context.Background(), task)
+			assert.Equal(t, tt.expectErr, err != nil)
+			mockDB.AssertExpectations(t)
+			mockResizer.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// The states of tasks that failed, either waiting to be retried or archived after using up their retries
+const (
+	TaskRetry    = "retry"
+	TaskArchived = "archived"
+)
+
+// The number of tasks listed from a queue at a time
+const inspectPageSize = 100
+
+// ErrTaskNotFound is returned for a task that does not exist or has not failed
+var ErrTaskNotFound = errors.New("failed task not found")
+
+// A task that failed, along with the error of its last attempt
+type FailedTask struct {
+	ID            string          `json:"id"`                        // e.g. the id of the job of the task
+	Queue         string          `json:"queue"`                     // e.g. default
+	Type          string          `json:"type"`                      // e.g. image:resize
+	State         string          `json:"state"`                     // e.g. retry, archived
+	FileID        string          `json:"file_id,omitempty"`         // e.g. the file the task processes
+	Payload       json.RawMessage `json:"payload"`                   // e.g. the payload of the task
+	LastError     string          `json:"last_error"`                // e.g. file is not a video
+	Retried       int             `json:"retried"`                   // e.g. the number of times the task was retried
+	MaxRetry      int             `json:"max_retry"`                 // e.g. the number of times the task may be retried
+	LastFailedAt  time.Time       `json:"last_failed_at"`            // e.g. when the last attempt failed
+	NextProcessAt *time.Time      `json:"next_process_at,omitempty"` // e.g. when the task is retried, unset once archived
+}
+
+// Filters the failed tasks that are listed, empty fields match every task
+type FailedTaskFilter struct {
+	State  string // e.g. retry, archived
+	Type   string // e.g. image:resize
+	FileID string // e.g. the file the tasks process
+}
+
+// A wrapper interface for the asynq inspector
+// allowing for easier testing and mocking
+type Inspector interface {
+	FailedTasks(f FailedTaskFilter) ([]FailedTask, error)
+	FailedTask(queue string, id string) (*FailedTask, error)
+	Replay(queue string, id string) error
+	Delete(queue string, id string) error
+	Close() error
+}
+
+// A wrapper struct for the asynq inspector
+type inspector struct {
+	inspector *asynq.Inspector
+}
+
+// Initializes a new inspector of the tasks in the queues
+// with the given redis address
+func NewInspector(rAddr string, rDB int) Inspector {
+	return &inspector{
+		inspector: asynq.NewInspector(asynq.RedisClientOpt{Addr: rAddr, DB: rDB}),
+	}
+}
+
+// FailedTasks lists the failed tasks of every queue that match the filter
+func (i *inspector) FailedTasks(f FailedTaskFilter) ([]FailedTask, error) {
+	queues, err := i.inspector.Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	states := []string{TaskRetry, TaskArchived}
+	if f.State != "" {
+		states = []string{f.State}
+	}
+
+	failed := []FailedTask{}
+	for _, q := range queues {
+		for _, s := range states {
+			list := i.inspector.ListRetryTasks
+			if s == TaskArchived {
+				list = i.inspector.ListArchivedTasks
+			}
+
+			for page := 1; ; page++ {
+				tis, err := list(q, asynq.Page(page), asynq.PageSize(inspectPageSize))
+				if err != nil {
+					return nil, fmt.Errorf("list %s tasks of queue %s: %w", s, q, err)
+				}
+
+				for _, ti := range tis {
+					ft := failedTask(ti)
+					if (f.Type == "" || ft.Type == f.Type) && (f.FileID == "" || ft.FileID == f.FileID) {
+						failed = append(failed, ft)
+					}
+				}
+
+				if len(tis) < inspectPageSize {
+					break
+				}
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// FailedTask returns the task with the given id if it failed
+func (i *inspector) FailedTask(queue string, id string) (*FailedTask, error) {
+	ti, err := i.inspector.GetTaskInfo(queue, id)
+	if errors.Is(err, asynq.ErrQueueNotFound) || errors.Is(err, asynq.ErrTaskNotFound) {
+		return nil, ErrTaskNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ft := failedTask(ti)
+	if !slices.Contains([]string{TaskRetry, TaskArchived}, ft.State) {
+		return nil, ErrTaskNotFound
+	}
+
+	return &ft, nil
+}
+
+// Replay moves the failed task back to the queue to be processed straight away
+// The retries of a replayed task are not reset, so a task that fails again is archived
+func (i *inspector) Replay(queue string, id string) error {
+	if _, err := i.FailedTask(queue, id); err != nil {
+		return err
+	}
+
+	return i.inspector.RunTask(queue, id)
+}
+
+// Delete removes the failed task from its queue for good
+func (i *inspector) Delete(queue string, id string) error {
+	if _, err := i.FailedTask(queue, id); err != nil {
+		return err
+	}
+
+	return i.inspector.DeleteTask(queue, id)
+}
+
+// Closes the connections of the inspector
+func (i *inspector) Close() error {
+	return i.inspector.Close()
+}
+
+// failedTask describes the task along with the file it processes
+func failedTask(ti *asynq.TaskInfo) FailedTask {
+	ft := FailedTask{
+		ID:           ti.ID,
+		Queue:        ti.Queue,
+		Type:         ti.Type,
+		State:        ti.State.String(),
+		FileID:       payloadFileID(ti.Payload),
+		Payload:      json.RawMessage(ti.Payload),
+		LastError:    ti.LastErr,
+		Retried:      ti.Retried,
+		MaxRetry:     ti.MaxRetry,
+		LastFailedAt: ti.LastFailedAt,
+	}
+
+	if !ti.NextProcessAt.IsZero() {
+		ft.NextProcessAt = &ti.NextProcessAt
+	}
+
+	if !json.Valid(ti.Payload) {
+		ft.Payload = nil
+	}
+
+	return ft
+}
+
+// payloadFileID returns the file of the payload of any task type
+// Webhook deliveries refer to the file through their event
+func payloadFileID(payload []byte) string {
+	var p struct {
+		FileID string
+		Event  struct {
+			FileID string `json:"file_id"`
+		}
+	}
+
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return ""
+	}
+
+	if p.FileID != "" {
+		return p.FileID
+	}
+
+	return p.Event.FileID
+}
@@ -0,0 +1,74 @@
+package tasks_test
+
+import (
+	"simple-file-processor/internal/tasks"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInspector tests that archived tasks are listed by type and file, and can be replayed and deleted
+func TestInspector(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := tasks.NewAsyncClient(mr.Addr(), 0)
+	defer c.Close()
+
+	resize, _ := tasks.NewImageResizeTask(c, &tasks.ImageResizePayload{Width: 100, JobID: "job-1", FileID: "file-1"}, options, &log)
+	video, _ := tasks.NewVideoMetadataTask(c, &tasks.VideoMetadataTaskPayload{JobID: "job-2", FileID: "file-2"}, options, &log)
+	pending, _ := tasks.NewImageResizeTask(c, &tasks.ImageResizePayload{Width: 200, JobID: "job-3", FileID: "file-1"}, options, &log)
+	for _, task := range []tasks.Task{resize, video, pending} {
+		_, err := task.Enqueue()
+		assert.NoError(t, err)
+	}
+
+	// Tasks are archived by the worker once their retries are used up
+	ai := asynq.NewInspector(asynq.RedisClientOpt{Addr: mr.Addr()})
+	defer ai.Close()
+	assert.NoError(t, ai.ArchiveTask("default", "job-1"))
+	assert.NoError(t, ai.ArchiveTask("default", "job-2"))
+
+	in := tasks.NewInspector(mr.Addr(), 0)
+	defer in.Close()
+
+	fts, err := in.FailedTasks(tasks.FailedTaskFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, fts, 2, "pending tasks have not failed")
+
+	fts, err = in.FailedTasks(tasks.FailedTaskFilter{State: tasks.TaskArchived, Type: tasks.ImageResizeTaskType, FileID: "file-1"})
+	assert.NoError(t, err)
+	if assert.Len(t, fts, 1) {
+		assert.Equal(t, "job-1", fts[0].ID)
+		assert.Equal(t, tasks.TaskArchived, fts[0].State)
+		assert.JSONEq(t, string(resize.Payload()), string(fts[0].Payload))
+	}
+
+	fts, err = in.FailedTasks(tasks.FailedTaskFilter{State: tasks.TaskRetry})
+	assert.NoError(t, err)
+	assert.Empty(t, fts)
+
+	ft, err := in.FailedTask("default", "job-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "file-2", ft.FileID)
+
+	_, err = in.FailedTask("default", "job-3")
+	assert.ErrorIs(t, err, tasks.ErrTaskNotFound, "pending tasks have not failed")
+	_, err = in.FailedTask("missing", "job-1")
+	assert.ErrorIs(t, err, tasks.ErrTaskNotFound)
+	assert.ErrorIs(t, in.Replay("default", "job-3"), tasks.ErrTaskNotFound)
+
+	// Replayed tasks are pending again and deleted tasks are gone
+	assert.NoError(t, in.Replay("default", "job-1"))
+	ti, err := ai.GetTaskInfo("default", "job-1")
+	assert.NoError(t, err)
+	assert.Equal(t, asynq.TaskStatePending, ti.State)
+
+	assert.NoError(t, in.Delete("default", "job-2"))
+	_, err = ai.GetTaskInfo("default", "job-2")
+	assert.ErrorIs(t, err, asynq.ErrTaskNotFound)
+
+	fts, err = in.FailedTasks(tasks.FailedTaskFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, fts)
+}
@@ -0,0 +1,126 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// The states of jobs that have not finished yet
+var activeJobStates = []string{models.JobQueued, models.JobRunning, models.JobRetrying}
+
+// trackJob runs the task while recording the progress of its job and the status of its file
+// The error of the task is returned so that asynq retries it, unless retrying cannot fix it,
+// and a job or file that cannot be updated never fails the task itself
+// A job that finally fails is announced to the webhooks of the file
+func trackJob(ctx context.Context, d db.Database, n Notifier, id string, fid string, l *zerolog.Logger, run func() error) error {
+	if id == "" {
+		return skipRetry(run())
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	updateJob(d, id, l, map[string]interface{}{
+		"state":      models.JobRunning,
+		"attempts":   retried + 1,
+		"started_at": time.Now(),
+	})
+	transitionFile(d, n, fid, models.StatusProcessing, models.StatusTransition{JobID: id, Reason: "job started"}, l)
+
+	err := skipRetry(run())
+	switch {
+	case err == nil:
+		updateJob(d, id, l, map[string]interface{}{
+			"state":        models.JobCompleted,
+			"error":        "",
+			"completed_at": time.Now(),
+		})
+		completeFile(d, n, id, fid, l)
+	case retried < maxRetry && !errors.Is(err, asynq.SkipRetry):
+		// The file stays in processing while the task is retried
+		updateJob(d, id, l, map[string]interface{}{
+			"state": models.JobRetrying,
+			"error": err.Error(),
+		})
+	default:
+		updateJob(d, id, l, map[string]interface{}{
+			"state":        models.JobFailed,
+			"error":        err.Error(),
+			"completed_at": time.Now(),
+		})
+		transitionFile(d, n, fid, models.StatusFailed, models.StatusTransition{JobID: id, Reason: "job failed: " + err.Error()}, l)
+		n.Notify(events.New(events.JobFailed, fid, map[string]interface{}{
+			"job_id":   id,
+			"attempts": retried + 1,
+			"error":    err.Error(),
+		}), nil)
+	}
+
+	return err
+}
+
+// skipRetry marks the errors that retrying the task cannot fix, such as a file or object that does not exist,
+// an image that cannot be decoded or resized that large, or a frame past the end of a video, so that asynq archives the task straight away
+func skipRetry(err error) error {
+	if err == nil || errors.Is(err, asynq.SkipRetry) {
+		return err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, storage.ErrNotFound) || errors.Is(err, image.ErrFormat) || errors.Is(err, lib.ErrTooLarge) || errors.Is(err, lib.ErrSourceTooLarge) || errors.Is(err, lib.ErrBeyondEnd) {
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	return err
+}
+
+func updateJob(d db.Database, id string, l *zerolog.Logger, fields map[string]interface{}) {
+	if err := d.UpdateJob(id, fields); err != nil {
+		l.Error().Err(err).Str("job_id", id).Msg("Failed to update job")
+	}
+}
+
+// completeFile completes the file once none of its other jobs are still to finish
+// A file that failed stays failed until another of its tasks starts
+func completeFile(d db.Database, n Notifier, id string, fid string, l *zerolog.Logger) {
+	jobs, err := d.JobsByFileID(fid)
+	if err != nil {
+		l.Error().Err(err).Str("file_id", fid).Msg("Failed to list jobs of file")
+		return
+	}
+
+	for _, j := range jobs {
+		if j.ID != id && slices.Contains(activeJobStates, j.State) {
+			return
+		}
+	}
+
+	transitionFile(d, n, fid, models.StatusCompleted, models.StatusTransition{JobID: id, Reason: "jobs completed"}, l)
+}
+
+// transitionFile moves the file to the given status and announces its new status
+func transitionFile(d db.Database, n Notifier, fid string, to string, st models.StatusTransition, l *zerolog.Logger) {
+	err := d.TransitionFile(fid, to, st)
+	if errors.Is(err, models.ErrInvalidTransition) {
+		l.Debug().Err(err).Str("file_id", fid).Msg("Skipping file status transition")
+		return
+	}
+
+	if err != nil {
+		l.Error().Err(err).Str("file_id", fid).Msg("Failed to transition file status")
+		return
+	}
+
+	n.Notify(events.NewStatusChanged(fid, to, st.JobID, st.Reason), nil)
+}
@@ -0,0 +1,144 @@
+package tasks_test
+
+import (
+	"context"
+	"fmt"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// state matches the update of a job to the given state
+func state(s string) interface{} {
+	return mock.MatchedBy(func(fields map[string]interface{}) bool {
+		return fields["state"] == s
+	})
+}
+
+// notifier returns a notifier that accepts every event
+func notifier() *mocktasks.Notifier {
+	n := new(mocktasks.Notifier)
+	n.On("Notify", mock.Anything, mock.Anything).Return().Maybe()
+	return n
+}
+
+// event matches an event of the given type about the given file
+func event(eventType string, fid string) interface{} {
+	return mock.MatchedBy(func(e events.Event) bool {
+		return e.Type == eventType && e.FileID == fid
+	})
+}
+
+// TestProcessTaskJob tests that the job of a task and the status of its file are updated as the task runs
+func TestProcessTaskJob(t *testing.T) {
+	log := zerolog.Nop()
+	task := asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"Width":100,"Height":100,"JobID":"job-id","FileID":"123","StoragePath":"/path/to/file","Filename":"test.jpg"}`))
+	tests := []struct {
+		name      string
+		resizeErr error
+		addErr    error
+		final     string
+		event     string
+	}{
+		{name: "completed", final: models.JobCompleted, event: events.OutputCreated},
+		{name: "resize failed", resizeErr: assert.AnError, final: models.JobFailed, event: events.JobFailed},
+		{name: "output not added", addErr: assert.AnError, final: models.JobFailed, event: events.JobFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			resizer := new(mocktasks.Resizer)
+			resizer.On("ResizeImage", mock.Anything, "/path/to/file", "test.jpg", lib.ResizeOptions{Width: 100, Height: 100}).Return(models.ProcessedOutput{}, tt.resizeErr)
+			if tt.resizeErr == nil {
+				db.On("AddProcessedOutput", "123", mock.Anything).Return(tt.addErr)
+			}
+
+			db.On("UpdateJob", "job-id", mock.MatchedBy(func(fields map[string]interface{}) bool {
+				return fields["state"] == models.JobRunning && fields["attempts"] == 1
+			})).Return(nil).Once()
+			db.On("UpdateJob", "job-id", state(tt.final)).Return(nil).Once()
+			db.On("TransitionFile", "123", models.StatusProcessing, mock.Anything).Return(nil).Once()
+			if tt.final == models.JobCompleted {
+				db.On("JobsByFileID", "123").Return([]models.Job{{ID: "job-id", State: models.JobCompleted}, {ID: "other-job-id", State: models.JobFailed}}, nil)
+				db.On("TransitionFile", "123", models.StatusCompleted, models.StatusTransition{JobID: "job-id", Reason: "jobs completed"}).Return(nil).Once()
+			} else {
+				db.On("TransitionFile", "123", models.StatusFailed, mock.MatchedBy(func(st models.StatusTransition) bool {
+					return st.JobID == "job-id" && st.Reason == "job failed: "+assert.AnError.Error()
+				})).Return(nil).Once()
+			}
+
+			n := new(mocktasks.Notifier)
+			n.On("Notify", event(tt.event, "123"), (*models.File)(nil)).Return().Once()
+			n.On("Notify", event(events.StatusChanged, "123"), (*models.File)(nil)).Return().Twice()
+
+			err := tasks.NewImageResizeHandler(db, resizer, n, &log).ProcessTask(context.Background(), task)
+			assert.Equal(t, tt.final == models.JobCompleted, err == nil)
+			db.AssertExpectations(t)
+			n.AssertExpectations(t)
+		})
+	}
+
+	t.Run("other jobs active", func(t *testing.T) {
+		db := new(mockdb.Database)
+		resizer := new(mocktasks.Resizer)
+		resizer.On("ResizeImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, nil)
+		db.On("AddProcessedOutput", "123", mock.Anything).Return(nil)
+		db.On("UpdateJob", "job-id", mock.Anything).Return(nil)
+		db.On("TransitionFile", "123", models.StatusProcessing, mock.Anything).Return(nil)
+		db.On("JobsByFileID", "123").Return([]models.Job{{ID: "job-id", State: models.JobCompleted}, {ID: "other-job-id", State: models.JobRetrying}}, nil)
+
+		assert.NoError(t, tasks.NewImageResizeHandler(db, resizer, notifier(), &log).ProcessTask(context.Background(), task))
+		db.AssertNotCalled(t, "TransitionFile", "123", models.StatusCompleted, mock.Anything)
+	})
+
+	t.Run("untracked task", func(t *testing.T) {
+		db := new(mockdb.Database)
+		resizer := new(mocktasks.Resizer)
+		resizer.On("ResizeImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, nil)
+		db.On("AddProcessedOutput", "123", mock.Anything).Return(nil)
+
+		untracked := asynq.NewTask(tasks.ImageResizeTaskType, []byte(`{"Width":100,"Height":100,"FileID":"123"}`))
+		assert.NoError(t, tasks.NewImageResizeHandler(db, resizer, notifier(), &log).ProcessTask(context.Background(), untracked))
+		db.AssertNotCalled(t, "UpdateJob", mock.Anything, mock.Anything)
+	})
+
+	t.Run("job update fails", func(t *testing.T) {
+		db := new(mockdb.Database)
+		resizer := new(mocktasks.Resizer)
+		resizer.On("ResizeImage", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, nil)
+		db.On("AddProcessedOutput", "123", mock.Anything).Return(nil)
+		db.On("UpdateJob", "job-id", mock.Anything).Return(assert.AnError)
+		db.On("TransitionFile", "123", mock.Anything, mock.Anything).Return(fmt.Errorf("%w from failed to completed", models.ErrInvalidTransition))
+		db.On("JobsByFileID", "123").Return(nil, assert.AnError)
+
+		assert.NoError(t, tasks.NewImageResizeHandler(db, resizer, notifier(), &log).ProcessTask(context.Background(), task))
+	})
+}
+
+// TestEnqueue tests that a task is enqueued with the id of its job
+func TestEnqueue(t *testing.T) {
+	log := zerolog.Nop()
+	client := new(mocktasks.Client)
+	client.On("Enqueue", mock.Anything, asynq.Queue("default"), mock.Anything, mock.Anything, asynq.TaskID("job-id")).Return(&asynq.TaskInfo{ID: "job-id", Queue: "default"}, nil)
+
+	task, err := tasks.NewVideoMetadataTask(client, &tasks.VideoMetadataTaskPayload{JobID: "job-id", FileID: "123"}, options, &log)
+	assert.NoError(t, err)
+	assert.Equal(t, "job-id", task.ID())
+	assert.NotContains(t, string(task.Payload()), "job-id", "the job is the id of the task, not part of its payload")
+	assert.Equal(t, tasks.VideoMetadataTaskType, task.Type())
+
+	ti, err := task.Enqueue()
+	assert.NoError(t, err)
+	assert.Equal(t, "default", ti.Queue)
+	client.AssertExpectations(t)
+}
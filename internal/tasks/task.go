@@ -1,6 +1,8 @@
 package tasks
 
 import (
+	"context"
+	"simple-file-processor/internal/config"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -9,26 +11,85 @@ import (
 
 // The client that will be used to enqueue the image resize task
 type task struct {
-	client Client          // Client to interact with the task queue
-	log    *zerolog.Logger // Logger to log messages
-	task   *asynq.Task     // Task to be enqueued
+	id      string             // The id of the task and of the job tracking it, generated by asynq when empty
+	client  Client             // Client to interact with the task queue
+	log     *zerolog.Logger    // Logger to log messages
+	task    *asynq.Task        // Task to be enqueued
+	options config.TaskOptions // The options of the type of the task
 }
 
 // ImageResizeTask interface defines the methods that the image resize task client should implement
 type Task interface {
-	// Name returns the name of the task
-	Enqueue() error // Enqueues the task with the given payload
+	ID() string                        // Returns the id the task is enqueued with
+	Type() string                      // Returns the type of the task e.g. image:resize
+	Payload() []byte                   // Returns the encoded payload of the task
+	Enqueue() (*asynq.TaskInfo, error) // Enqueues the task with the given payload
+	Unlocked() Task                    // Returns the task without the uniqueness lock of its type
+}
+
+func (i *task) ID() string {
+	return i.id
+}
+
+func (i *task) Type() string {
+	return i.task.Type()
+}
+
+func (i *task) Payload() []byte {
+	return i.task.Payload()
+}
+
+// A unique task keeps its lock until the lock expires, even after the task failed or was archived,
+// so a task enqueued again once its identical task is gone has to be enqueued without the lock
+func (i *task) Unlocked() Task {
+	u := *i
+	u.options.Unique = 0
+	return &u
 }
 
 // Enqueues the image resize task with the given payload
-func (i *task) Enqueue() error {
+func (i *task) Enqueue() (*asynq.TaskInfo, error) {
+	opts := []asynq.Option{
+		asynq.Queue(i.options.Queue),
+		asynq.MaxRetry(i.options.MaxRetry),
+		asynq.Timeout(i.options.Timeout),
+	}
+
+	if i.options.Deadline > 0 {
+		opts = append(opts, asynq.Deadline(time.Now().Add(i.options.Deadline)))
+	}
+
+	if i.options.Retention > 0 {
+		opts = append(opts, asynq.Retention(i.options.Retention))
+	}
+
+	// Identical payloads are rejected with asynq.ErrDuplicateTask until the task succeeds or the lock expires
+	if i.options.Unique > 0 {
+		opts = append(opts, asynq.Unique(i.options.Unique))
+	}
+
+	if i.id != "" {
+		opts = append(opts, asynq.TaskID(i.id))
+	}
+
 	// Enqueue the task with the given payload
-	_, err := i.client.Enqueue(i.task, asynq.MaxRetry(3), asynq.Timeout(60*time.Second))
+	ti, err := i.client.Enqueue(i.task, opts...)
 	if err != nil {
 		i.log.Error().Err(err).Msg("Failed to enqueue task with payload: " + string(i.task.Payload()))
-		return err
+		return nil, err
 	}
 
 	i.log.Info().Msg("Enqueued task with payload: " + string(i.task.Payload()))
-	return nil
+	return ti, nil
+}
+
+// jobID returns the id of the job that tracks the task being processed
+// Jobs are identified by the id the task was enqueued with, unless the payload carries the job
+func jobID(ctx context.Context, fromPayload string) string {
+	if fromPayload != "" {
+		return fromPayload
+	}
+
+	id, _ := asynq.GetTaskID(ctx)
+	return id
 }
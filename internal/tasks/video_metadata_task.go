@@ -1,13 +1,21 @@
 package tasks
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
 	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
 	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
@@ -15,10 +23,12 @@ import (
 const (
 	VideoMetadataTaskType = "video:extract-metadata" // Name of the task
 	metadataExt           = "json"                   // The file extension of the metadata file
+	presignExpiry         = time.Hour                // How long ffprobe may read the video from the storage
 )
 
 // Holds the payload for the video metadata task
 type VideoMetadataTaskPayload struct {
+	JobID       string `json:",omitempty"` // The job that tracks the task, sent as the id of the task rather than in the payload
 	FileID      string
 	StoragePath string
 	Filename    string
@@ -26,15 +36,18 @@ type VideoMetadataTaskPayload struct {
 
 // Constructs a client for the video metadata task
 type videoMetadataHandler struct {
-	db  db.Database
-	ext lib.MetadataExtractor
-	fs  lib.FileSystem
-	log *zerolog.Logger
+	db       db.Database
+	ext      lib.MetadataExtractor
+	st       storage.Storage
+	notifier Notifier
+	log      *zerolog.Logger
 }
 
 // Constructs a client for the video metadata task
-func NewVideoMetadataTask(c Client, p *VideoMetadataTaskPayload, l *zerolog.Logger) (Task, error) {
-	payload, err := json.Marshal(p)
+func NewVideoMetadataTask(c Client, p *VideoMetadataTaskPayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	q := *p
+	q.JobID = ""
+	payload, err := json.Marshal(q)
 	if err != nil {
 		l.Error().Err(err).Msg("Failed to marshal video metadata task payload for file: " + p.FileID)
 		return nil, err
@@ -42,18 +55,21 @@ func NewVideoMetadataTask(c Client, p *VideoMetadataTaskPayload, l *zerolog.Logg
 
 	l.Info().Msg("Creating video metadata task with payload: " + string(payload))
 	return &task{
-		client: c,
-		log:    l,
-		task:   asynq.NewTask(VideoMetadataTaskType, payload),
+		id:      p.JobID,
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(VideoMetadataTaskType, payload),
+		options: o,
 	}, nil
 }
 
-func NewVideoMetadataHandler(ext lib.MetadataExtractor, db db.Database, fs lib.FileSystem, l *zerolog.Logger) *videoMetadataHandler {
+func NewVideoMetadataHandler(ext lib.MetadataExtractor, db db.Database, st storage.Storage, n Notifier, l *zerolog.Logger) *videoMetadataHandler {
 	return &videoMetadataHandler{
-		db:  db,
-		ext: ext,
-		fs:  fs,
-		log: l,
+		db:       db,
+		ext:      ext,
+		st:       st,
+		notifier: n,
+		log:      l,
 	}
 }
 
@@ -64,10 +80,17 @@ func (h *videoMetadataHandler) ProcessTask(ctx context.Context, t *asynq.Task) e
 	var p VideoMetadataTaskPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		h.log.Error().Err(err).Msg("Failed to unmarshal video metadata task payload")
-		return err
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
 	}
 
 	h.log.Info().Msgf("Processing video metadata task for file %s", p.FileID)
+	return trackJob(ctx, h.db, h.notifier, jobID(ctx, p.JobID), p.FileID, h.log, func() error {
+		return h.process(ctx, p)
+	})
+}
+
+// Extracts the metadata of the video and stores it as a processed output
+func (h *videoMetadataHandler) process(ctx context.Context, p VideoMetadataTaskPayload) error {
 
 	// Get the file from the database
 	f, err := h.db.FileByID(p.FileID)
@@ -76,16 +99,22 @@ func (h *videoMetadataHandler) ProcessTask(ctx context.Context, t *asynq.Task) e
 		return err
 	}
 
-	// If the file is not a video, return an error
-	if !f.IsVideo() {
-		h.log.Error().Msg("File is not a video")
-		return fmt.Errorf("file is not a video")
+	// If the file is not a video the metadata can be extracted from, return an error
+	if !f.Supports(media.ProcessorVideoMetadata) {
+		h.log.Error().Str("mime_type", f.MimeType).Msg("File is not a video")
+		return fmt.Errorf("file is not a video: %w", asynq.SkipRetry)
 	}
 
 	// Extract the video metadata
 	h.log.Info().Msgf("Extracting video metadata for file %s at path %s", p.FileID, f.StoragePath)
-	path := fmt.Sprintf("%s/%s", p.StoragePath, p.Filename)
-	m, err := h.ext.ExtractVideoMetadata(path)
+	// ffprobe reads the video straight from the storage rather than through the worker
+	loc, err := h.st.PresignGet(ctx, path.Join(p.StoragePath, p.Filename), presignExpiry)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to locate video in storage")
+		return err
+	}
+
+	m, err := h.ext.ExtractVideoMetadata(ctx, loc)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to extract video metadata")
 		return err
@@ -99,28 +128,27 @@ func (h *videoMetadataHandler) ProcessTask(ctx context.Context, t *asynq.Task) e
 	}
 
 	// Generate the metadata file
-	_, err = generateMetadataFile(h.fs, f, m)
+	_, err = generateMetadataFile(ctx, h.st, f, m)
 	if err != nil {
 		h.log.Error().Err(err).Msg("Failed to generate metadata file")
 		return err
 	}
 
 	h.log.Info().Msgf("Processed video metadata for file %s and saved to %s", p.FileID, po.StoragePath)
+	h.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), f)
 	return nil
 }
 
-func generateMetadataFile(fs lib.FileSystem, f *models.File, vm *lib.VideoMetadata) (string, error) {
-	// Create the metadata file
-	metadataFile := fmt.Sprintf("%s/%s-metadata.%s", f.StoragePath, f.ID, metadataExt)
-	file, err := fs.Create(metadataFile)
-	if err != nil {
+func generateMetadataFile(ctx context.Context, st storage.Storage, f *models.File, vm *lib.VideoMetadata) (string, error) {
+	// Encode the metadata
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(vm); err != nil {
 		return "", err
 	}
-	defer file.Close()
 
-	// Write the metadata to the file
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(vm); err != nil {
+	// Write the metadata file to the storage
+	metadataFile := path.Join(f.StoragePath, fmt.Sprintf("%s-metadata.%s", f.ID, metadataExt))
+	if _, err := st.Put(ctx, metadataFile, &buf, int64(buf.Len()), "application/json"); err != nil {
 		return "", err
 	}
 
@@ -140,5 +168,6 @@ func processedOutput(f *models.File, vm *lib.VideoMetadata) models.ProcessedOutp
 		Name:        fmt.Sprintf("%s-%s", f.ID, "metadata"),
 		Extension:   metadataExt,
 		StoragePath: f.StoragePath,
+		ID:          uuid.New(),
 	}
 }
@@ -1,14 +1,16 @@
 package tasks_test
 
 import (
-	"bytes"
 	"context"
 	"errors"
+	"simple-file-processor/internal/config"
 	"simple-file-processor/internal/lib"
 	"simple-file-processor/internal/mocks/mockdb"
 	"simple-file-processor/internal/mocks/mocklib"
+	"simple-file-processor/internal/mocks/mockstorage"
 	"simple-file-processor/internal/mocks/mocktasks"
 	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/storage"
 	"simple-file-processor/internal/tasks"
 	"testing"
 
@@ -20,16 +22,8 @@ import (
 
 var log = zerolog.Nop()
 
-// MockFile is a mock implementation of the os.File interface
-// to simulate file operations in tests
-// This is a simple mock that embeds bytes.Buffer, todo: Move to a separate file if needed
-type MockFile struct {
-	bytes.Buffer
-}
-
-func (m *MockFile) Close() error {
-	return nil
-}
+// The options of task types that are not configured
+var options = config.TaskOptions{Queue: config.DefaultTaskQueue, MaxRetry: config.DefaultTaskMaxRetry, Timeout: config.DefaultTaskTimeout}
 
 // Test_NewVideoMetadataTask tests the NewVideoMetadataTask function
 func Test_NewVideoMetadataTask(t *testing.T) {
@@ -51,7 +45,7 @@ func Test_NewVideoMetadataTask(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			task, err := tasks.NewVideoMetadataTask(tt.client, tt.payload, tt.logger)
+			task, err := tasks.NewVideoMetadataTask(tt.client, tt.payload, options, tt.logger)
 			if (tt.expectErr && err == nil) || (!tt.expectErr && err != nil) {
 				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
 				return
@@ -70,7 +64,7 @@ func TestNewVideoMetadataHandler(t *testing.T) {
 		name    string
 		db      *mockdb.Database
 		resizer *mocklib.MetadataExtractor
-		fs      *mocklib.FileSystem
+		st      *mockstorage.Storage
 		logger  *zerolog.Logger
 	}{
 		{
@@ -83,7 +77,7 @@ func TestNewVideoMetadataHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := tasks.NewVideoMetadataHandler(tt.resizer, tt.db, tt.fs, tt.logger)
+			handler := tasks.NewVideoMetadataHandler(tt.resizer, tt.db, tt.st, notifier(), tt.logger)
 			assert.NotNil(t, handler)
 		})
 	}
@@ -94,25 +88,27 @@ func TestVideoMetadataProcessTask(t *testing.T) {
 	task := asynq.NewTask(tasks.VideoMetadataTaskType, []byte(`{"FileID":"123","StoragePath":"/path/to/file","Filename":"test.mp4"}`))
 
 	tests := []struct {
-		name           string
-		mockDB         func(m *mockdb.Database)
-		mockExtractor  func(m *mocklib.MetadataExtractor)
-		mockFileSystem func(m *mocklib.FileSystem)
-		task           *asynq.Task
-		expectErr      bool
+		name          string
+		mockDB        func(m *mockdb.Database)
+		mockExtractor func(m *mocklib.MetadataExtractor)
+		mockStorage   func(m *mockstorage.Storage)
+		task          *asynq.Task
+		expectErr     bool
+		skipRetry     bool
 	}{
 		{
 			name: "valid task",
 			mockDB: func(m *mockdb.Database) {
 				fid := "123"
-				m.On("FileByID", fid).Return(&models.File{ID: fid, StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4"}, nil)
+				m.On("FileByID", fid).Return(&models.File{ID: fid, StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4", MimeType: "video/mp4", Type: "video"}, nil)
 				m.On("AddProcessedOutput", fid, mock.Anything).Return(nil, nil)
 			},
 			mockExtractor: func(m *mocklib.MetadataExtractor) {
-				m.On("ExtractVideoMetadata", "/path/to/file/test.mp4").Return(&lib.VideoMetadata{}, nil)
+				m.On("ExtractVideoMetadata", mock.Anything, "/path/to/file/test.mp4").Return(&lib.VideoMetadata{}, nil)
 			},
-			mockFileSystem: func(m *mocklib.FileSystem) {
-				m.On("Create", "/path/to/file/123-metadata.json").Return(&MockFile{}, nil)
+			mockStorage: func(m *mockstorage.Storage) {
+				m.On("PresignGet", mock.Anything, "/path/to/file/test.mp4", mock.Anything).Return("/path/to/file/test.mp4", nil)
+				m.On("Put", mock.Anything, "/path/to/file/123-metadata.json", mock.Anything, mock.Anything, "application/json").Return(storage.ObjectInfo{}, nil)
 			},
 			task:      task,
 			expectErr: false,
@@ -120,37 +116,55 @@ func TestVideoMetadataProcessTask(t *testing.T) {
 		{
 			name: "file is not a video",
 			mockDB: func(m *mockdb.Database) {
-				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.txt", UploadedExtension: "txt"}, nil)
+				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.txt", UploadedExtension: "txt", MimeType: "text/plain; charset=utf-8", Type: "other"}, nil)
+			},
+			mockExtractor: func(_ *mocklib.MetadataExtractor) {},
+			mockStorage:   func(m *mockstorage.Storage) {},
+			task:          task,
+			expectErr:     true,
+			skipRetry:     true,
+		},
+		{
+			name: "video missing from storage",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4", MimeType: "video/mp4", Type: "video"}, nil)
 			},
-			mockExtractor:  func(_ *mocklib.MetadataExtractor) {},
-			mockFileSystem: func(m *mocklib.FileSystem) {},
-			task:           task,
-			expectErr:      true,
+			mockExtractor: func(_ *mocklib.MetadataExtractor) {},
+			mockStorage: func(m *mockstorage.Storage) {
+				m.On("PresignGet", mock.Anything, "/path/to/file/test.mp4", mock.Anything).Return("", storage.ErrNotFound)
+			},
+			task:      task,
+			expectErr: true,
+			skipRetry: true,
 		},
 		{
 			name: "failed to extract video metadata",
 			mockDB: func(m *mockdb.Database) {
-				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4"}, nil)
+				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4", MimeType: "video/mp4", Type: "video"}, nil)
 			},
 			mockExtractor: func(m *mocklib.MetadataExtractor) {
-				m.On("ExtractVideoMetadata", "/path/to/file/test.mp4").Return(nil, errors.New("extract error"))
+				m.On("ExtractVideoMetadata", mock.Anything, "/path/to/file/test.mp4").Return(nil, errors.New("extract error"))
 			},
-			mockFileSystem: func(m *mocklib.FileSystem) {},
-			task:           task,
-			expectErr:      true,
+			mockStorage: func(m *mockstorage.Storage) {
+				m.On("PresignGet", mock.Anything, "/path/to/file/test.mp4", mock.Anything).Return("/path/to/file/test.mp4", nil)
+			},
+			task:      task,
+			expectErr: true,
 		},
 		{
 			name: "failed to add processed output",
 			mockDB: func(m *mockdb.Database) {
-				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4"}, nil)
+				m.On("FileByID", "123").Return(&models.File{StoragePath: "/path/to/file", OriginalName: "test.mp4", UploadedExtension: "mp4", MimeType: "video/mp4", Type: "video"}, nil)
 				m.On("AddProcessedOutput", "123", mock.Anything).Return(errors.New("db error"))
 			},
 			mockExtractor: func(m *mocklib.MetadataExtractor) {
-				m.On("ExtractVideoMetadata", "/path/to/file/test.mp4").Return(&lib.VideoMetadata{}, nil)
+				m.On("ExtractVideoMetadata", mock.Anything, "/path/to/file/test.mp4").Return(&lib.VideoMetadata{}, nil)
 			},
-			mockFileSystem: func(m *mocklib.FileSystem) {},
-			task:           task,
-			expectErr:      true,
+			mockStorage: func(m *mockstorage.Storage) {
+				m.On("PresignGet", mock.Anything, "/path/to/file/test.mp4", mock.Anything).Return("/path/to/file/test.mp4", nil)
+			},
+			task:      task,
+			expectErr: true,
 		},
 	}
 
@@ -158,12 +172,12 @@ func TestVideoMetadataProcessTask(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			db := new(mockdb.Database)
 			ext := new(mocklib.MetadataExtractor)
-			fs := new(mocklib.FileSystem)
-			handler := tasks.NewVideoMetadataHandler(ext, db, fs, &log)
+			st := new(mockstorage.Storage)
+			handler := tasks.NewVideoMetadataHandler(ext, db, st, notifier(), &log)
 
 			tt.mockDB(db)
 			tt.mockExtractor(ext)
-			tt.mockFileSystem(fs)
+			tt.mockStorage(st)
 
 			err := handler.ProcessTask(context.Background(), tt.task)
 			if (tt.expectErr && err == nil) || (!tt.expectErr && err != nil) {
@@ -174,6 +188,8 @@ func TestVideoMetadataProcessTask(t *testing.T) {
 			if !tt.expectErr {
 				assert.Nil(t, err)
 			}
+
+			assert.Equal(t, tt.skipRetry, errors.Is(err, asynq.SkipRetry), "whether the task is archived without retries")
 		})
 	}
 }
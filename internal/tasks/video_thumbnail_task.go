@@ -0,0 +1,249 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
+	"simple-file-processor/internal/models"
+	"strconv"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+const (
+	VideoPosterTaskType = "video:poster" // Name of the poster task
+	VideoSpriteTaskType = "video:sprite" // Name of the sprite sheet task
+)
+
+// Holds the payload for the video poster task
+type VideoPosterPayload struct {
+	Timestamp   float64 // The second of the video the poster is taken at, unless a percentage is given
+	Percent     float64 // The percentage of the duration the poster is taken at
+	Width       int     // The width of the poster, the width of the video when zero
+	JobID       string  `json:",omitempty"` // The job that tracks the task, sent as the id of the task rather than in the payload
+	FileID      string
+	StoragePath string
+	Filename    string
+}
+
+// Holds the payload for the video sprite sheet task
+type VideoSpritePayload struct {
+	Frames      int    // The number of frames of the sheet
+	Columns     int    // The number of frames in a row of the sheet
+	Width       int    // The width of each frame
+	JobID       string `json:",omitempty"` // The job that tracks the task, sent as the id of the task rather than in the payload
+	FileID      string
+	StoragePath string
+	Filename    string
+}
+
+type videoPosterHandler struct {
+	db          db.Database
+	thumbnailer lib.Thumbnailer
+	notifier    Notifier
+	log         *zerolog.Logger
+}
+
+type videoSpriteHandler struct {
+	db          db.Database
+	thumbnailer lib.Thumbnailer
+	notifier    Notifier
+	log         *zerolog.Logger
+}
+
+// Constructs a client for the video poster task
+// The job is left out of the payload so that identical posters have identical payloads
+func NewVideoPosterTask(c Client, p *VideoPosterPayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	q := *p
+	q.JobID = ""
+	payload, err := json.Marshal(q)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to marshal video poster task payload for file: " + p.FileID)
+		return nil, err
+	}
+
+	l.Info().Msg("Creating video poster task with payload: " + string(payload))
+	return &task{
+		id:      p.JobID,
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(VideoPosterTaskType, payload),
+		options: o,
+	}, nil
+}
+
+// Constructs a client for the video sprite sheet task
+// The job is left out of the payload so that identical sprite sheets have identical payloads
+func NewVideoSpriteTask(c Client, p *VideoSpritePayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	q := *p
+	q.JobID = ""
+	payload, err := json.Marshal(q)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to marshal video sprite task payload for file: " + p.FileID)
+		return nil, err
+	}
+
+	l.Info().Msg("Creating video sprite task with payload: " + string(payload))
+	return &task{
+		id:      p.JobID,
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(VideoSpriteTaskType, payload),
+		options: o,
+	}, nil
+}
+
+// Constructs a new video poster handler for the async worker
+func NewVideoPosterHandler(db db.Database, thumbnailer lib.Thumbnailer, n Notifier, l *zerolog.Logger) *videoPosterHandler {
+	return &videoPosterHandler{
+		db:          db,
+		thumbnailer: thumbnailer,
+		notifier:    n,
+		log:         l,
+	}
+}
+
+// Constructs a new video sprite sheet handler for the async worker
+func NewVideoSpriteHandler(db db.Database, thumbnailer lib.Thumbnailer, n Notifier, l *zerolog.Logger) *videoSpriteHandler {
+	return &videoSpriteHandler{
+		db:          db,
+		thumbnailer: thumbnailer,
+		notifier:    n,
+		log:         l,
+	}
+}
+
+// Handles the video poster task and takes the poster frame of the video
+// This will be called by the async worker
+func (h *videoPosterHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p VideoPosterPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		h.log.Error().Err(err).Msg("Failed to unmarshal video poster task payload")
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	h.log.Info().Msgf("Processing video poster task for file %s", p.FileID)
+	return trackJob(ctx, h.db, h.notifier, jobID(ctx, p.JobID), p.FileID, h.log, func() error {
+		return h.process(ctx, p)
+	})
+}
+
+// Takes the poster of the video and stores it as a processed output
+func (h *videoPosterHandler) process(ctx context.Context, p VideoPosterPayload) error {
+	f, err := videoFile(h.db, p.FileID, media.ProcessorPoster, h.log)
+	if err != nil {
+		return err
+	}
+
+	po, err := h.thumbnailer.Poster(ctx, p.StoragePath, p.Filename, lib.PosterOptions{
+		Timestamp: p.Timestamp,
+		Percent:   p.Percent,
+		Width:     p.Width,
+		Duration:  videoDuration(f),
+	})
+	if err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to take poster of file: %s", p.FileID))
+		return err
+	}
+
+	if err := h.db.AddProcessedOutput(p.FileID, po); err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to add poster %s to file: %s", po.Name, p.FileID))
+		return err
+	}
+
+	h.log.Info().Msg(fmt.Sprintf("Added poster %s to file: %s", po.Name, p.FileID))
+	h.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), f)
+	return nil
+}
+
+// Handles the video sprite sheet task and tiles frames of the video into a sheet
+// This will be called by the async worker
+func (h *videoSpriteHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p VideoSpritePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		h.log.Error().Err(err).Msg("Failed to unmarshal video sprite task payload")
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	h.log.Info().Msgf("Processing video sprite task for file %s", p.FileID)
+	id := jobID(ctx, p.JobID)
+	return trackJob(ctx, h.db, h.notifier, id, p.FileID, h.log, func() error {
+		return h.process(ctx, p, id)
+	})
+}
+
+// Makes the sprite sheet of the video and its WebVTT index, and stores them as processed outputs of one batch
+// The id of the batch is the id of the job, so that identical sprite sheets have identical payloads
+func (h *videoSpriteHandler) process(ctx context.Context, p VideoSpritePayload, batchID string) error {
+	f, err := videoFile(h.db, p.FileID, media.ProcessorSprite, h.log)
+	if err != nil {
+		return err
+	}
+
+	pos, err := h.thumbnailer.Sprite(ctx, p.StoragePath, p.Filename, lib.SpriteOptions{
+		Frames:   p.Frames,
+		Columns:  p.Columns,
+		Width:    p.Width,
+		Duration: videoDuration(f),
+		// The cues refer to the sheet by its content endpoint, so that players fetch it from the API
+		ImageURL: "/file/" + f.ID + "/outputs/{id}/content",
+	})
+	if err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to make sprite sheet of file: %s", p.FileID))
+		return err
+	}
+
+	for n := range pos {
+		pos[n].BatchID = batchID
+	}
+
+	if err := h.db.AddProcessedOutputs(p.FileID, pos); err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to add sprite sheet %s to file: %s", batchID, p.FileID))
+		return err
+	}
+
+	h.log.Info().Msg(fmt.Sprintf("Added sprite sheet %s to file: %s", batchID, p.FileID))
+	for _, po := range pos {
+		h.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), f)
+	}
+
+	return nil
+}
+
+// videoFile returns the file, unless it is not a video that may be run through the processor
+func videoFile(d db.Database, fid string, processor string, l *zerolog.Logger) (*models.File, error) {
+	f, err := d.FileByID(fid)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to get file by ID")
+		return nil, err
+	}
+
+	if !f.Supports(processor) {
+		l.Error().Str("mime_type", f.MimeType).Str("processor", processor).Msg("File is not a video that supports the processor")
+		return nil, fmt.Errorf("file is not a video that supports %s: %w", processor, asynq.SkipRetry)
+	}
+
+	return f, nil
+}
+
+// videoDuration returns the duration in seconds that the metadata task extracted from the video
+// Zero is returned when the metadata has not been extracted yet, so that the video is probed instead
+func videoDuration(f *models.File) float64 {
+	for _, po := range f.ProcessedOutputs {
+		if po.Type != models.VideoMetadataType {
+			continue
+		}
+
+		if d, err := strconv.ParseFloat(po.Duration, 64); err == nil && d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
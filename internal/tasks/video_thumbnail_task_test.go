@@ -0,0 +1,176 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestNewVideoThumbnailTasks tests that the job is left out of the payloads of the poster and sprite tasks
+func TestNewVideoThumbnailTasks(t *testing.T) {
+	pt, err := tasks.NewVideoPosterTask(new(mocktasks.Client), &tasks.VideoPosterPayload{Percent: 25, JobID: "job-id", FileID: "123"}, options, &log)
+	assert.NoError(t, err)
+	assert.Equal(t, "job-id", pt.ID())
+	assert.Equal(t, tasks.VideoPosterTaskType, pt.Type())
+	assert.NotContains(t, string(pt.Payload()), "job-id")
+
+	st, err := tasks.NewVideoSpriteTask(new(mocktasks.Client), &tasks.VideoSpritePayload{Frames: 20, JobID: "job-id", FileID: "123"}, options, &log)
+	assert.NoError(t, err)
+	assert.Equal(t, "job-id", st.ID())
+	assert.Equal(t, tasks.VideoSpriteTaskType, st.Type())
+	assert.NotContains(t, string(st.Payload()), "job-id")
+}
+
+// TestVideoPosterProcessTask tests that the poster is taken with the duration that the metadata task extracted
+func TestVideoPosterProcessTask(t *testing.T) {
+	payload, _ := json.Marshal(tasks.VideoPosterPayload{Percent: 25, Width: 640, JobID: "job-id", FileID: "123", StoragePath: "/path/to/file", Filename: "test.mp4"})
+	task := asynq.NewTask(tasks.VideoPosterTaskType, payload)
+	video := &models.File{ID: "123", StoragePath: "/path/to/file", MimeType: "video/mp4", Type: "video", ProcessedOutputs: []models.ProcessedOutput{
+		{Type: models.VideoMetadataType, Duration: "12.5"},
+	}}
+
+	tests := []struct {
+		name            string
+		mockDB          func(m *mockdb.Database)
+		mockThumbnailer func(m *mocktasks.Thumbnailer)
+		expectErr       bool
+		skipRetry       bool
+	}{
+		{
+			name: "valid task",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+				m.On("AddProcessedOutput", "123", models.ProcessedOutput{Type: models.VideoPosterType}).Return(nil)
+				m.On("JobsByFileID", "123").Return([]models.Job{}, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				m.On("Poster", mock.Anything, "/path/to/file", "test.mp4", lib.PosterOptions{Percent: 25, Width: 640, Duration: 12.5}).Return(models.ProcessedOutput{Type: models.VideoPosterType}, nil)
+			},
+		},
+		{
+			name: "file is not a video",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(&models.File{ID: "123", MimeType: "image/png", Type: "image"}, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {},
+			expectErr:       true,
+			skipRetry:       true,
+		},
+		{
+			name: "timestamp beyond the end",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				m.On("Poster", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, fmt.Errorf("%w: 20 of 12.5 seconds", lib.ErrBeyondEnd))
+			},
+			expectErr: true,
+			skipRetry: true,
+		},
+		{
+			name: "ffmpeg error",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				m.On("Poster", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(models.ProcessedOutput{}, errors.New("ffmpeg error"))
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			thumbnailer := new(mocktasks.Thumbnailer)
+			tt.mockDB(db)
+			tt.mockThumbnailer(thumbnailer)
+			db.On("UpdateJob", "job-id", mock.Anything).Return(nil)
+			db.On("TransitionFile", "123", mock.Anything, mock.Anything).Return(nil)
+
+			err := tasks.NewVideoPosterHandler(db, thumbnailer, notifier(), &log).ProcessTask(context.Background(), task)
+			assert.Equal(t, tt.expectErr, err != nil)
+			assert.Equal(t, tt.skipRetry, errors.Is(err, asynq.SkipRetry), "whether the task is archived without retries")
+			db.AssertExpectations(t)
+			thumbnailer.AssertExpectations(t)
+		})
+	}
+}
+
+// TestVideoSpriteProcessTask tests that the sheet and its index are added together in the batch of the job
+func TestVideoSpriteProcessTask(t *testing.T) {
+	payload, _ := json.Marshal(tasks.VideoSpritePayload{Frames: 20, JobID: "job-id", FileID: "123", StoragePath: "/path/to/file", Filename: "test.mp4"})
+	task := asynq.NewTask(tasks.VideoSpriteTaskType, payload)
+	video := &models.File{ID: "123", StoragePath: "/path/to/file", MimeType: "video/mp4", Type: "video"}
+
+	tests := []struct {
+		name            string
+		mockDB          func(m *mockdb.Database)
+		mockThumbnailer func(m *mocktasks.Thumbnailer)
+		expectErr       bool
+	}{
+		{
+			name: "valid task",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+				m.On("AddProcessedOutputs", "123", mock.MatchedBy(func(pos []models.ProcessedOutput) bool {
+					return len(pos) == 2 && pos[0].BatchID == "job-id" && pos[1].BatchID == "job-id"
+				})).Return(nil)
+				m.On("JobsByFileID", "123").Return([]models.Job{}, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				// The duration is probed when the metadata has not been extracted
+				o := lib.SpriteOptions{Frames: 20, ImageURL: "/file/123/outputs/{id}/content"}
+				m.On("Sprite", mock.Anything, "/path/to/file", "test.mp4", o).Return([]models.ProcessedOutput{{Type: models.VideoSpriteType}, {Type: models.VideoSpriteIndexType}}, nil)
+			},
+		},
+		{
+			name: "sprite error",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				m.On("Sprite", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("ffmpeg error"))
+			},
+			expectErr: true,
+		},
+		{
+			name: "error adding processed outputs",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+				m.On("AddProcessedOutputs", "123", mock.Anything).Return(assert.AnError)
+			},
+			mockThumbnailer: func(m *mocktasks.Thumbnailer) {
+				m.On("Sprite", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.ProcessedOutput{{}, {}}, nil)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			thumbnailer := new(mocktasks.Thumbnailer)
+			tt.mockDB(db)
+			tt.mockThumbnailer(thumbnailer)
+			db.On("UpdateJob", "job-id", mock.Anything).Return(nil)
+			db.On("TransitionFile", "123", mock.Anything, mock.Anything).Return(nil)
+
+			err := tasks.NewVideoSpriteHandler(db, thumbnailer, notifier(), &log).ProcessTask(context.Background(), task)
+			assert.Equal(t, tt.expectErr, err != nil)
+			db.AssertExpectations(t)
+			thumbnailer.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/media"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+const (
+	VideoTranscodeTaskType = "video:transcode" // Name of the task
+)
+
+// Holds the payload for the video transcode task
+type VideoTranscodePayload struct {
+	Profiles    []lib.TranscodeProfile // The renditions the video is transcoded to
+	JobID       string                 `json:",omitempty"` // The job that tracks the task, sent as the id of the task rather than in the payload
+	FileID      string
+	StoragePath string
+	Filename    string
+}
+
+type videoTranscodeHandler struct {
+	db         db.Database
+	transcoder lib.Transcoder
+	notifier   Notifier
+	log        *zerolog.Logger
+}
+
+// Constructs a client for the video transcode task
+// The job is left out of the payload so that identical transcodes have identical payloads
+func NewVideoTranscodeTask(c Client, p *VideoTranscodePayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	q := *p
+	q.JobID = ""
+	payload, err := json.Marshal(q)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to marshal video transcode task payload for file: " + p.FileID)
+		return nil, err
+	}
+
+	l.Info().Msg("Creating video transcode task with payload: " + string(payload))
+	return &task{
+		id:      p.JobID,
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(VideoTranscodeTaskType, payload),
+		options: o,
+	}, nil
+}
+
+// Constructs a new video transcode handler for the async worker
+func NewVideoTranscodeHandler(db db.Database, transcoder lib.Transcoder, n Notifier, l *zerolog.Logger) *videoTranscodeHandler {
+	return &videoTranscodeHandler{
+		db:         db,
+		transcoder: transcoder,
+		notifier:   n,
+		log:        l,
+	}
+}
+
+// Handles the video transcode task and transcodes the video to every profile of the payload
+// This will be called by the async worker
+func (h *videoTranscodeHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p VideoTranscodePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		h.log.Error().Err(err).Msg("Failed to unmarshal video transcode task payload")
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	h.log.Info().Msgf("Processing video transcode task for file %s", p.FileID)
+	id := jobID(ctx, p.JobID)
+	return trackJob(ctx, h.db, h.notifier, id, p.FileID, h.log, func() error {
+		return h.process(ctx, p, id)
+	})
+}
+
+// Transcodes the video and stores its renditions as processed outputs of one batch
+// The id of the batch is the id of the job, so that identical transcodes have identical payloads
+// The renditions are added together so that a batch is either complete or absent
+func (h *videoTranscodeHandler) process(ctx context.Context, p VideoTranscodePayload, batchID string) error {
+	f, err := h.db.FileByID(p.FileID)
+	if err != nil {
+		h.log.Error().Err(err).Msg("Failed to get file by ID")
+		return err
+	}
+
+	if !f.Supports(media.ProcessorTranscode) {
+		h.log.Error().Str("mime_type", f.MimeType).Msg("File is not a video that can be transcoded")
+		return fmt.Errorf("file is not a video that can be transcoded: %w", asynq.SkipRetry)
+	}
+
+	pos, err := h.transcoder.Transcode(ctx, p.StoragePath, p.Filename, p.Profiles)
+	if err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to transcode video of file: %s", p.FileID))
+		return err
+	}
+
+	for n := range pos {
+		pos[n].BatchID = batchID
+	}
+
+	if err := h.db.AddProcessedOutputs(p.FileID, pos); err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to add batch %s to file: %s", batchID, p.FileID))
+		return err
+	}
+
+	h.log.Info().Msg(fmt.Sprintf("Added batch %s of %d renditions to file: %s", batchID, len(pos), p.FileID))
+	for _, po := range pos {
+		h.notifier.Notify(events.New(events.OutputCreated, p.FileID, po), f)
+	}
+
+	return nil
+}
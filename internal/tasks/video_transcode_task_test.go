@@ -0,0 +1,111 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"simple-file-processor/internal/lib"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var profiles = []lib.TranscodeProfile{
+	{Name: "720p", Format: lib.FormatMP4, Width: 1280, Height: 720, VideoBitrate: 2500},
+	{Name: "480p-webm", Format: lib.FormatWebM, Height: 480, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+// TestNewVideoTranscodeTask tests that the job is left out of the payload of the task
+func TestNewVideoTranscodeTask(t *testing.T) {
+	task, err := tasks.NewVideoTranscodeTask(new(mocktasks.Client), &tasks.VideoTranscodePayload{Profiles: profiles, JobID: "job-id", FileID: "123"}, options, &log)
+	assert.NoError(t, err)
+	assert.Equal(t, "job-id", task.ID())
+	assert.Equal(t, tasks.VideoTranscodeTaskType, task.Type())
+
+	var p tasks.VideoTranscodePayload
+	assert.NoError(t, json.Unmarshal(task.Payload(), &p))
+	assert.Empty(t, p.JobID)
+	assert.Equal(t, profiles, p.Profiles)
+}
+
+// TestVideoTranscodeProcessTask tests that the renditions of a video are added together in the batch of the job
+func TestVideoTranscodeProcessTask(t *testing.T) {
+	payload, _ := json.Marshal(tasks.VideoTranscodePayload{Profiles: profiles, JobID: "job-id", FileID: "123", StoragePath: "/path/to/file", Filename: "test.mp4"})
+	task := asynq.NewTask(tasks.VideoTranscodeTaskType, payload)
+	video := &models.File{ID: "123", StoragePath: "/path/to/file", MimeType: "video/mp4", Type: "video"}
+
+	tests := []struct {
+		name           string
+		mockDB         func(m *mockdb.Database)
+		mockTranscoder func(m *mocktasks.Transcoder)
+		expectErr      bool
+		skipRetry      bool
+	}{
+		{
+			name: "valid task",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+				m.On("AddProcessedOutputs", "123", mock.MatchedBy(func(pos []models.ProcessedOutput) bool {
+					return len(pos) == 2 && pos[0].BatchID == "job-id" && pos[1].BatchID == "job-id"
+				})).Return(nil)
+				m.On("JobsByFileID", "123").Return([]models.Job{}, nil)
+			},
+			mockTranscoder: func(m *mocktasks.Transcoder) {
+				m.On("Transcode", mock.Anything, "/path/to/file", "test.mp4", profiles).Return([]models.ProcessedOutput{{Preset: "720p"}, {Preset: "480p-webm"}}, nil)
+			},
+		},
+		{
+			name: "file is not a video",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(&models.File{ID: "123", MimeType: "image/png", Type: "image"}, nil)
+			},
+			mockTranscoder: func(m *mocktasks.Transcoder) {},
+			expectErr:      true,
+			skipRetry:      true,
+		},
+		{
+			name: "transcode error",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+			},
+			mockTranscoder: func(m *mocktasks.Transcoder) {
+				m.On("Transcode", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("ffmpeg error"))
+			},
+			expectErr: true,
+		},
+		{
+			name: "error adding processed outputs",
+			mockDB: func(m *mockdb.Database) {
+				m.On("FileByID", "123").Return(video, nil)
+				m.On("AddProcessedOutputs", "123", mock.Anything).Return(assert.AnError)
+			},
+			mockTranscoder: func(m *mocktasks.Transcoder) {
+				m.On("Transcode", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.ProcessedOutput{{}}, nil)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			transcoder := new(mocktasks.Transcoder)
+			tt.mockDB(db)
+			tt.mockTranscoder(transcoder)
+			db.On("UpdateJob", "job-id", mock.Anything).Return(nil)
+			db.On("TransitionFile", "123", mock.Anything, mock.Anything).Return(nil)
+
+			err := tasks.NewVideoTranscodeHandler(db, transcoder, notifier(), &log).ProcessTask(context.Background(), task)
+			assert.Equal(t, tt.expectErr, err != nil)
+			assert.Equal(t, tt.skipRetry, errors.Is(err, asynq.SkipRetry), "whether the task is archived without retries")
+			db.AssertExpectations(t)
+			transcoder.AssertExpectations(t)
+		})
+	}
+}
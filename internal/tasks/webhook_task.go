@@ -0,0 +1,171 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"simple-file-processor/internal/config"
+	"simple-file-processor/internal/db"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/models"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+const (
+	WebhookTaskType = "webhook:deliver" // Name of the task
+)
+
+// Holds the payload for the webhook delivery task
+type WebhookPayload struct {
+	Event events.Event
+	URL   string // The webhook the event is posted to
+}
+
+type webhookHandler struct {
+	db     db.Database
+	sender events.Sender
+	log    *zerolog.Logger
+}
+
+// Constructs a client for the webhook delivery task
+// A failed delivery is retried up to the max retry of the options, with the backoff of asynq between attempts
+func NewWebhookTask(c Client, p *WebhookPayload, o config.TaskOptions, l *zerolog.Logger) (Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		l.Error().Err(err).Msg("Failed to marshal webhook task payload for file: " + p.Event.FileID)
+		return nil, err
+	}
+
+	return &task{
+		client:  c,
+		log:     l,
+		task:    asynq.NewTask(WebhookTaskType, payload),
+		options: o,
+	}, nil
+}
+
+// Constructs a new webhook handler for the async worker
+func NewWebhookHandler(db db.Database, s events.Sender, l *zerolog.Logger) *webhookHandler {
+	return &webhookHandler{
+		db:     db,
+		sender: s,
+		log:    l,
+	}
+}
+
+// Handles the webhook delivery task and posts the event to the webhook
+// Every attempt is recorded in the delivery log, and the error of a failed
+// attempt is returned so that asynq retries it, unless the attempt cannot succeed
+func (h *webhookHandler) ProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p WebhookPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		h.log.Error().Err(err).Msg("Failed to unmarshal webhook task payload")
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	start := time.Now()
+	code, err := h.sender.Send(ctx, p.URL, p.Event)
+	d := &models.WebhookDelivery{
+		EventID:    p.Event.ID,
+		EventType:  p.Event.Type,
+		FileID:     p.Event.FileID,
+		URL:        p.URL,
+		Attempt:    retried + 1,
+		StatusCode: code,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	if ierr := h.db.InsertWebhookDelivery(d); ierr != nil {
+		h.log.Error().Err(ierr).Str("event_id", p.Event.ID).Msg("Failed to record webhook delivery")
+	}
+
+	if err != nil {
+		h.log.Error().Err(err).Msg(fmt.Sprintf("Failed to deliver event %s to %s", p.Event.ID, p.URL))
+		if errors.Is(err, events.ErrForbiddenAddress) {
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+
+		return err
+	}
+
+	h.log.Info().Msg(fmt.Sprintf("Delivered event %s of type %s to %s", p.Event.ID, p.Event.Type, p.URL))
+	return nil
+}
+
+// Notifier sends the events of files to the subscribers of their stream and to their webhooks
+type Notifier interface {
+	// Notify publishes the event and enqueues a delivery of it to every webhook that accepts it
+	// The file is looked up by the id of the event when it is not given
+	Notify(e events.Event, f *models.File)
+}
+
+type notifier struct {
+	client    Client
+	db        db.Database
+	stream    events.Stream
+	endpoints []events.Endpoint
+	options   config.TaskOptions
+	log       *zerolog.Logger
+}
+
+// Constructs a notifier that publishes events to the stream and delivers them to the given endpoints
+// along with the webhook registered on the upload of the file, if any
+func NewNotifier(c Client, d db.Database, s events.Stream, endpoints []events.Endpoint, o config.TaskOptions, l *zerolog.Logger) Notifier {
+	return &notifier{
+		client:    c,
+		db:        d,
+		stream:    s,
+		endpoints: endpoints,
+		options:   o,
+		log:       l,
+	}
+}
+
+// Events are best effort, so an event that cannot be published or enqueued is logged rather than returned
+func (n *notifier) Notify(e events.Event, f *models.File) {
+	if err := n.stream.Publish(context.Background(), e); err != nil {
+		n.log.Error().Err(err).Str("event_id", e.ID).Msg("Failed to publish event of file " + e.FileID)
+	}
+
+	if !events.IsWebhookEvent(e.Type) {
+		return
+	}
+
+	urls := []string{}
+	for _, ep := range n.endpoints {
+		if ep.Accepts(e.Type) {
+			urls = append(urls, ep.URL)
+		}
+	}
+
+	if f == nil {
+		var err error
+		if f, err = n.db.FileByID(e.FileID); err != nil {
+			n.log.Error().Err(err).Str("file_id", e.FileID).Msg("Failed to get file of event")
+		}
+	}
+
+	if f != nil && f.WebhookURL != "" {
+		urls = append(urls, f.WebhookURL)
+	}
+
+	for _, u := range urls {
+		t, err := NewWebhookTask(n.client, &WebhookPayload{Event: e, URL: u}, n.options, n.log)
+		if err != nil {
+			continue
+		}
+
+		if _, err := t.Enqueue(); err != nil {
+			n.log.Error().Err(err).Str("event_id", e.ID).Msg("Failed to enqueue webhook delivery to " + u)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simple-file-processor/internal/events"
+	"simple-file-processor/internal/mocks/mockdb"
+	"simple-file-processor/internal/mocks/mockevents"
+	"simple-file-processor/internal/mocks/mocktasks"
+	"simple-file-processor/internal/models"
+	"simple-file-processor/internal/tasks"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestWebhookProcessTask tests that events are posted to the webhook and every attempt is logged
+func TestWebhookProcessTask(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{name: "delivered", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusServiceUnavailable, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			var signature string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				signature = r.Header.Get(events.SignatureHeader)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			e := events.New(events.OutputCreated, "123", models.ProcessedOutput{Name: "test"})
+			payload, _ := json.Marshal(tasks.WebhookPayload{Event: e, URL: srv.URL})
+
+			db := new(mockdb.Database)
+			db.On("InsertWebhookDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+				return d.EventID == e.ID && d.FileID == "123" && d.URL == srv.URL && d.Attempt == 1 &&
+					d.StatusCode == tt.status && d.Succeeded() == !tt.expectErr
+			})).Return(nil).Once()
+
+			h := tasks.NewWebhookHandler(db, events.NewSender("secret", time.Second, []events.Endpoint{{URL: srv.URL}}), &log)
+			err := h.ProcessTask(context.Background(), asynq.NewTask(tasks.WebhookTaskType, payload))
+			assert.Equal(t, tt.expectErr, err != nil)
+			assert.True(t, events.Verify([]byte("secret"), body, signature))
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+// TestWebhookProcessTaskSkipRetry tests that deliveries that cannot succeed are archived without retries
+func TestWebhookProcessTaskSkipRetry(t *testing.T) {
+	t.Run("invalid payload", func(t *testing.T) {
+		db := new(mockdb.Database)
+		err := tasks.NewWebhookHandler(db, events.NewSender("secret", time.Second, nil), &log).ProcessTask(context.Background(), asynq.NewTask(tasks.WebhookTaskType, []byte("{")))
+		assert.ErrorIs(t, err, asynq.SkipRetry)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("webhook on a loopback address", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		e := events.New(events.JobFailed, "123", nil)
+		payload, _ := json.Marshal(tasks.WebhookPayload{Event: e, URL: srv.URL})
+		db := new(mockdb.Database)
+		db.On("InsertWebhookDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			return d.EventID == e.ID && !d.Succeeded()
+		})).Return(nil).Once()
+
+		err := tasks.NewWebhookHandler(db, events.NewSender("secret", time.Second, nil), &log).ProcessTask(context.Background(), asynq.NewTask(tasks.WebhookTaskType, payload))
+		assert.ErrorIs(t, err, events.ErrForbiddenAddress)
+		assert.ErrorIs(t, err, asynq.SkipRetry)
+		db.AssertExpectations(t)
+	})
+}
+
+// TestNotify tests that events are enqueued for every webhook that accepts them
+func TestNotify(t *testing.T) {
+	endpoints := []events.Endpoint{
+		{URL: "https://example.com/all"},
+		{URL: "https://example.com/failures", Events: []string{events.JobFailed}},
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		file      *models.File
+		mockDB    func(db *mockdb.Database)
+		expected  []string
+	}{
+		{
+			name:      "global webhooks",
+			eventType: events.FileUploaded,
+			file:      &models.File{ID: "123"},
+			expected:  []string{"https://example.com/all"},
+		},
+		{
+			name:      "upload webhook",
+			eventType: events.JobFailed,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "123").Return(&models.File{ID: "123", WebhookURL: "https://example.com/upload"}, nil)
+			},
+			expected: []string{"https://example.com/all", "https://example.com/failures", "https://example.com/upload"},
+		},
+		{
+			name:      "stream only",
+			eventType: events.StatusChanged,
+			expected:  []string{},
+		},
+		{
+			name:      "file not found",
+			eventType: events.OutputCreated,
+			mockDB: func(db *mockdb.Database) {
+				db.On("FileByID", "123").Return(nil, assert.AnError)
+			},
+			expected: []string{"https://example.com/all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := new(mockdb.Database)
+			if tt.mockDB != nil {
+				tt.mockDB(db)
+			}
+
+			urls := []string{}
+			client := new(mocktasks.Client)
+			client.On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				var p tasks.WebhookPayload
+				assert.NoError(t, json.Unmarshal(args.Get(0).(*asynq.Task).Payload(), &p))
+				assert.Equal(t, tt.eventType, p.Event.Type)
+				urls = append(urls, p.URL)
+			}).Return(&asynq.TaskInfo{}, nil)
+
+			// Every event is published, whether or not it is sent to webhooks
+			stream := new(mockevents.Stream)
+			stream.On("Publish", mock.Anything, event(tt.eventType, "123")).Return(nil).Once()
+
+			tasks.NewNotifier(client, db, stream, endpoints, options, &log).Notify(events.New(tt.eventType, "123", nil), tt.file)
+			assert.Equal(t, tt.expected, urls)
+			db.AssertExpectations(t)
+			stream.AssertExpectations(t)
+		})
+	}
+}
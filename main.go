@@ -1,13 +1,43 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
 	"simple-file-processor/internal/server"
 )
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [api|worker|all]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  api     serves the API and enqueues tasks for the workers")
+		fmt.Fprintln(flag.CommandLine.Output(), "  worker  processes the tasks of the queues")
+		fmt.Fprintln(flag.CommandLine.Output(), "  all     serves the API and processes the tasks in one process (default)")
+	}
+	flag.Parse()
+
+	// Run both the API and the worker unless a mode is given
+	name := string(server.ModeAll)
+	if flag.NArg() > 0 {
+		name = flag.Arg(0)
+	}
+
+	mode, err := server.ParseMode(name)
+	if err == nil && flag.NArg() > 1 {
+		err = fmt.Errorf("expected a single mode, got %d", flag.NArg())
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Initialize the server with the default configurations
-	server := server.NewServer()
+	s := server.NewServer(mode)
 
 	// Start the server
-	server.Start()
+	if err := s.Start(); err != nil {
+		os.Exit(1)
+	}
 }